@@ -8,25 +8,28 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var addOptions repos.AddOptions
+
 // addCmd represents the add command
 var addCmd = &cobra.Command{
 	Use:   "add",
 	Short: "Add a repository.",
 	Run: func(cmd *cobra.Command, args []string) {
-		client, err := repos.NewRepoManager(
-			repos.WithVerbose(verbose),
-			repos.WithConfig(config),
-		)
-		cobra.CheckErr(err)
-
-		err = client.Add(args[0], 1)
-		cobra.CheckErr(err)
+		client, err := newRepoManager()
+		checkErr(err)
+
+		err = client.Add(args[0], 1, addOptions)
+		checkErr(err)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(addCmd)
 
+	addCmd.Flags().StringVar(&addOptions.CreateRemote, "create-remote", "", "Create the remote repository on this configured provider when there is no origin, and push to it.")
+	addCmd.Flags().BoolVar(&addOptions.Private, "private", false, "Make created remote repositories private.")
+	addCmd.Flags().StringVar(&addOptions.File, "file", "", "Write the repository to this included config file instead of the main one.")
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
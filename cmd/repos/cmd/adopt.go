@@ -0,0 +1,29 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var adoptDepth int
+
+// adoptCmd represents the adopt command
+var adoptCmd = &cobra.Command{
+	Use:   "adopt",
+	Short: "Scan the workspace and add every git repository found to the config.",
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		err = client.Adopt(adoptDepth)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(adoptCmd)
+
+	adoptCmd.Flags().IntVar(&adoptDepth, "depth", 3, "How many directory levels to descend into.")
+}
@@ -0,0 +1,43 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var (
+	applyFileOptions     repos.ApplyFileOptions
+	applyFileMessageFile string
+)
+
+// applyFileCmd represents the apply-file command
+var applyFileCmd = &cobra.Command{
+	Use:   "apply-file <src> <dest-path>",
+	Short: "Copy a file into multiple repositories in batch, optionally committing and pushing it.",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		message, err := readMessage(applyFileOptions.Message, applyFileMessageFile)
+		checkErr(err)
+		applyFileOptions.Message = message
+		client, err := newRepoManager()
+		checkErr(err)
+
+		err = client.ApplyFile(args[0], args[1], applyFileOptions)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(applyFileCmd)
+
+	applyFileCmd.Flags().StringSliceVar(&applyFileOptions.Select.Groups, "group", nil, "Only apply to repositories in these groups.")
+	applyFileCmd.Flags().StringSliceVar(&applyFileOptions.Select.Only, "only", nil, "Only apply to the repositories with these names.")
+	registerSelectCompletions(applyFileCmd)
+	applyFileCmd.Flags().BoolVar(&applyFileOptions.Commit, "commit", false, "Commit the file.")
+	applyFileCmd.Flags().StringVarP(&applyFileOptions.Message, "message", "m", "", "Commit message template with {{.RepoName}}, {{.Branch}}, {{.Date}}, {{.TicketFromBranch}} and {{.File}}, defaults to \"Update {{.File}}\".")
+	applyFileCmd.Flags().StringVarP(&applyFileMessageFile, "message-file", "F", "", "Read the commit message template from a file.")
+	applyFileCmd.Flags().BoolVar(&applyFileOptions.Push, "push", false, "Push the commit to origin, needs --commit.")
+}
@@ -0,0 +1,111 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	authUser       string
+	githubHostname string
+	githubClientID string
+	githubScopes   []string
+)
+
+// authCmd represents the auth command
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage the tokens kept in the OS keyring.",
+}
+
+// authLoginCmd represents the auth login command
+var authLoginCmd = &cobra.Command{
+	Use:   "login <host>",
+	Short: "Store a token for a host, used for provider APIs and HTTPS remotes.",
+	Long: `Store a token for a host such as github.com in the OS keyring: the keychain
+on macOS, the secret service on Linux and the credential manager on Windows.
+The token is read from stdin, or prompted for on a terminal.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		token, err := readSecret("Token for " + args[0])
+		checkErr(err)
+
+		err = repos.Login(args[0], repos.Credential{User: authUser, Token: token})
+		checkErr(err)
+		fmt.Fprintf(os.Stderr, "Stored the token for %s.\n", args[0])
+	},
+}
+
+// authLoginGitHubCmd represents the auth login github command
+var authLoginGitHubCmd = &cobra.Command{
+	Use:   "github",
+	Short: "Log in to GitHub in the browser and store the token it grants.",
+	Long: `Log in to GitHub with the OAuth device flow: repos prints a code to enter on
+the GitHub page it names, waits until it is authorized and stores the token
+in the OS keyring for the host. The token is used for provider APIs and HTTPS
+remotes, so no personal access token has to be created by hand.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := repos.LoginGitHub(interruptCtx, repos.GitHubLoginOptions{
+			Host:     githubHostname,
+			ClientID: githubClientID,
+			Scopes:   githubScopes,
+			Prompt: func(code repos.DeviceCode) {
+				fmt.Fprintf(os.Stderr, "Open %s and enter the code %s within %s.\n", code.VerificationURI, code.UserCode, code.ExpiresIn)
+			},
+		})
+		checkErr(err)
+		fmt.Fprintf(os.Stderr, "Stored the token for %s.\n", githubHostname)
+	},
+}
+
+// authLogoutCmd represents the auth logout command
+var authLogoutCmd = &cobra.Command{
+	Use:   "logout <host>",
+	Short: "Remove the token of a host from the OS keyring.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := repos.Logout(args[0])
+		checkErr(err)
+		fmt.Fprintf(os.Stderr, "Removed the token for %s.\n", args[0])
+	},
+}
+
+// readSecret prompts for a secret without echoing it on a terminal and reads
+// the first line of stdin otherwise.
+func readSecret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, prompt+": ")
+		token, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return strings.TrimSpace(string(token)), err
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("reading from stdin: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authLoginCmd)
+	authCmd.AddCommand(authLogoutCmd)
+	authLoginCmd.AddCommand(authLoginGitHubCmd)
+
+	authLoginCmd.Flags().StringVar(&authUser, "user", "", "User name for HTTPS remotes, which most forges ignore for tokens.")
+
+	authLoginGitHubCmd.Flags().StringVar(&githubHostname, "hostname", "github.com", "Host of the GitHub instance, for GitHub Enterprise Server.")
+	authLoginGitHubCmd.Flags().StringVar(&githubClientID, "client-id", "", "Client ID of the OAuth app to authorize (default from REPOS_GITHUB_CLIENT_ID).")
+	authLoginGitHubCmd.Flags().StringSliceVar(&githubScopes, "scopes", nil, "Scopes of the token (default repo,read:org).")
+}
@@ -0,0 +1,29 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var backupOut string
+
+// backupCmd represents the backup command
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Archive the config, its included and nested config files and a bundle of every repository.",
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		err = client.Backup(backupOut)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+
+	backupCmd.Flags().StringVarP(&backupOut, "out", "o", "backup.tar.gz", "Path of the archive to write.")
+}
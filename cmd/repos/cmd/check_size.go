@@ -0,0 +1,78 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkSizeOptions repos.CheckSizeOptions
+	checkSizeLimit   string
+	checkSizeJSON    bool
+)
+
+// checkSizeCmd represents the check-size command
+var checkSizeCmd = &cobra.Command{
+	Use:   "check-size",
+	Short: "List files too large to push in the repositories.",
+	Long: `List the files over the size limit in the working trees of the repositories,
+tracked or not ignored, and in the commits that pushing would publish. The
+limit is the max_file_size config setting, 50MB unless set, or --limit.
+
+Push, sync and apply-file check the commits they push against max_file_size and
+refuse repos adding larger files, so build artifacts don't end up in the
+history. Set max_file_size to 0 to turn that off, or pass --allow-large to
+push.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if checkSizeLimit != "" {
+			limit, err := repos.ParseSize(checkSizeLimit)
+			checkErr(err)
+			checkSizeOptions.Limit = limit
+		}
+		client, err := newRepoManager()
+		checkErr(err)
+
+		files, checkErrs := client.CheckSize(checkSizeOptions)
+		if files == nil {
+			checkErr(checkErrs)
+		}
+
+		if checkSizeJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			checkErr(encoder.Encode(files))
+		} else {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, file := range files {
+				where := "working tree"
+				if file.Commit != "" {
+					where = "unpushed commit " + file.Commit
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", file.Repo, file.Path, repos.FormatBytes(file.Size), where)
+			}
+			checkErr(w.Flush())
+		}
+		checkErr(checkErrs)
+		if len(files) > 0 {
+			os.Exit(exitFailed)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkSizeCmd)
+
+	checkSizeCmd.Flags().StringVar(&checkSizeLimit, "limit", "", "Size limit overriding max_file_size, e.g. 10MB.")
+	checkSizeCmd.Flags().BoolVar(&checkSizeJSON, "json", false, "Print the large files as JSON.")
+	checkSizeCmd.Flags().StringSliceVar(&checkSizeOptions.Select.Groups, "group", nil, "Only check repos in these groups.")
+	checkSizeCmd.Flags().StringSliceVar(&checkSizeOptions.Select.Only, "only", nil, "Only check the repos with these names.")
+	registerSelectCompletions(checkSizeCmd)
+}
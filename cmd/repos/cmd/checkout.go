@@ -0,0 +1,33 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var checkoutOptions repos.ListOptions
+
+var checkoutCmd = &cobra.Command{
+	Use:               "checkout <branch>",
+	Short:             "Check out a branch in multiple repositories in batch, skipping those without it.",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeBranches,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		err = client.Checkout(args[0], checkoutOptions)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkoutCmd)
+
+	checkoutCmd.Flags().StringSliceVar(&checkoutOptions.Groups, "group", nil, "Only check out in repositories in these groups.")
+	checkoutCmd.Flags().StringSliceVar(&checkoutOptions.Only, "only", nil, "Only check out in the repositories with these names.")
+	registerSelectCompletions(checkoutCmd)
+}
@@ -0,0 +1,70 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ciSelect repos.ListOptions
+	ciJSON   bool
+)
+
+// ciCmd represents the ci command
+var ciCmd = &cobra.Command{
+	Use:   "ci",
+	Short: "Show the CI state of the default branch of every repository.",
+	Long: `Look up the checks of the head of the configured or default branch on origin
+of every repository whose host has a provider configured: commit statuses and
+check runs on GitHub, commit statuses on Gitea and pipelines on GitLab. The
+state is success, failure, pending or none when there are no checks.
+
+The exit code is 1 when any branch fails its checks.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		entries, ciErr := client.CI(ciSelect)
+		if entries == nil {
+			checkErr(ciErr)
+		}
+		failing := false
+		for _, entry := range entries {
+			failing = failing || entry.Checks == repos.ChecksFailure
+		}
+
+		if ciJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			checkErr(encoder.Encode(entries))
+		} else {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, entry := range entries {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Repo, entry.Branch, shortHash(entry.Commit), entry.Checks)
+			}
+			checkErr(w.Flush())
+		}
+		checkErr(ciErr)
+		if failing {
+			os.Exit(exitFailed)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(ciCmd)
+
+	ciCmd.Flags().BoolVar(&ciJSON, "json", false, "Print the CI states as JSON.")
+	ciCmd.Flags().StringSliceVar(&ciSelect.Groups, "group", nil, "Only show repos in these groups.")
+	ciCmd.Flags().StringSliceVar(&ciSelect.Only, "only", nil, "Only show the repos with these names.")
+	registerSelectCompletions(ciCmd)
+}
@@ -0,0 +1,31 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var cleanupOptions repos.CleanupOptions
+
+// cleanupBranchesCmd represents the cleanup-branches command
+var cleanupBranchesCmd = &cobra.Command{
+	Use:   "cleanup-branches",
+	Short: "Delete local branches already merged into the default branch of multiple repositories in batch.",
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		err = client.CleanupBranches(cleanupOptions)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cleanupBranchesCmd)
+
+	cleanupBranchesCmd.Flags().BoolVar(&cleanupOptions.DryRun, "dry-run", false, "Only list the branches that would be deleted.")
+	cleanupBranchesCmd.Flags().BoolVar(&cleanupOptions.Prune, "prune", false, "Also prune remote-tracking branches deleted on origin.")
+}
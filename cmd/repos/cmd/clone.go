@@ -0,0 +1,45 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cloneOptions      repos.CloneOptions
+	cloneSingleBranch bool
+)
+
+// cloneCmd represents the clone command
+var cloneCmd = &cobra.Command{
+	Use:   "clone",
+	Short: "Clone configured repositories that are missing in the workspace.",
+	Long: `Clone the configured repositories that are missing in the workspace. Lazy
+repositories, set with lazy: true in the config for the workspace or a
+repository, are left to be cloned on first use, by repos get or an operation
+selecting them with --only, unless --all is given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		_, err := repos.ParseCloneFilter(cloneOptions.Filter)
+		checkErr(err)
+		client, err := newRepoManager()
+		checkErr(err)
+
+		if cmd.Flags().Changed("single-branch") {
+			cloneOptions.SingleBranch = &cloneSingleBranch
+		}
+		err = client.Clone(cloneOptions)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cloneCmd)
+
+	cloneCmd.Flags().IntVar(&cloneOptions.Depth, "depth", 0, "Create shallow clones with this many commits, overrides the config.")
+	cloneCmd.Flags().BoolVar(&cloneSingleBranch, "single-branch", false, "Only fetch the configured branch, overrides the config.")
+	cloneCmd.Flags().BoolVar(&cloneOptions.All, "all", false, "Also clone the lazy repositories.")
+	cloneCmd.Flags().StringVar(&cloneOptions.Filter, "filter", "", "Create partial clones, e.g. blob:none or tree:0, overrides the config.")
+}
@@ -0,0 +1,79 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var (
+	commitOptions     repos.CommitOptions
+	commitMessageFile string
+	commitInteractive bool
+)
+
+// readMessage returns the commit message template of --message, or the
+// content of the --message-file file.
+func readMessage(message, file string) (string, error) {
+	if file == "" {
+		return message, nil
+	}
+	if message != "" {
+		return "", fmt.Errorf("--message and --message-file can't be used together")
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// commitCmd represents the commit command
+var commitCmd = &cobra.Command{
+	Use:   "commit",
+	Short: "Commit the changes of multiple repositories in batch.",
+	Long: `Commit every change, untracked files included, of the repositories with
+changes, e.g.:
+
+  repos commit -m "{{.TicketFromBranch}}: bump the Go version"
+
+The message is a Go template with {{.RepoName}}, {{.Branch}}, {{.Date}} and
+{{.TicketFromBranch}}, the issue key such as ABC-123 in the branch name. The
+same variables work in the messages of apply-file and replace.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		message, err := readMessage(commitOptions.Message, commitMessageFile)
+		checkErr(err)
+		commitOptions.Message = message
+		var options []repos.NewRepoManagerClientOptions
+		if commitInteractive {
+			action := "Commit"
+			if commitOptions.Push {
+				action = "Commit and push"
+			}
+			options = append(options, repos.WithReview(reviewer(action)))
+		}
+		client, err := newRepoManager(options...)
+		checkErr(err)
+
+		err = client.Commit(commitOptions)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(commitCmd)
+
+	commitCmd.Flags().StringVarP(&commitOptions.Message, "message", "m", "", "Commit message template with {{.RepoName}}, {{.Branch}}, {{.Date}} and {{.TicketFromBranch}}.")
+	commitCmd.Flags().StringVarP(&commitMessageFile, "message-file", "F", "", "Read the commit message template from a file.")
+	commitCmd.Flags().BoolVar(&commitOptions.Push, "push", false, "Push the commits to origin.")
+	commitCmd.Flags().BoolVarP(&commitInteractive, "interactive", "i", false, "Show the message, changed files and diffstat of every repo and ask before committing it.")
+	commitCmd.Flags().StringSliceVar(&commitOptions.Select.Groups, "group", nil, "Only commit in repositories in these groups.")
+	commitCmd.Flags().StringSliceVar(&commitOptions.Select.Only, "only", nil, "Only commit in the repositories with these names.")
+	registerSelectCompletions(commitCmd)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+// reloadConfig loads the config again for completions. Cobra initializes
+// before it parses the flags of the command being completed, so --config and
+// --workspace only take effect now.
+func reloadConfig() {
+	config = nil
+	initConfig()
+}
+
+// completeRepoNames completes the names of the configured repos.
+func completeRepoNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	reloadConfig()
+	names := make([]string, 0, len(config.Repos))
+	for name := range config.Repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeFirstRepoName completes a repo name as the first argument only.
+func completeFirstRepoName(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	return completeRepoNames(cmd, args, toComplete)
+}
+
+// completeGroups completes the groups of the configured repos. Flags taking
+// a comma separated list complete the item after the last comma.
+func completeGroups(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	reloadConfig()
+	return completeList(config.Groups(), toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeOnly completes repo names for --only.
+func completeOnly(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	names, _ := completeRepoNames(cmd, args, toComplete)
+	return completeList(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeBranches completes the local and origin branches of the checked
+// out repos.
+func completeBranches(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	reloadConfig()
+	client, err := newRepoManager(repos.WithVerbosity(repos.VerbosityQuiet))
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return client.Branches(repos.ListOptions{}), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeList prefixes values with the items already typed before the last
+// comma of toComplete.
+func completeList(values []string, toComplete string) []string {
+	i := strings.LastIndex(toComplete, ",")
+	if i < 0 {
+		return values
+	}
+	prefix := toComplete[:i+1]
+	completions := make([]string, 0, len(values))
+	for _, value := range values {
+		completions = append(completions, prefix+value)
+	}
+	return completions
+}
+
+// registerSelectCompletions completes the --group and --only flags of cmd.
+func registerSelectCompletions(cmd *cobra.Command) {
+	cobra.CheckErr(cmd.RegisterFlagCompletionFunc("group", completeGroups))
+	cobra.CheckErr(cmd.RegisterFlagCompletionFunc("only", completeOnly))
+}
+
+// completeWorkspaces completes the workspaces of the config file.
+func completeWorkspaces(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	workspace = ""
+	reloadConfig()
+	names := make([]string, 0, len(config.Workspaces))
+	for name := range config.Workspaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeOutput completes the --output formats.
+func completeOutput(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return []string{repos.OutputCSV, repos.OutputTSV}, cobra.ShellCompDirectiveNoFileComp
+}
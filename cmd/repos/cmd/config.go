@@ -16,23 +16,99 @@ limitations under the License.
 package cmd
 
 import (
+	"fmt"
+	"os"
+
+	"github.com/jerloo/repos"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 )
 
+var validateRemotes bool
+
 // configCmd represents the config command
 var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Change repos configuration.",
 	Run: func(cmd *cobra.Command, args []string) {
-		yamlBytes, err := yaml.Marshal(config)
-		cobra.CheckErr(err)
+		yamlBytes, err := config.Marshal()
+		checkErr(err)
 		cmd.Println(string(yamlBytes))
 	},
 }
 
+// configValidateCmd represents the config validate command
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration for mistakes.",
+	Run: func(cmd *cobra.Command, args []string) {
+		failed := false
+		for _, issue := range config.Validate(validateRemotes) {
+			fmt.Fprintln(os.Stderr, issue)
+			if issue.Severity == repos.SeverityError {
+				failed = true
+			}
+		}
+		if failed {
+			os.Exit(exitConfigError)
+		}
+		cmd.Println("Config is valid.")
+	},
+}
+
+// configSchemaCmd represents the config schema command
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the configuration.",
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(repos.ConfigSchema)
+	},
+}
+
+// configEncryptCmd represents the config encrypt command
+var configEncryptCmd = &cobra.Command{
+	Use:   "encrypt [value]",
+	Short: "Encrypt a value, such as a token, for the configuration.",
+	Long: `Encrypt a value with the master key for pasting into the configuration, where
+it is decrypted when the config is loaded. The value is read from stdin when
+it isn't given. The master key comes from REPOS_MASTER_KEY or the OS keyring,
+see config keygen.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var value string
+		if len(args) == 1 {
+			value = args[0]
+		} else {
+			var err error
+			value, err = readSecret("Value")
+			checkErr(err)
+		}
+		encrypted, err := repos.EncryptValue(value)
+		checkErr(err)
+		fmt.Println(encrypted)
+	},
+}
+
+// configKeygenCmd represents the config keygen command
+var configKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Create the master key for encrypted values in the OS keyring.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		key, err := repos.GenerateMasterKey()
+		checkErr(err)
+		fmt.Fprintln(os.Stderr, "Stored the master key in the OS keyring. Set it as REPOS_MASTER_KEY on machines without one:")
+		fmt.Println(key)
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configSchemaCmd)
+	configCmd.AddCommand(configEncryptCmd)
+	configCmd.AddCommand(configKeygenCmd)
+
+	configValidateCmd.Flags().BoolVar(&validateRemotes, "remote", false, "Also check that every url is reachable.")
 
 	// Here you will define your flags and configuration settings.
 
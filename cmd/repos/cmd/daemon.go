@@ -0,0 +1,96 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var (
+	daemonListen    string
+	daemonTokenFile string
+)
+
+// homeFile returns the path of name in the home directory, the default of
+// the daemon log and token files.
+func homeFile(name string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, name), nil
+}
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run the configured schedules until interrupted.",
+	Long: `Run the schedules of the config file until interrupted, e.g.
+
+schedules:
+  infra:
+    cron: "0 * * * *"
+    command: sync
+    groups: [infra]
+  nightly:
+    cron: "@daily"
+    command: pull
+
+and post the results to the configured notifications, e.g.
+
+notifications:
+  team:
+    type: slack
+    url_env: SLACK_WEBHOOK_URL
+    when: failure
+
+With --listen the daemon also serves a control API on a TCP address or on
+unix:<path>. Requests authenticate with "Authorization: Bearer <token>", the
+token coming from REPOS_API_TOKEN or the token file, which is created when
+missing.
+
+  GET  /results  the last result of every schedule
+  POST /runs     {"schedule": "nightly"} or {"command": "pull", "groups": ["infra"]}
+  GET  /events   progress as JSON lines
+
+The daemon logs to ~/.repos-daemon.log, or the --log-file given, next to the
+console, rotating the file when it grows past 10MB.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !cmd.Flags().Changed("log-file") {
+			var err error
+			logFile, err = homeFile(".repos-daemon.log")
+			checkErr(err)
+		}
+		client, err := newRepoManager()
+		checkErr(err)
+
+		opts := repos.DaemonOptions{Listen: daemonListen}
+		if daemonListen != "" {
+			opts.Token = os.Getenv("REPOS_API_TOKEN")
+			if opts.Token == "" {
+				if daemonTokenFile == "" {
+					daemonTokenFile, err = homeFile(".repos-api-token")
+					checkErr(err)
+				}
+				opts.Token, err = repos.LoadAPIToken(daemonTokenFile)
+				checkErr(err)
+			}
+		}
+
+		err = client.Daemon(interruptCtx, opts)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+
+	daemonCmd.Flags().StringVar(&daemonListen, "listen", "", "Serve the control API on host:port or unix:<path>.")
+	daemonCmd.Flags().StringVar(&daemonTokenFile, "token-file", "", "File holding the control API token when REPOS_API_TOKEN is unset, ~/.repos-api-token by default.")
+}
@@ -0,0 +1,37 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var deleteOptions repos.DeleteOptions
+
+// deleteCmd represents the delete command
+var deleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Remove a repository from the config and its directory from disk.",
+	Long: `Remove a repository from the config and move its directory to the .repos-trash
+directory of the workspace. Repositories with uncommitted changes, stashes or
+commits no remote has are refused unless --force is given.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeFirstRepoName,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		err = client.Delete(args[0], deleteOptions)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(deleteCmd)
+
+	deleteCmd.Flags().BoolVar(&deleteOptions.KeepDir, "keep-dir", false, "Only remove the repository from the config.")
+	deleteCmd.Flags().BoolVar(&deleteOptions.Purge, "purge", false, "Delete the directory instead of moving it to the trash.")
+	deleteCmd.Flags().BoolVar(&deleteOptions.Force, "force", false, "Delete even when there is work that isn't pushed.")
+}
@@ -0,0 +1,47 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var execOptions repos.ExecOptions
+
+// execCmd represents the exec command
+var execCmd = &cobra.Command{
+	Use:     "exec [flags] -- <command> [args...]",
+	Aliases: []string{"foreach"},
+	Short:   "Run a command in every repository in parallel.",
+	Long: `Run a command in the directory of every repository in parallel, e.g.:
+
+  repos exec -- go mod tidy
+  repos exec --output-dir ./logs -- make test
+
+The output of every repository is printed in one piece once its command exits.
+With --output-dir it is written to <dir>/<repo>.log instead, and the exit codes
+to <dir>/index.json, to look into large runs afterwards. The command gets the
+repository in REPOS_REPO and its directory in REPOS_DIR. Repositories whose
+command exits with an error fail.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		err = client.Exec(args, execOptions)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+
+	execCmd.Flags().SetInterspersed(false)
+	execCmd.Flags().StringVar(&execOptions.OutputDir, "output-dir", "", "Write the output of every repo to <dir>/<repo>.log and the exit codes to <dir>/index.json.")
+	execCmd.Flags().StringSliceVar(&execOptions.Select.Groups, "group", nil, "Only run in repos in these groups.")
+	execCmd.Flags().StringSliceVar(&execOptions.Select.Only, "only", nil, "Only run in the repos with these names.")
+	cobra.CheckErr(execCmd.MarkFlagDirname("output-dir"))
+	registerSelectCompletions(execCmd)
+}
@@ -0,0 +1,34 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export <mr|vcstool|gita>",
+	Short: "Print the repositories in the config format of another multi-repo tool.",
+	Long: `Print the repositories in the config format of another multi-repo tool:
+
+  mr       a .mrconfig of myrepos, to keep in the workspace directory
+  vcstool  a .repos file for vcs import, run in the workspace directory
+  gita     the repos.csv of gita, with absolute paths`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"mr", "vcstool", "gita"},
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		err = client.Export(args[0], os.Stdout)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+}
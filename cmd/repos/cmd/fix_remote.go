@@ -0,0 +1,30 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var fixRemoteOptions repos.FixRemoteOptions
+
+// fixRemoteCmd represents the fix-remote command
+var fixRemoteCmd = &cobra.Command{
+	Use:   "fix-remote",
+	Short: "Set the origin of every repository to the url in the config.",
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		err = client.FixRemote(fixRemoteOptions)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fixRemoteCmd)
+
+	fixRemoteCmd.Flags().BoolVar(&fixRemoteOptions.FromDisk, "from-disk", false, "Update the config with the origins on disk instead.")
+}
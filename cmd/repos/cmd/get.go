@@ -0,0 +1,34 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// getCmd represents the get command
+var getCmd = &cobra.Command{
+	Use:   "get <name>",
+	Short: "Clone a repository if it isn't there yet and print its directory.",
+	Long: `Clone a repository if it isn't in the workspace yet, as lazy repositories aren't
+until first used, and print its directory, e.g.:
+
+  cd "$(repos get api)"`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeFirstRepoName,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		dir, err := client.Get(args[0])
+		checkErr(err)
+		fmt.Println(dir)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(getCmd)
+}
@@ -0,0 +1,42 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// goworkCmd represents the gowork command
+var goworkCmd = &cobra.Command{
+	Use:   "gowork",
+	Short: "Generate or update the go.work file of the workspace.",
+	Long: `Scan the repositories for go.mod files and create or update the go.work file at
+the workspace root with a use directive for each module. Directives of modules
+that are gone are dropped. Set go_work: true in the config to keep go.work in
+sync whenever repositories are added, cloned or removed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		added, dropped, err := client.GoWork()
+		checkErr(err)
+
+		if len(added) == 0 && len(dropped) == 0 {
+			fmt.Println("go.work is up to date")
+			return
+		}
+		for _, use := range added {
+			fmt.Println("use", use)
+		}
+		for _, use := range dropped {
+			fmt.Println("dropped", use)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(goworkCmd)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var (
+	graphOptions repos.GraphOptions
+	graphFormat  string
+	graphSave    bool
+)
+
+// graphCmd represents the graph command
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Show how the repositories depend on each other.",
+	Long: `Scan the go.mod files, and with --package-json the package.json files, of the
+repositories and show which of them depend on modules or packages of others,
+as a list, as JSON or in the Graphviz DOT language:
+
+  repos graph --format dot | dot -Tsvg > repos.svg
+
+With --save the dependencies are added to the after fields of the config, so
+commands run on the repositories they depend on first.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		graph, err := client.Graph(graphOptions)
+		checkErr(err)
+
+		switch graphFormat {
+		case "dot":
+			fmt.Print(graph.DOT())
+		case "json":
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			checkErr(encoder.Encode(graph))
+		case "text":
+			for _, dependency := range graph.Dependencies {
+				fmt.Printf("%s -> %s (%s)\n", dependency.Repo, dependency.DependsOn, dependency.Via)
+			}
+		default:
+			checkErr(fmt.Errorf("unknown format %q, must be text, dot or json", graphFormat))
+		}
+
+		if graphSave {
+			added, err := client.SaveDependencies(graph)
+			checkErr(err)
+			for _, dependency := range added {
+				fmt.Fprintf(os.Stderr, "%s now runs after %s\n", dependency.Repo, dependency.DependsOn)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+
+	graphCmd.Flags().StringVar(&graphFormat, "format", "text", "Output format: text, dot or json.")
+	graphCmd.Flags().BoolVar(&graphOptions.PackageJSON, "package-json", false, "Also look at the package.json files.")
+	graphCmd.Flags().BoolVar(&graphSave, "save", false, "Add the dependencies to the after fields of the config.")
+	graphCmd.Flags().StringSliceVar(&graphOptions.Select.Groups, "group", nil, "Only look at repos in these groups.")
+	graphCmd.Flags().StringSliceVar(&graphOptions.Select.Only, "only", nil, "Only look at the repos with these names.")
+	registerSelectCompletions(graphCmd)
+}
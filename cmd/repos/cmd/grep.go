@@ -0,0 +1,38 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var grepOptions repos.GrepOptions
+
+// grepCmd represents the grep command
+var grepCmd = &cobra.Command{
+	Use:   "grep <pattern>",
+	Short: "Search tracked files of multiple repositories in batch.",
+	Long: `Search the tracked files of every repository, printing the matches prefixed
+with the repository name.
+
+Like git grep, it exits with 3 when nothing matched, so scripts can tell that
+from repositories that failed, which exit with 1.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		err = client.Grep(args[0], grepOptions)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(grepCmd)
+
+	grepCmd.Flags().BoolVarP(&grepOptions.Regex, "regex", "E", false, "Treat the pattern as an extended regular expression.")
+	grepCmd.Flags().BoolVarP(&grepOptions.IgnoreCase, "ignore-case", "i", false, "Ignore case distinctions.")
+	grepCmd.Flags().StringArrayVarP(&grepOptions.Globs, "glob", "g", nil, "Only search files matching the glob, e.g. '**/*.go'.")
+}
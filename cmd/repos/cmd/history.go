@@ -0,0 +1,87 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyOptions repos.HistoryOptions
+	historyJSON    bool
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history [repo]",
+	Short: "Show what batch operations did to the repos of the workspace, newest first.",
+	Long: `Show what batch operations did to the repos of the workspace, newest first.
+
+Every batch operation such as pull, push and sync, as well as clone,
+unshallow, stash, tag, release, maintain, cleanup-branches, fix-remote and
+delete, appends the outcome and the HEAD commits before and after of each repo
+to .repos-history.jsonl in the workspace directory. Commands that only read
+the repos or edit the config, such as status and add, aren't recorded.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeFirstRepoName,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		if len(args) == 1 {
+			historyOptions.Repo = args[0]
+		}
+		entries, err := client.History(historyOptions)
+		checkErr(err)
+
+		if historyJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			checkErr(encoder.Encode(entries))
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, entry := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				entry.Time.Local().Format("2006-01-02 15:04:05"), entry.Command, entry.Repo, entry.Outcome,
+				headChange(entry), entry.Reason)
+		}
+		checkErr(w.Flush())
+	},
+}
+
+// headChange shows where an operation moved HEAD, e.g. 1a2b3c4..5d6e7f8.
+func headChange(entry *repos.HistoryEntry) string {
+	if !entry.Changed() {
+		return shortHash(entry.After)
+	}
+	return shortHash(entry.Before) + ".." + shortHash(entry.After)
+}
+
+func shortHash(hash string) string {
+	if hash == "" {
+		return "-"
+	}
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().StringVar(&historyOptions.Command, "command", "", "Only show entries of this command, e.g. pull.")
+	historyCmd.Flags().StringVar(&historyOptions.Since, "since", "", "Only show entries newer than this, e.g. 7d, 2w or 2023-01-01.")
+	historyCmd.Flags().BoolVar(&historyOptions.Changed, "changed", false, "Only show entries that moved HEAD.")
+	historyCmd.Flags().BoolVar(&historyOptions.Failed, "failed", false, "Only show entries that failed.")
+	historyCmd.Flags().IntVarP(&historyOptions.Limit, "limit", "n", 0, "Show at most this many entries.")
+	historyCmd.Flags().BoolVar(&historyJSON, "json", false, "Print the entries as JSON.")
+}
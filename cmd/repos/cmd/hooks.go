@@ -0,0 +1,82 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var (
+	hooksOptions   repos.HooksOptions
+	hooksMode      string
+	hooksUninstall repos.ListOptions
+)
+
+// hooksCmd represents the hooks command
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Install a shared set of git hooks in the repositories.",
+}
+
+// hooksInstallCmd represents the hooks install command
+var hooksInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install the git hooks of a directory in every repository.",
+	Long: `Install the git hooks in the --from directory, such as pre-commit and
+commit-msg, in every repository, e.g.:
+
+  repos hooks install --from ./hooks
+
+With --mode symlink, the default, the hooks are linked from .git/hooks so
+changes to them apply right away, with copy they are copied and with
+hooks-path core.hooksPath points at the directory. Repositories with hooks of
+their own are refused unless --force is given, which keeps them as .orig.
+Installing again replaces what was installed before.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		mode, err := repos.ParseHookMode(hooksMode)
+		checkErr(err)
+		hooksOptions.Mode = mode
+		client, err := newRepoManager()
+		checkErr(err)
+
+		err = client.InstallHooks(hooksOptions)
+		checkErr(err)
+	},
+}
+
+// hooksUninstallCmd represents the hooks uninstall command
+var hooksUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the git hooks hooks install installed.",
+	Long: `Remove the git hooks hooks install installed from every repository and put the
+hooks they replaced back, or unset core.hooksPath.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		err = client.UninstallHooks(hooksUninstall)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(hooksCmd)
+	hooksCmd.AddCommand(hooksInstallCmd)
+	hooksCmd.AddCommand(hooksUninstallCmd)
+
+	hooksInstallCmd.Flags().StringVar(&hooksOptions.From, "from", "", "Directory with the hooks to install.")
+	hooksInstallCmd.Flags().StringVar(&hooksMode, "mode", "", "How to install the hooks: symlink, copy or hooks-path.")
+	hooksInstallCmd.Flags().BoolVarP(&hooksOptions.Force, "force", "f", false, "Replace hooks the repos have of their own, keeping them as .orig.")
+	hooksInstallCmd.Flags().StringSliceVar(&hooksOptions.Select.Groups, "group", nil, "Only install in repos in these groups.")
+	hooksInstallCmd.Flags().StringSliceVar(&hooksOptions.Select.Only, "only", nil, "Only install in the repos with these names.")
+	cobra.CheckErr(hooksInstallCmd.MarkFlagRequired("from"))
+	cobra.CheckErr(hooksInstallCmd.MarkFlagDirname("from"))
+	registerSelectCompletions(hooksInstallCmd)
+	hooksUninstallCmd.Flags().StringSliceVar(&hooksUninstall.Groups, "group", nil, "Only uninstall from repos in these groups.")
+	hooksUninstallCmd.Flags().StringSliceVar(&hooksUninstall.Only, "only", nil, "Only uninstall from the repos with these names.")
+	registerSelectCompletions(hooksUninstallCmd)
+}
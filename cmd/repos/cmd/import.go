@@ -0,0 +1,81 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var importOptions repos.ImportOptions
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Add the repositories listed in the config of another tool.",
+}
+
+// importManifestCmd represents the import manifest command
+var importManifestCmd = &cobra.Command{
+	Use:   "manifest <file|url>",
+	Short: "Add the projects of a repo-tool manifest.xml, following its includes.",
+	Long: `Add the projects of a repo-tool manifest.xml, following its includes.
+
+Project paths become the repo directories and the manifest groups their groups.
+Revisions that are branches become the tracked branch, for tags and commits the
+upstream or dest-branch of the project is tracked instead. Relative fetch urls
+of remotes, like "..", are resolved against --base, which defaults to the url
+of the manifest.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		err = client.ImportRepoManifest(args[0], importOptions)
+		checkErr(err)
+	},
+}
+
+// newImportToolCmd creates the import command for the config format of
+// another multi-repo tool.
+func newImportToolCmd(format, use, short string, defaultSource func() string) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.RangeArgs(0, 1),
+		Run: func(cmd *cobra.Command, args []string) {
+			source := ""
+			if len(args) == 1 {
+				source = args[0]
+			} else if defaultSource != nil {
+				source = defaultSource()
+			}
+			if source == "" {
+				checkErr(fmt.Errorf("%s needs the file to import", cmd.CommandPath()))
+			}
+			client, err := newRepoManager()
+			checkErr(err)
+
+			err = client.Import(format, source)
+			checkErr(err)
+		},
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.AddCommand(importManifestCmd)
+	importCmd.AddCommand(newImportToolCmd(repos.FormatMr, "mr [.mrconfig]",
+		"Add the git repositories of a myrepos .mrconfig, ./.mrconfig by default.",
+		func() string { return ".mrconfig" }))
+	importCmd.AddCommand(newImportToolCmd(repos.FormatVcstool, "vcstool <file|url>",
+		"Add the git repositories of a vcstool .repos file.", nil))
+	importCmd.AddCommand(newImportToolCmd(repos.FormatGita, "gita [repos.csv]",
+		"Add the repositories of gita with their groups, from its config directory by default.",
+		repos.DefaultGitaConfig))
+
+	importCmd.PersistentFlags().StringVar(&importOptions.Base, "base", "", "Resolve relative remote urls against this url, e.g. the url of the manifest repository.")
+}
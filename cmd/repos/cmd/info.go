@@ -0,0 +1,63 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// infoCmd represents the info command
+var infoCmd = &cobra.Command{
+	Use:               "info <name>",
+	Short:             "Show the config and state of a single repository.",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeFirstRepoName,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		info, err := client.Info(args[0])
+		checkErr(err)
+
+		entry, err := yaml.Marshal(info.Config)
+		checkErr(err)
+		fmt.Printf("%s:\n  %s\n", info.Config.Name, strings.ReplaceAll(strings.TrimSpace(string(entry)), "\n", "\n  "))
+
+		line := func(key string, value interface{}) {
+			fmt.Printf("%-12s %v\n", key+":", value)
+		}
+		line("dir", info.Dir)
+		line("branch", info.Branch)
+		line("head", info.Head+" "+info.Subject)
+		if info.Upstream == "" {
+			line("upstream", "none")
+		} else {
+			line("upstream", fmt.Sprintf("%s, %d ahead, %d behind", info.Upstream, info.Ahead, info.Behind))
+		}
+		names := make([]string, 0, len(info.Remotes))
+		for name := range info.Remotes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			line("remote", name+" "+info.Remotes[name])
+		}
+		if info.LastFetch.IsZero() {
+			line("last fetch", "never")
+		} else {
+			line("last fetch", info.LastFetch.Format("2006-01-02 15:04:05"))
+		}
+		line("stashes", info.Stashes)
+		line("clean", info.Clean)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+}
@@ -0,0 +1,67 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var (
+	listOptions repos.ListOptions
+	listFormat  string
+	listJSON    bool
+)
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the configured repositories.",
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		entries := client.List(listOptions)
+
+		switch {
+		case listJSON:
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			checkErr(encoder.Encode(entries))
+		case listFormat != "":
+			tmpl, err := repos.FormatTemplate(listFormat)
+			checkErr(err)
+			for _, entry := range entries {
+				checkErr(tmpl.Execute(os.Stdout, entry))
+			}
+		default:
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, entry := range entries {
+				dir := entry.Dir
+				if entry.NotCloned {
+					dir += " (not cloned)"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+					entry.Name, dir, entry.Url, entry.Branch, strings.Join(entry.Groups, ","))
+			}
+			checkErr(w.Flush())
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().StringSliceVar(&listOptions.Groups, "group", nil, "Only list repositories in these groups.")
+	listCmd.Flags().StringSliceVar(&listOptions.Only, "only", nil, "Only list the repositories with these names.")
+	registerSelectCompletions(listCmd)
+	listCmd.Flags().StringVar(&listFormat, "format", "", "Print every repository with a Go template, e.g. '{{.Name}} {{.Url}}'.")
+	listCmd.Flags().BoolVar(&listJSON, "json", false, "Print the repositories as JSON.")
+}
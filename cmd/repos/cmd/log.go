@@ -0,0 +1,71 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logOptions repos.LogOptions
+	logJSON    bool
+	logOutput  string
+)
+
+// logCmd represents the log command
+var logCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Show recent commits of multiple repositories in one timeline.",
+	Run: func(cmd *cobra.Command, args []string) {
+		output, err := repos.ParseOutput(logOutput)
+		checkErr(err)
+		if output != "" && logJSON {
+			checkErr(fmt.Errorf("--output and --json can't be combined"))
+		}
+		client, err := newRepoManager()
+		checkErr(err)
+
+		entries, logErr := client.Log(logOptions)
+		if entries == nil {
+			checkErr(logErr)
+		}
+
+		if output != "" {
+			var rows [][]string
+			for _, entry := range entries {
+				rows = append(rows, []string{entry.Date.Format(time.RFC3339), entry.Repo, entry.Hash, entry.Author, entry.Subject})
+			}
+			checkErr(repos.WriteDelimited(os.Stdout, output, []string{"date", "repo", "hash", "author", "subject"}, rows))
+		} else if logJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			checkErr(encoder.Encode(entries))
+		} else {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, entry := range entries {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+					entry.Date.Format("2006-01-02 15:04"), entry.Repo, entry.Hash[:7], entry.Author, entry.Subject)
+			}
+			checkErr(w.Flush())
+		}
+		checkErr(logErr)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logCmd)
+
+	logCmd.Flags().StringVar(&logOptions.Since, "since", "7d", "Show commits newer than this, e.g. 7d, 2w or 2023-01-01.")
+	logCmd.Flags().StringVar(&logOptions.Author, "author", "", "Only show commits by matching authors.")
+	logCmd.Flags().BoolVar(&logJSON, "json", false, "Print the commits as JSON.")
+	logCmd.Flags().StringVarP(&logOutput, "output", "o", "", "Print the commits as csv or tsv.")
+	cobra.CheckErr(logCmd.RegisterFlagCompletionFunc("output", completeOutput))
+}
@@ -0,0 +1,31 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var maintainOptions repos.MaintainOptions
+
+// maintainCmd represents the maintain command
+var maintainCmd = &cobra.Command{
+	Use:   "maintain",
+	Short: "Run git gc, remote prune and reflog expiry of multiple repositories in batch.",
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		err = client.Maintain(maintainOptions)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(maintainCmd)
+
+	maintainCmd.Flags().BoolVar(&maintainOptions.Auto, "auto", false, "Only run gc when git considers it necessary.")
+	maintainCmd.Flags().StringVar(&maintainOptions.ReflogExpire, "reflog-expire", "90.days", "Expire reflog entries older than this.")
+}
@@ -0,0 +1,36 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var moveOptions repos.MoveOptions
+
+// moveCmd represents the move command
+var moveCmd = &cobra.Command{
+	Use:               "move <name> <newdir>",
+	Short:             "Move a repository to another directory and update the config.",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeFirstRepoName,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		newDir, err := filepath.Abs(args[1])
+		checkErr(err)
+		err = client.Move(args[0], newDir, moveOptions)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(moveCmd)
+
+	moveCmd.Flags().BoolVar(&moveOptions.ConfigOnly, "config-only", false, "Only update the config, e.g. when the directory was moved already.")
+}
@@ -0,0 +1,222 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// pickHeight is how many matches the built-in picker shows.
+const pickHeight = 10
+
+var (
+	pickPath  bool
+	pickQuery string
+)
+
+// errPickCancelled is returned when the picker is left without a choice.
+var errPickCancelled = errors.New("nothing picked")
+
+// pickWithFzf lets fzf choose among the entries, shown as name and
+// directory.
+func pickWithFzf(fzf string, entries []*repos.ListEntry) (*repos.ListEntry, error) {
+	var input bytes.Buffer
+	for _, entry := range entries {
+		fmt.Fprintf(&input, "%s\t%s\n", entry.Name, entry.Dir)
+	}
+	cmd := exec.Command(fzf, "--delimiter=\t", "--height=40%", "--reverse", "--query="+pickQuery)
+	cmd.Stdin = &input
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return nil, errPickCancelled
+	}
+	if err != nil {
+		return nil, err
+	}
+	name, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\t")
+	for _, entry := range entries {
+		if entry.Name == name {
+			return entry, nil
+		}
+	}
+	return nil, errPickCancelled
+}
+
+// openTerminal returns the terminal to draw the picker on, which isn't
+// stdout as that may be captured, e.g. by cd "$(repos pick --path)".
+func openTerminal() (*os.File, func(), error) {
+	if tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+		return tty, func() { tty.Close() }, nil
+	}
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return os.Stdin, func() {}, nil
+	}
+	return nil, nil, fmt.Errorf("picking a repo needs a terminal")
+}
+
+// pickBuiltin is a small fuzzy picker for when fzf isn't installed: typing
+// filters the repos, the arrow keys or ctrl-p and ctrl-n move, enter picks
+// and escape or ctrl-c cancels.
+func pickBuiltin(entries []*repos.ListEntry) (*repos.ListEntry, error) {
+	tty, closeTTY, err := openTerminal()
+	if err != nil {
+		return nil, err
+	}
+	defer closeTTY()
+	state, err := term.MakeRaw(int(tty.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	defer term.Restore(int(tty.Fd()), state)
+
+	query := []rune(pickQuery)
+	cursor := 0
+	// The terminal cursor stays on the prompt line, the matches are drawn
+	// below it.
+	draw := func(matches []*repos.ListEntry) {
+		prompt := fmt.Sprintf("%d/%d > %s", len(matches), len(entries), string(query))
+		var screen strings.Builder
+		screen.WriteString("\r\033[J" + prompt)
+		shown := matches
+		if len(shown) > pickHeight {
+			shown = shown[:pickHeight]
+		}
+		for i, entry := range shown {
+			line := fmt.Sprintf("%-24s %s", entry.Name, entry.Dir)
+			if i == cursor {
+				line = "\033[7m" + line + "\033[0m"
+			}
+			screen.WriteString("\r\n" + line)
+		}
+		if len(shown) > 0 {
+			fmt.Fprintf(&screen, "\033[%dA", len(shown))
+		}
+		fmt.Fprintf(&screen, "\r\033[%dC", len([]rune(prompt)))
+		io.WriteString(tty, screen.String())
+	}
+	clear := func() {
+		io.WriteString(tty, "\r\033[J")
+	}
+
+	buf := make([]byte, 16)
+	for {
+		matches := repos.FuzzyFilter(string(query), entries)
+		if cursor >= len(matches) || cursor >= pickHeight {
+			cursor = 0
+		}
+		draw(matches)
+		n, err := tty.Read(buf)
+		if err != nil {
+			clear()
+			return nil, err
+		}
+		input := buf[:n]
+		switch {
+		case bytes.Equal(input, []byte("\033[A")) || input[0] == 16:
+			if cursor > 0 {
+				cursor--
+			}
+		case bytes.Equal(input, []byte("\033[B")) || input[0] == 14:
+			if cursor < len(matches)-1 && cursor < pickHeight-1 {
+				cursor++
+			}
+		case input[0] == '\r' || input[0] == '\n':
+			clear()
+			if len(matches) == 0 {
+				return nil, errPickCancelled
+			}
+			return matches[cursor], nil
+		case input[0] == 3 || input[0] == 7 || bytes.Equal(input, []byte("\033")):
+			clear()
+			return nil, errPickCancelled
+		case input[0] == 127 || input[0] == 8:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				cursor = 0
+			}
+		case input[0] == 21:
+			query, cursor = nil, 0
+		case input[0] >= ' ' && input[0] != 127:
+			query = append(query, []rune(string(input))...)
+			cursor = 0
+		}
+	}
+}
+
+// pickCmd represents the pick command
+var pickCmd = &cobra.Command{
+	Use:   "pick [command [args...]]",
+	Short: "Pick a repository with a fuzzy finder and run a command for it.",
+	Long: `Pick a repository with fzf, or a built-in fuzzy finder when fzf isn't
+installed, and run a repos command for it with its name as the first argument,
+e.g. repos pick info. Without a command the name is printed, with --path the
+directory, as in:
+
+  cd "$(repos pick --path)"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		entries := client.List(repos.ListOptions{})
+		if len(entries) == 0 {
+			checkErr(fmt.Errorf("no repos configured"))
+		}
+		var picked *repos.ListEntry
+		if fzf, lookErr := exec.LookPath("fzf"); lookErr == nil {
+			picked, err = pickWithFzf(fzf, entries)
+		} else {
+			picked, err = pickBuiltin(entries)
+		}
+		if errors.Is(err, errPickCancelled) {
+			os.Exit(exitInterrupted)
+		}
+		checkErr(err)
+
+		switch {
+		case len(args) > 0:
+			self, err := os.Executable()
+			checkErr(err)
+			commandArgs := []string{"--config", cfgFile}
+			if workspace != "" {
+				commandArgs = append(commandArgs, "--workspace", workspace)
+			}
+			commandArgs = append(append(commandArgs, args[0], picked.Name), args[1:]...)
+			command := exec.Command(self, commandArgs...)
+			command.Stdin, command.Stdout, command.Stderr = os.Stdin, os.Stdout, os.Stderr
+			err = command.Run()
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				os.Exit(exitErr.ExitCode())
+			}
+			checkErr(err)
+		case pickPath:
+			dir, err := client.Get(picked.Name)
+			checkErr(err)
+			fmt.Println(dir)
+		default:
+			fmt.Println(picked.Name)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pickCmd)
+
+	// Flags after the command are its own.
+	pickCmd.Flags().SetInterspersed(false)
+	pickCmd.Flags().BoolVar(&pickPath, "path", false, "Print the directory of the picked repository instead of its name.")
+	pickCmd.Flags().StringVarP(&pickQuery, "query", "Q", "", "Start with this query.")
+}
@@ -0,0 +1,74 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// pluginPrefix starts the names of the executables that extend repos, e.g.
+// repos-foo on PATH is run for repos foo.
+const pluginPrefix = "repos-"
+
+// findPlugin returns the executable for the subcommand in args when no
+// built-in command has that name, along with the arguments for it. The global
+// flags before the subcommand are parsed on the way.
+func findPlugin(args []string) (string, []string, bool) {
+	flags := pflag.NewFlagSet("repos", pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.SetOutput(io.Discard)
+	flags.AddFlagSet(rootCmd.PersistentFlags())
+	if err := flags.Parse(args); err != nil || flags.NArg() == 0 {
+		return "", nil, false
+	}
+	name := flags.Arg(0)
+	if strings.HasPrefix(name, "-") || strings.HasPrefix(name, "__") || name == "help" || name == "completion" {
+		return "", nil, false
+	}
+	for _, cmd := range rootCmd.Commands() {
+		if cmd.Name() == name || cmd.HasAlias(name) {
+			return "", nil, false
+		}
+	}
+	path, err := exec.LookPath(pluginPrefix + name)
+	if err != nil {
+		return "", nil, false
+	}
+	return path, flags.Args()[1:], true
+}
+
+// runPlugin runs a plugin with the location of the config in its environment:
+// REPOS_CONFIG is the config file, REPOS_WORKSPACE the selected workspace,
+// REPOS_ROOT the directory repo dirs are relative to and REPOS_VERBOSITY the
+// verbosity level.
+func runPlugin(path string, args []string) int {
+	initConfig()
+	cfgPath, err := filepath.Abs(cfgFile)
+	checkErr(err)
+	root, err := filepath.Abs(config.WorkspaceDir())
+	checkErr(err)
+	cmd := exec.Command(path, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(),
+		"REPOS_CONFIG="+cfgPath,
+		"REPOS_WORKSPACE="+workspace,
+		"REPOS_ROOT="+root,
+		"REPOS_VERBOSITY="+strconv.Itoa(verbosity()),
+	)
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	checkErr(err)
+	return exitOK
+}
@@ -0,0 +1,145 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var (
+	prListOptions    repos.PullRequestListOptions
+	prCreateOptions  repos.PullRequestOptions
+	prCreateBodyFile string
+	prJSON           bool
+)
+
+// printPullRequests prints the repo and url of every pull request, or all of
+// them as JSON with --json.
+func printPullRequests(pulls []*repos.PullRequest) {
+	if prJSON {
+		if pulls == nil {
+			pulls = []*repos.PullRequest{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		checkErr(encoder.Encode(pulls))
+		return
+	}
+	for _, pull := range pulls {
+		fmt.Printf("%s\t%s\n", pull.Repo, pull.URL)
+	}
+}
+
+// prCmd represents the pr command
+var prCmd = &cobra.Command{
+	Use:   "pr",
+	Short: "Manage pull requests of multiple repositories on their providers.",
+}
+
+// prCreateCmd represents the pr create command
+var prCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Push a branch and open a pull request for it in every repository.",
+	Long: `Push the branch of every repository where it has commits its base branch
+doesn't have and open a pull request, or merge request on GitLab, for it on
+the configured provider of the repository's host, e.g. after apply-file or
+replace:
+
+  repos pr create --branch fix/foo --title "Fix foo" --body-file body.md
+
+The base is the configured branch of each repository, or its default branch.
+Repositories with an open pull request for the branch keep it. The urls of the
+pull requests are printed at the end.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if prCreateBodyFile != "" {
+			var body []byte
+			var err error
+			if prCreateBodyFile == "-" {
+				body, err = io.ReadAll(os.Stdin)
+			} else {
+				body, err = os.ReadFile(prCreateBodyFile)
+			}
+			checkErr(err)
+			prCreateOptions.Body = string(body)
+		}
+		var options []repos.NewRepoManagerClientOptions
+		if prJSON {
+			// Keeps the summary out of the JSON.
+			options = append(options, repos.WithVerbosity(repos.VerbosityQuiet))
+		}
+		client, err := newRepoManager(options...)
+		checkErr(err)
+
+		pulls, err := client.CreatePullRequests(prCreateOptions)
+		printPullRequests(pulls)
+		checkErr(err)
+	},
+}
+
+// prListCmd represents the pr list command
+var prListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List open pull requests across the repositories with their review and CI states.",
+	Long: `List the open pull requests, or merge requests on GitLab, of the user of the
+provider token in every repository whose host has a provider configured, with
+their review and CI states, to follow a change across repositories. With
+--all every open pull request into the base branch of each repository is
+listed instead.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		pulls, listErr := client.ListPullRequests(prListOptions)
+		if pulls == nil {
+			checkErr(listErr)
+		}
+		if prJSON {
+			printPullRequests(pulls)
+		} else {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "REPO\tPR\tTITLE\tBRANCH\tREVIEW\tCHECKS\tURL")
+			for _, pull := range pulls {
+				title := pull.Title
+				if pull.Draft {
+					title += " (draft)"
+				}
+				fmt.Fprintf(w, "%s\t#%d\t%s\t%s\t%s\t%s\t%s\n", pull.Repo, pull.Number, title, pull.Branch,
+					pull.Review, pull.Checks, pull.URL)
+			}
+			checkErr(w.Flush())
+		}
+		checkErr(listErr)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(prCmd)
+	prCmd.AddCommand(prListCmd)
+	prCmd.AddCommand(prCreateCmd)
+
+	prCmd.PersistentFlags().BoolVar(&prJSON, "json", false, "Print the pull requests as JSON.")
+	prCreateCmd.Flags().StringVar(&prCreateOptions.Branch, "branch", "", "Branch to open pull requests for, defaults to the checked out one.")
+	prCreateCmd.Flags().StringVar(&prCreateOptions.Base, "base", "", "Branch to merge into, defaults to the configured or default branch.")
+	prCreateCmd.Flags().StringVarP(&prCreateOptions.Title, "title", "t", "", "Title of the pull requests.")
+	prCreateCmd.Flags().StringVarP(&prCreateOptions.Body, "body", "b", "", "Description of the pull requests.")
+	prCreateCmd.Flags().StringVarP(&prCreateBodyFile, "body-file", "F", "", "Read the description from a file, - for stdin.")
+	prCreateCmd.Flags().BoolVarP(&prCreateOptions.Draft, "draft", "d", false, "Open the pull requests as drafts.")
+	prCreateCmd.Flags().StringSliceVar(&prCreateOptions.Select.Groups, "group", nil, "Only open pull requests in repos in these groups.")
+	prCreateCmd.Flags().StringSliceVar(&prCreateOptions.Select.Only, "only", nil, "Only open pull requests in the repos with these names.")
+	cobra.CheckErr(prCreateCmd.MarkFlagRequired("title"))
+	prListCmd.Flags().BoolVar(&prListOptions.All, "all", false, "List every open pull request into the base branch, not only yours.")
+	prListCmd.Flags().StringSliceVar(&prListOptions.Select.Groups, "group", nil, "Only list pull requests of repos in these groups.")
+	prListCmd.Flags().StringSliceVar(&prListOptions.Select.Only, "only", nil, "Only list pull requests of the repos with these names.")
+	registerSelectCompletions(prListCmd)
+	registerSelectCompletions(prCreateCmd)
+}
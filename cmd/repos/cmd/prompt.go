@@ -0,0 +1,80 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var (
+	promptMaxAge  time.Duration
+	promptRefresh bool
+)
+
+// refreshPromptInBackground starts repos prompt --refresh without waiting
+// for it.
+func refreshPromptInBackground() error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	args := []string{"--config", cfgFile, "--quiet"}
+	if workspace != "" {
+		args = append(args, "--workspace", workspace)
+	}
+	command := exec.Command(self, append(args, "prompt", "--refresh")...)
+	if err := command.Start(); err != nil {
+		return err
+	}
+	return command.Process.Release()
+}
+
+// promptCmd represents the prompt command
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Print a terse summary of the workspace for shell prompts.",
+	Long: `Print how many repositories are dirty, ahead of or behind their upstream or
+failed, e.g. "3 dirty, 1 behind", or nothing when all is well, for PS1 or a
+starship custom module:
+
+  PS1='$(repos prompt) \$ '
+
+The summary is read from a cache, so the prompt stays fast. When the cache is
+older than --max-age it is refreshed in the background for the next prompt.
+Refreshing only looks at the repositories on disk and never fetches, ahead
+and behind are as of the last fetch. --refresh refreshes the cache right away.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager(repos.WithVerbosity(repos.VerbosityQuiet))
+		checkErr(err)
+
+		var summary *repos.PromptSummary
+		if promptRefresh {
+			summary, err = client.RefreshPrompt()
+			checkErr(err)
+		} else {
+			summary, err = client.CachedPrompt()
+			checkErr(err)
+			if (summary == nil || time.Since(summary.Time) > promptMaxAge) && client.ClaimPromptRefresh() {
+				checkErr(refreshPromptInBackground())
+			}
+		}
+		if summary != nil && summary.String() != "" {
+			fmt.Println(summary)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(promptCmd)
+
+	promptCmd.Flags().DurationVar(&promptMaxAge, "max-age", 30*time.Second, "Refresh the cached summary in the background when it is older than this.")
+	promptCmd.Flags().BoolVar(&promptRefresh, "refresh", false, "Refresh the cached summary now and print it.")
+}
@@ -20,29 +20,33 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var skipUnchanged bool
+
 // pullCmd represents the pull command
 var pullCmd = &cobra.Command{
 	Use:   "pull",
 	Short: "Perform git pull command of multiple repositories in batch.",
 	Run: func(cmd *cobra.Command, args []string) {
-		client, err := repos.NewRepoManager(
-			repos.WithVerbose(verbose),
-			repos.WithConfig(config),
-		)
-		if err != nil {
-			panic(err)
-		}
+		policy, err := repos.ParseBranchPolicy(branchPolicy)
+		checkErr(err)
+		detached, err := repos.ParseBranchPolicy(detachedPolicy)
+		checkErr(err)
+
+		client, err := newRepoManager(repos.WithBranchPolicy(policy), repos.WithDetachedPolicy(detached), repos.WithSkipUnchanged(skipUnchanged))
+		checkErr(err)
 
 		err = client.Pull()
-		if err != nil {
-			panic(err)
-		}
+		checkErr(err)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(pullCmd)
 
+	pullCmd.Flags().StringVar(&branchPolicy, "branch-policy", "", "What to do when a repo isn't on its configured branch: fail, skip or checkout.")
+	pullCmd.Flags().StringVar(&detachedPolicy, "detached-policy", "", "What to do when a repo has a detached HEAD: fail, skip or checkout.")
+	pullCmd.Flags().BoolVar(&skipUnchanged, "skip-unchanged", false, "Skip repos whose remote branches haven't moved since their last pull, checked with git ls-remote.")
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
@@ -16,26 +16,112 @@ limitations under the License.
 package cmd
 
 import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
 	"github.com/jerloo/repos"
 	"github.com/spf13/cobra"
 )
 
+var (
+	pushOptions repos.PushOptions
+	interactive bool
+)
+
+var (
+	stdin     = bufio.NewReader(os.Stdin)
+	confirmMu sync.Mutex
+)
+
+// confirm asks a yes or no question on the terminal, one at a time as repos
+// are worked on in parallel. Anything but y or yes is a no.
+func confirm(question string) bool {
+	confirmMu.Lock()
+	defer confirmMu.Unlock()
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := stdin.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// reviewer returns the review of --interactive: it prints what would be
+// pushed or committed for each repo and asks whether to action it, y(es),
+// n(o), a(ll), approving the rest, or q(uit), declining the rest.
+func reviewer(action string) func(repo, preview string) bool {
+	var all, quit bool
+	return func(repo, preview string) bool {
+		confirmMu.Lock()
+		defer confirmMu.Unlock()
+		if all || quit {
+			return all
+		}
+		fmt.Fprintf(os.Stderr, "== %s\n%s\n", repo, preview)
+		for {
+			fmt.Fprintf(os.Stderr, "%s %s? [y/n/a/q] ", action, repo)
+			answer, err := stdin.ReadString('\n')
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "y", "yes":
+				return true
+			case "n", "no":
+				return false
+			case "a", "all":
+				all = true
+				return true
+			case "q", "quit":
+				quit = true
+				return false
+			}
+			if err != nil {
+				quit = true
+				return false
+			}
+		}
+	}
+}
+
 // pushCmd represents the push command
 var pushCmd = &cobra.Command{
 	Use:   "push",
 	Short: "Perform git push command of multiple repositories in batch.",
 	Run: func(cmd *cobra.Command, args []string) {
-		client, err := repos.NewRepoManager(repos.WithVerbose(verbose), repos.WithConfig(config))
-		cobra.CheckErr(err)
+		policy, err := repos.ParseBranchPolicy(branchPolicy)
+		checkErr(err)
+		detached, err := repos.ParseBranchPolicy(detachedPolicy)
+		checkErr(err)
 
-		err = client.Push()
-		cobra.CheckErr(err)
+		options := []repos.NewRepoManagerClientOptions{repos.WithBranchPolicy(policy), repos.WithDetachedPolicy(detached)}
+		if interactive {
+			options = append(options, repos.WithReview(reviewer("Push")))
+		}
+		if pushOptions.ForceWithLease {
+			// The questions would end up in the middle of the live table.
+			options = append(options, repos.WithLiveTable(false))
+		}
+		client, err := newRepoManager(options...)
+		checkErr(err)
+
+		pushOptions.Confirm = func(repo string) bool {
+			return confirm(fmt.Sprintf("Force push %s?", repo))
+		}
+		err = client.Push(pushOptions)
+		checkErr(err)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(pushCmd)
 
+	pushCmd.Flags().StringVar(&branchPolicy, "branch-policy", "", "What to do when a repo isn't on its configured branch: fail, skip or checkout.")
+	pushCmd.Flags().StringVar(&detachedPolicy, "detached-policy", "", "What to do when a repo has a detached HEAD: fail, skip or checkout.")
+	pushCmd.Flags().BoolVar(&pushOptions.Tags, "tags", false, "Push tags too.")
+	pushCmd.Flags().BoolVar(&pushOptions.AllBranches, "all-branches", false, "Push every local branch instead of the checked out one.")
+	pushCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Show the commits and diffstat to push for every repo and ask before pushing it.")
+	pushCmd.Flags().BoolVar(&pushOptions.AllowLarge, "allow-large", false, "Push files over the max_file_size limit too.")
+	pushCmd.Flags().BoolVar(&pushOptions.ForceWithLease, "force-with-lease", false, "Overwrite remote branches that haven't moved since the last fetch, asking for every repo.")
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
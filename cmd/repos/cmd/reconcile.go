@@ -0,0 +1,72 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var reconcileDepth int
+
+// choose asks which of the actions to take on the terminal, keyed by their
+// first letter. Anything else skips.
+func choose(question string, actions ...repos.ReconcileAction) repos.ReconcileAction {
+	keys := make([]string, len(actions))
+	for i, action := range actions {
+		keys[i] = "[" + string(action[:1]) + "]" + string(action[1:])
+	}
+	confirmMu.Lock()
+	defer confirmMu.Unlock()
+	fmt.Fprintf(os.Stderr, "%s %s, [s]kip? ", question, strings.Join(keys, ", "))
+	answer, _ := stdin.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	for _, action := range actions {
+		if answer != "" && strings.HasPrefix(string(action), answer) {
+			return action
+		}
+	}
+	return repos.ReconcileSkip
+}
+
+// reconcileCmd represents the reconcile command
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "List git repositories missing from the config and configured ones missing on disk.",
+	Long: `List the git repositories in the workspace that aren't in the config, orphans,
+and the configured repositories whose directory doesn't exist, missing ones.
+On a terminal, asks whether to adopt or delete each orphan and whether to
+clone each missing repo or remove it from the config.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		opts := repos.ReconcileOptions{Depth: reconcileDepth}
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			opts.Orphan = func(dir string) repos.ReconcileAction {
+				action := choose(dir+":", repos.ReconcileAdopt, repos.ReconcileRemove)
+				if action == repos.ReconcileRemove && !confirm(fmt.Sprintf("Delete %s and everything in it?", dir)) {
+					return repos.ReconcileSkip
+				}
+				return action
+			}
+			opts.Missing = func(repo string) repos.ReconcileAction {
+				return choose(repo+":", repos.ReconcileClone, repos.ReconcileRemove)
+			}
+		}
+		err = client.Reconcile(opts)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reconcileCmd)
+
+	reconcileCmd.Flags().IntVar(&reconcileDepth, "depth", 3, "How many directory levels to search for orphans.")
+}
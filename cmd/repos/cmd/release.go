@@ -0,0 +1,47 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var releaseOptions repos.ReleaseOptions
+
+// releaseCmd represents the release command
+var releaseCmd = &cobra.Command{
+	Use:   "release <tag>",
+	Short: "Tag and push a release of multiple repositories, all or none.",
+	Long: `Tag HEAD of the selected repositories, push the tag and optionally create a
+release on the provider of each, e.g.:
+
+  repos release v1.4.0 --group platform --create-release
+
+Every repository must be clean and in sync with its upstream branch, nothing
+is tagged otherwise. When a repository fails, the releases, pushed tags and
+tags created so far are deleted again.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		err = client.Release(args[0], releaseOptions)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(releaseCmd)
+
+	releaseCmd.Flags().StringSliceVar(&releaseOptions.Select.Groups, "group", nil, "Only release repos in these groups.")
+	releaseCmd.Flags().StringSliceVar(&releaseOptions.Select.Only, "only", nil, "Only release the repos with these names.")
+	releaseCmd.Flags().StringVarP(&releaseOptions.Message, "message", "m", "", "Message of the annotated tags, defaults to the tag name.")
+	releaseCmd.Flags().BoolVarP(&releaseOptions.Sign, "sign", "s", false, "Create GPG-signed tags.")
+	releaseCmd.Flags().BoolVar(&releaseOptions.CreateRelease, "create-release", false, "Also create a release on the configured provider of each repo's host.")
+	releaseCmd.Flags().StringVar(&releaseOptions.Notes, "notes", "", "Description of the provider releases.")
+	releaseCmd.Flags().BoolVar(&releaseOptions.Draft, "draft", false, "Create the provider releases as drafts.")
+	releaseCmd.Flags().BoolVar(&releaseOptions.Prerelease, "prerelease", false, "Mark the provider releases as prereleases.")
+	registerSelectCompletions(releaseCmd)
+}
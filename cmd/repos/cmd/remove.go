@@ -4,7 +4,6 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
-	"github.com/jerloo/repos"
 	"github.com/spf13/cobra"
 )
 
@@ -13,13 +12,11 @@ var removeCmd = &cobra.Command{
 	Use:   "remove",
 	Short: "Remove a repository.",
 	Run: func(cmd *cobra.Command, args []string) {
-		client, err := repos.NewRepoManager(repos.WithVerbose(verbose),
-			repos.WithConfig(config),
-		)
-		cobra.CheckErr(err)
+		client, err := newRepoManager()
+		checkErr(err)
 
 		err = client.Remove(args[0])
-		cobra.CheckErr(err)
+		checkErr(err)
 	},
 }
 
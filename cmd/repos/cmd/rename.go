@@ -0,0 +1,32 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var renameOptions repos.RenameOptions
+
+// renameCmd represents the rename command
+var renameCmd = &cobra.Command{
+	Use:               "rename <old> <new>",
+	Short:             "Rename a repository in the config.",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeFirstRepoName,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		err = client.Rename(args[0], args[1], renameOptions)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(renameCmd)
+
+	renameCmd.Flags().BoolVar(&renameOptions.MoveDir, "move-dir", false, "Also rename the directory of the repository.")
+}
@@ -0,0 +1,49 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var (
+	replaceOptions     repos.ReplaceOptions
+	replacePattern     string
+	replaceWith        string
+	replaceMessageFile string
+)
+
+// replaceCmd represents the replace command
+var replaceCmd = &cobra.Command{
+	Use:   "replace --pattern <regexp> --with <replacement>",
+	Short: "Replace a regular expression in tracked files of multiple repositories in batch.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		message, err := readMessage(replaceOptions.Message, replaceMessageFile)
+		checkErr(err)
+		replaceOptions.Message = message
+		client, err := newRepoManager()
+		checkErr(err)
+
+		err = client.Replace(replacePattern, replaceWith, replaceOptions)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replaceCmd)
+
+	replaceCmd.Flags().StringVar(&replacePattern, "pattern", "", "Regular expression to replace, in Go syntax.")
+	replaceCmd.Flags().StringVar(&replaceWith, "with", "", "Replacement, $1 refers to the first group.")
+	replaceCmd.Flags().StringArrayVarP(&replaceOptions.Globs, "glob", "g", nil, "Only replace in files matching the glob, e.g. '**/*.go'.")
+	replaceCmd.Flags().StringSliceVar(&replaceOptions.Select.Groups, "group", nil, "Only replace in repositories in these groups.")
+	replaceCmd.Flags().StringSliceVar(&replaceOptions.Select.Only, "only", nil, "Only replace in the repositories with these names.")
+	registerSelectCompletions(replaceCmd)
+	replaceCmd.Flags().BoolVarP(&replaceOptions.DryRun, "dry-run", "n", false, "Only print the diff.")
+	replaceCmd.Flags().BoolVar(&replaceOptions.Commit, "commit", false, "Commit the changes of every repository.")
+	replaceCmd.Flags().StringVarP(&replaceOptions.Message, "message", "m", "", "Commit message template with the variables of commit plus {{.Pattern}}, {{.With}} and {{.Files}}.")
+	replaceCmd.Flags().StringVarP(&replaceMessageFile, "message-file", "F", "", "Read the commit message template from a file.")
+	replaceCmd.MarkFlagRequired("pattern")
+}
@@ -0,0 +1,61 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var (
+	resetOptions repos.ResetOptions
+	resetAll     bool
+	resetYes     bool
+)
+
+// resetToRemoteCmd represents the reset-to-remote command
+var resetToRemoteCmd = &cobra.Command{
+	Use:   "reset-to-remote [name...]",
+	Short: "Reset diverged repositories to their upstream, keeping a backup.",
+	Long: `Fetch and hard-reset the checked out branch of the named repositories, or of
+every repository with --all, to its upstream, e.g. to recover checkouts whose
+local commits are throwaway:
+
+  repos reset-to-remote api web
+  repos reset-to-remote --all --group tools
+
+Only repositories with local commits or uncommitted changes are reset, each
+after confirming what would be discarded unless --yes is given. The local
+commits are kept on a repos-backup/<branch>-<time> branch and the changes,
+untracked files included, in a stash, so "repos stash pop" brings them back.`,
+	ValidArgsFunction: completeRepoNames,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 && !resetAll {
+			checkErr(fmt.Errorf("name the repos to reset or use --all"))
+		}
+		if len(args) > 0 && resetAll {
+			checkErr(fmt.Errorf("name the repos to reset or use --all, not both"))
+		}
+		resetOptions.Select.Only = append(resetOptions.Select.Only, args...)
+		client, err := newRepoManager()
+		checkErr(err)
+
+		resetOptions.Confirm = func(repo, discarded string) bool {
+			return resetYes || confirm(fmt.Sprintf("Reset %s to its upstream, discarding %s?", repo, discarded))
+		}
+		err = client.ResetToRemote(resetOptions)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(resetToRemoteCmd)
+
+	resetToRemoteCmd.Flags().BoolVar(&resetAll, "all", false, "Reset every repo, or every repo in --group.")
+	resetToRemoteCmd.Flags().BoolVarP(&resetYes, "yes", "y", false, "Reset without asking for every repo.")
+	resetToRemoteCmd.Flags().StringSliceVar(&resetOptions.Select.Groups, "group", nil, "With --all, only reset repos in these groups.")
+	cobra.CheckErr(resetToRemoteCmd.RegisterFlagCompletionFunc("group", completeGroups))
+}
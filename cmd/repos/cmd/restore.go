@@ -0,0 +1,43 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var restoreYes bool
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore <archive|manifest>",
+	Short: "Restore the config and repositories from a backup archive, or the commits of a snapshot manifest, into the workspace.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		if repos.IsManifest(args[0]) {
+			manifest, err := repos.LoadManifest(args[0])
+			checkErr(err)
+			checkErr(client.RestoreManifest(manifest))
+			return
+		}
+		err = client.Restore(args[0], repos.RestoreOptions{
+			Confirm: func(path string) bool {
+				return restoreYes || confirm(fmt.Sprintf("Write the included config file %s, outside the config directory?", path))
+			},
+		})
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+
+	restoreCmd.Flags().BoolVarP(&restoreYes, "yes", "y", false, "Write included config files from outside the config directory without asking.")
+}
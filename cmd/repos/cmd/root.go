@@ -16,9 +16,14 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
 
 	"github.com/jerloo/repos"
 	"github.com/spf13/cobra"
@@ -27,8 +32,23 @@ import (
 )
 
 var (
-	cfgFile string
-	verbose bool
+	cfgFile         string
+	defaultCfgFile  string
+	workspace       string
+	verbose         int
+	quiet           bool
+	noColor         bool
+	jobs            int
+	branchPolicy    string
+	detachedPolicy  string
+	includeDisabled bool
+	notify          string
+	failFast        bool
+	continueOnError bool
+	slowest         int
+	reportPath      string
+	reportFormat    string
+	logFile         string
 )
 
 var config *repos.ReposConfig
@@ -44,10 +64,89 @@ var rootCmd = &cobra.Command{
 	// },
 }
 
+// Exit codes of the CLI.
+const (
+	exitOK = iota
+	// exitFailed means the command failed in some of the repos.
+	exitFailed
+	// exitConfigError means the command couldn't run at all, e.g. because of
+	// an invalid config or missing credentials.
+	exitConfigError
+	// exitNoMatch means a search such as grep found nothing.
+	exitNoMatch
+	// exitInterrupted means the command was stopped by SIGINT or SIGTERM, as
+	// shells report it for SIGINT.
+	exitInterrupted = 130
+)
+
+// exitCode maps an error to the exit code scripts can rely on.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, repos.ErrReposFailed):
+		return exitFailed
+	case errors.Is(err, repos.ErrInterrupted):
+		return exitInterrupted
+	case errors.Is(err, repos.ErrNoMatch):
+		return exitNoMatch
+	default:
+		return exitConfigError
+	}
+}
+
+// checkErr prints err and exits with its exit code, unless it is nil. Finding
+// nothing is told by the exit code only.
+func checkErr(err error) {
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, repos.ErrNoMatch) {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+	os.Exit(exitCode(err))
+}
+
+// interruptCtx is done once the user asks repos to stop.
+var interruptCtx = context.Background()
+
+// notifyInterrupt returns a context that is done on the first SIGINT or
+// SIGTERM, letting batch operations finish the repos in progress. A second
+// signal exits right away.
+func notifyInterrupt() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+		case <-ctx.Done():
+			return
+		}
+		fmt.Fprintln(os.Stderr, "Stopping after the repos in progress, interrupt again to quit now.")
+		cancel()
+		<-signals
+		os.Exit(exitInterrupted)
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// Unknown subcommands run the repos-<name> plugin on PATH if there is one.
 func Execute() {
-	cobra.CheckErr(rootCmd.Execute())
+	if plugin, args, ok := findPlugin(os.Args[1:]); ok {
+		os.Exit(runPlugin(plugin, args))
+	}
+	var stop context.CancelFunc
+	interruptCtx, stop = notifyInterrupt()
+	defer stop()
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(exitConfigError)
+	}
 }
 
 func init() {
@@ -61,7 +160,7 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
-	defaultCfgFile := filepath.Join(homeDir, ".repos.yaml")
+	defaultCfgFile = filepath.Join(homeDir, ".repos.yaml")
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", defaultCfgFile, "config file (default is $HOME/.repos.yaml)")
 
@@ -69,17 +168,91 @@ func init() {
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 
-	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Set verbose mode.")
+	rootCmd.PersistentFlags().CountVarP(&verbose, "verbose", "v", "Print more: -v adds a line per repo, -vv debug logs and -vvv git progress.")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Only print errors.")
+	rootCmd.PersistentFlags().IntVarP(&jobs, "jobs", "j", 0, "How many repositories to work on at once (default from config or 8).")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output, which is on when writing to a terminal.")
+	rootCmd.PersistentFlags().BoolVar(&includeDisabled, "include-disabled", false, "Also operate on repos disabled in the config.")
+	rootCmd.PersistentFlags().IntVar(&slowest, "slowest", 0, "List the N repos that took longest after batch operations, with an estimate of what they transferred.")
+	rootCmd.PersistentFlags().StringVar(&reportPath, "report", "", "Write a JSON report of the outcome, time and estimated transfers of every repo to this file.")
+	rootCmd.PersistentFlags().StringVar(&reportFormat, "report-format", "", "Write the --report as csv, tsv or with a Go template run for every repo, e.g. '{{.Name}} {{.Outcome}}', instead of as JSON.")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Also write JSON logs to this file, rotated at 10MB with 5 old files kept.")
+	rootCmd.PersistentFlags().StringVar(&notify, "notify", "", "When to send a desktop notification after batch operations: never, always or failure (default from config or never).")
+	rootCmd.PersistentFlags().BoolVar(&failFast, "fail-fast", false, "Don't start any more repos once one failed (default from the on_error config).")
+	rootCmd.PersistentFlags().BoolVar(&continueOnError, "continue-on-error", false, "Work on every repo even when some fail, the default.")
+	rootCmd.PersistentFlags().StringVarP(&workspace, "workspace", "w", "", "Use the named workspace of the config file.")
+	cobra.CheckErr(rootCmd.RegisterFlagCompletionFunc("workspace", completeWorkspaces))
+}
+
+// newRepoManager creates a RepoManager for the loaded config with the options
+// of the global flags applied before the given ones.
+func newRepoManager(options ...repos.NewRepoManagerClientOptions) (*repos.RepoManager, error) {
+	notifyPolicy, err := repos.ParseNotifyPolicy(notify)
+	if err != nil {
+		return nil, err
+	}
+	var errorPolicy repos.ErrorPolicy
+	switch {
+	case failFast && continueOnError:
+		return nil, fmt.Errorf("--fail-fast and --continue-on-error can't be combined")
+	case failFast:
+		errorPolicy = repos.ErrorFailFast
+	case continueOnError:
+		errorPolicy = repos.ErrorContinue
+	}
+	return repos.NewRepoManager(append([]repos.NewRepoManagerClientOptions{
+		repos.WithVerbosity(verbosity()),
+		repos.WithConfig(config),
+		repos.WithIncludeDisabled(includeDisabled),
+		repos.WithColor(useColor()),
+		repos.WithLiveTable(isTerminal()),
+		repos.WithJobs(jobs),
+		repos.WithNotify(notifyPolicy),
+		repos.WithErrorPolicy(errorPolicy),
+		repos.WithSlowest(slowest),
+		repos.WithReport(reportPath),
+		repos.WithReportFormat(reportFormat),
+		repos.WithLogFile(logFile),
+		repos.WithContext(interruptCtx),
+	}, options...)...)
+}
+
+func verbosity() int {
+	if quiet {
+		return repos.VerbosityQuiet
+	}
+	return verbose
+}
+
+// useColor reports whether output should be colored: stdout is a terminal and
+// neither --no-color nor the NO_COLOR environment variable is set.
+func useColor() bool {
+	if noColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTerminal()
+}
+
+// isTerminal reports whether stdout is a terminal, so output can be redrawn
+// in place.
+func isTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
 
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
 	if cfgFile == "" {
 		home, err := os.Getwd()
-		cobra.CheckErr(err)
+		checkErr(err)
 		cfgFile = filepath.Join(home, ".repos.yaml")
 	}
-	fmt.Println("Using config file:", cfgFile)
+	if cfgFile == defaultCfgFile {
+		cfgFile = findConfigFile(cfgFile)
+	}
+	if verbosity() > 0 {
+		fmt.Fprintln(os.Stderr, "Using config file:", cfgFile)
+	}
 	viper.SetConfigFile(cfgFile)
 
 	viper.AutomaticEnv() // read in environment variables that match
@@ -87,11 +260,19 @@ func initConfig() {
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
 		// fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
-		err := viper.Unmarshal(&config)
-		if err != nil {
-			panic(err)
+		backup, err := repos.MigrateConfig()
+		checkErr(err)
+		if backup != "" && !quiet {
+			fmt.Fprintf(os.Stderr, "Migrated config file to version %s, the original is kept at %s\n", repos.ConfigVersion, backup)
 		}
-		config.CfgFile = cfgFile
+		config, err = repos.LoadConfigFile(cfgFile)
+		checkErr(err)
+		err = config.LoadIncludes()
+		checkErr(err)
+		err = config.LoadNested()
+		checkErr(err)
+		err = config.DecryptSecrets()
+		checkErr(err)
 	}
 	if config == nil {
 		config = &repos.ReposConfig{
@@ -99,9 +280,32 @@ func initConfig() {
 		}
 	}
 	if config.Version == "" {
-		config.Version = "1"
+		config.Version = repos.ConfigVersion
 	}
 	if config.Repos == nil {
 		config.Repos = make(map[string]*repos.RepoConfig)
 	}
+	if workspace != "" {
+		workspaceConfig, err := config.Workspace(workspace)
+		checkErr(err)
+		config = workspaceConfig
+	}
+	for name, repoConfig := range config.Repos {
+		repoConfig.Name = name
+	}
+}
+
+// findConfigFile falls back to a .yml, .toml or .json sibling when the default
+// YAML config file doesn't exist.
+func findConfigFile(cfgFile string) string {
+	if _, err := os.Stat(cfgFile); err == nil {
+		return cfgFile
+	}
+	base := strings.TrimSuffix(cfgFile, filepath.Ext(cfgFile))
+	for _, ext := range []string{".yml", ".toml", ".json"} {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext
+		}
+	}
+	return cfgFile
 }
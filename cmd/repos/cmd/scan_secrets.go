@@ -0,0 +1,76 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scanSecretsOptions repos.SecretScanOptions
+	scanSecretsJSON    bool
+)
+
+// scanSecretsCmd represents the scan-secrets command
+var scanSecretsCmd = &cobra.Command{
+	Use:   "scan-secrets",
+	Short: "Look for leaked keys, tokens and passwords in the repositories.",
+	Long: `Scan the tracked files of the repositories, and with --history the lines added
+by their latest commits, for private keys, cloud and provider tokens and
+password or key assignments with random looking values, e.g. before pushing
+them all:
+
+  repos scan-secrets && repos push
+
+Paths listed in a .secretsignore file in the workspace or a repository are
+skipped: a pattern without a slash matches file names anywhere, one ending in a
+slash a directory, others paths from the repository root. Lines containing
+repos:allow-secret are skipped too.
+
+The exit code is 0 when nothing was found, 1 when secrets were found or
+repositories couldn't be scanned and 2 when the scan couldn't run at all.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		findings, scanErr := client.ScanSecrets(scanSecretsOptions)
+		if findings == nil {
+			checkErr(scanErr)
+		}
+
+		if scanSecretsJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			checkErr(encoder.Encode(findings))
+		} else {
+			for _, finding := range findings {
+				location := fmt.Sprintf("%s:%s:%d", finding.Repo, finding.File, finding.Line)
+				if finding.Commit != "" {
+					location += " in " + shortHash(finding.Commit)
+				}
+				fmt.Printf("%s: %s %s\n", location, finding.Rule, finding.Secret)
+			}
+		}
+		checkErr(scanErr)
+		if len(findings) > 0 {
+			fmt.Fprintf(os.Stderr, "found %d possible secret(s)\n", len(findings))
+			os.Exit(exitFailed)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(scanSecretsCmd)
+
+	scanSecretsCmd.Flags().IntVar(&scanSecretsOptions.History, "history", 0, "Also scan the lines added by this many of the latest commits.")
+	scanSecretsCmd.Flags().BoolVar(&scanSecretsJSON, "json", false, "Print the findings as JSON.")
+	scanSecretsCmd.Flags().StringSliceVar(&scanSecretsOptions.Select.Groups, "group", nil, "Only scan repos in these groups.")
+	scanSecretsCmd.Flags().StringSliceVar(&scanSecretsOptions.Select.Only, "only", nil, "Only scan the repos with these names.")
+	registerSelectCompletions(scanSecretsCmd)
+}
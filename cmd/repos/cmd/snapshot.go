@@ -0,0 +1,46 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var snapshotOptions repos.ListOptions
+
+// snapshotCmd represents the snapshot command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Print a JSON manifest of the commit and branch every repository is at.",
+	Long: `Print a JSON manifest of the commit and branch every repository is at.
+
+Pass the manifest to restore to check out exactly those commits again, e.g. to
+reproduce a build or roll back a bad sync:
+
+  repos snapshot > manifest.json
+  repos restore manifest.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		manifest, err := client.Snapshot(snapshotOptions)
+		checkErr(err)
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		checkErr(encoder.Encode(manifest))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+
+	snapshotCmd.Flags().StringSliceVar(&snapshotOptions.Groups, "group", nil, "Only record repos in these groups.")
+	snapshotCmd.Flags().StringSliceVar(&snapshotOptions.Only, "only", nil, "Only record the repos with these names.")
+	registerSelectCompletions(snapshotCmd)
+}
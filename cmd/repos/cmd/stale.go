@@ -0,0 +1,64 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	staleDays int
+	staleJSON bool
+)
+
+// staleCmd represents the stale command
+var staleCmd = &cobra.Command{
+	Use:   "stale",
+	Short: "List repositories without upstream commits or local activity for a while.",
+	Long: `List the repositories whose upstream branch got no new commits and that saw no
+local commits, checkouts or uncommitted changes within the last --days, the
+least recently active first. They are candidates for archiving or removing
+from the workspace. The upstream is as of the last fetch.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		entries, err := client.Stale(staleDays)
+		checkErr(err)
+
+		if staleJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			checkErr(encoder.Encode(entries))
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, entry := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Name, daysAgo(entry.LastActivity()),
+				"upstream "+daysAgo(entry.LastUpstream), "local "+daysAgo(entry.LastLocal))
+		}
+		checkErr(w.Flush())
+	},
+}
+
+// daysAgo shows how long ago t was in days, or never for a zero time.
+func daysAgo(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return fmt.Sprintf("%dd ago", int(time.Since(t).Hours()/24))
+}
+
+func init() {
+	rootCmd.AddCommand(staleCmd)
+
+	staleCmd.Flags().IntVar(&staleDays, "days", 90, "How many days without activity make a repository stale.")
+	staleCmd.Flags().BoolVar(&staleJSON, "json", false, "Print the stale repositories as JSON.")
+}
@@ -0,0 +1,46 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var stashMessage string
+
+// stashCmd represents the stash command
+var stashCmd = &cobra.Command{
+	Use:       "stash [pop|list]",
+	Short:     "Stash or restore local changes of multiple repositories in batch.",
+	ValidArgs: []string{"pop", "list"},
+	Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		action := ""
+		if len(args) > 0 {
+			action = args[0]
+		}
+		switch action {
+		case "":
+			err = client.Stash(stashMessage)
+		case "pop":
+			err = client.StashPop()
+		case "list":
+			err = client.StashList()
+		default:
+			err = fmt.Errorf("unknown stash action %q", action)
+		}
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(stashCmd)
+
+	stashCmd.Flags().StringVarP(&stashMessage, "message", "m", "", "Label of the stash entries, defaults to the current time.")
+}
@@ -0,0 +1,97 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsOptions repos.ContributorOptions
+	statsByRepo  bool
+	statsJSON    bool
+	statsCSV     bool
+	statsOutput  string
+)
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show commits and changed lines per author across the repositories.",
+	Long: `Show the commits and the lines added and deleted per author across the checked
+out branches of the repositories, merges left out, e.g. for a retrospective:
+
+  repos stats --since 2024-01-01 --by-repo
+
+Authors are told apart by email, after applying the .mailmap of each repo.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		output, err := repos.ParseOutput(statsOutput)
+		checkErr(err)
+		if statsCSV {
+			if output != "" && output != repos.OutputCSV {
+				checkErr(fmt.Errorf("--csv and --output %s can't be combined", output))
+			}
+			output = repos.OutputCSV
+		}
+		if statsJSON && output != "" {
+			checkErr(fmt.Errorf("--json and --%s can't be combined", output))
+		}
+		client, err := newRepoManager()
+		checkErr(err)
+
+		stats, err := client.Contributors(statsOptions)
+		checkErr(err)
+
+		switch {
+		case statsJSON:
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			checkErr(encoder.Encode(stats))
+		case output != "":
+			var rows [][]string
+			for _, author := range stats {
+				for _, repo := range author.Repos {
+					rows = append(rows, []string{author.Name, author.Email, repo.Repo,
+						strconv.Itoa(repo.Commits), strconv.Itoa(repo.Added), strconv.Itoa(repo.Deleted)})
+				}
+			}
+			header := []string{"name", "email", "repo", "commits", "added", "deleted"}
+			checkErr(repos.WriteDelimited(os.Stdout, output, header, rows))
+		default:
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "AUTHOR\tCOMMITS\tADDED\tDELETED\tREPOS")
+			for _, author := range stats {
+				fmt.Fprintf(w, "%s <%s>\t%d\t+%d\t-%d\t%d\n", author.Name, author.Email, author.Commits, author.Added, author.Deleted, len(author.Repos))
+				if statsByRepo {
+					for _, repo := range author.Repos {
+						fmt.Fprintf(w, "  %s\t%d\t+%d\t-%d\t\n", repo.Repo, repo.Commits, repo.Added, repo.Deleted)
+					}
+				}
+			}
+			checkErr(w.Flush())
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().StringVar(&statsOptions.Since, "since", "", "Only count commits newer than this, e.g. 2024-01-01, 4w or 3m.")
+	statsCmd.Flags().StringVar(&statsOptions.Until, "until", "", "Only count commits older than this, in the same formats as --since.")
+	statsCmd.Flags().StringSliceVar(&statsOptions.Select.Groups, "group", nil, "Only count repos in these groups.")
+	statsCmd.Flags().StringSliceVar(&statsOptions.Select.Only, "only", nil, "Only count the repos with these names.")
+	statsCmd.Flags().BoolVar(&statsByRepo, "by-repo", false, "Break the numbers of every author down per repository.")
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "Print the statistics as JSON.")
+	statsCmd.Flags().BoolVar(&statsCSV, "csv", false, "Print a CSV row per author and repository, the same as --output csv.")
+	statsCmd.Flags().StringVarP(&statsOutput, "output", "o", "", "Print a csv or tsv row per author and repository.")
+	cobra.CheckErr(statsCmd.RegisterFlagCompletionFunc("output", completeOutput))
+	registerSelectCompletions(statsCmd)
+}
@@ -4,36 +4,93 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
 	"github.com/jerloo/repos"
 	"github.com/spf13/cobra"
 )
 
+var (
+	statusOptions repos.StatusOptions
+	statusSort    string
+	statusFormat  string
+	statusOutput  string
+)
+
 // statusCmd represents the status command
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Status of all repos",
+	Long: `Show whether every repository is clean, its checked out branch and how far it is
+ahead of or behind its upstream.
+
+--dirty, --behind, --ahead and --failed only show the repositories that need
+attention for that reason, any of them when combined. --sort status lists
+failed repositories first, then dirty ones, then those ahead or behind, and
+--sort last-commit the ones with the newest commit first.
+
+--format prints every repository with a Go template instead, e.g.:
+
+  repos status --format '{{.Name}} {{.Head}} {{if not .Clean}}*{{end}}'
+
+The fields are Name, Dir, Branch, Commit, Head, Clean, Mirror, Upstream,
+Ahead, Behind, LastCommit, Worktree, OriginDiffers and Error. --output csv
+or tsv prints a row per repository for spreadsheets.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		client, err := repos.NewRepoManager(
-			repos.WithVerbose(verbose),
-			repos.WithConfig(config),
-		)
-		cobra.CheckErr(err)
-
-		err = client.Status()
-		cobra.CheckErr(err)
+		order, err := repos.ParseStatusSort(statusSort)
+		checkErr(err)
+		statusOptions.Sort = order
+		output, err := repos.ParseOutput(statusOutput)
+		checkErr(err)
+		if output != "" && statusFormat != "" {
+			checkErr(fmt.Errorf("--output and --format can't be combined"))
+		}
+		client, err := newRepoManager()
+		checkErr(err)
+
+		if output != "" {
+			var rows [][]string
+			for _, entry := range client.StatusEntries(statusOptions) {
+				lastCommit := ""
+				if !entry.LastCommit.IsZero() {
+					lastCommit = entry.LastCommit.Format(time.RFC3339)
+				}
+				rows = append(rows, []string{entry.Name, entry.Dir, entry.Branch, entry.Commit,
+					strconv.FormatBool(entry.Clean), entry.Upstream, strconv.Itoa(entry.Ahead),
+					strconv.Itoa(entry.Behind), lastCommit, entry.Error})
+			}
+			header := []string{"name", "dir", "branch", "commit", "clean", "upstream", "ahead", "behind", "last_commit", "error"}
+			checkErr(repos.WriteDelimited(os.Stdout, output, header, rows))
+			return
+		}
+		if statusFormat != "" {
+			tmpl, err := repos.FormatTemplate(statusFormat)
+			checkErr(err)
+			for _, entry := range client.StatusEntries(statusOptions) {
+				checkErr(tmpl.Execute(os.Stdout, entry))
+			}
+			return
+		}
+		err = client.Status(statusOptions)
+		checkErr(err)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(statusCmd)
 
-	// Here you will define your flags and configuration settings.
-
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// statusCmd.PersistentFlags().String("foo", "", "A help for foo")
-
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// statusCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	statusCmd.Flags().BoolVar(&statusOptions.Dirty, "dirty", false, "Only show repos with uncommitted changes.")
+	statusCmd.Flags().BoolVar(&statusOptions.Behind, "behind", false, "Only show repos behind their upstream.")
+	statusCmd.Flags().BoolVar(&statusOptions.Ahead, "ahead", false, "Only show repos ahead of their upstream.")
+	statusCmd.Flags().BoolVar(&statusOptions.Failed, "failed", false, "Only show repos whose status couldn't be read.")
+	statusCmd.Flags().StringVar(&statusSort, "sort", "", "Order the repos by name, status or last-commit.")
+	statusCmd.Flags().StringVar(&statusFormat, "format", "", "Print every repository with a Go template, e.g. '{{.Name}} {{.Head}}'.")
+	statusCmd.Flags().StringVarP(&statusOutput, "output", "o", "", "Print the statuses as csv or tsv.")
+	cobra.CheckErr(statusCmd.RegisterFlagCompletionFunc("output", completeOutput))
+	cobra.CheckErr(statusCmd.RegisterFlagCompletionFunc("sort", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"name", "status", "last-commit"}, cobra.ShellCompDirectiveNoFileComp
+	}))
 }
@@ -20,22 +20,41 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var syncStrategy string
+
 // syncCmd represents the sync command
 var syncCmd = &cobra.Command{
 	Use:   "sync",
 	Short: "Perform git pull && git push command of multiple repositories in batch.",
 	Run: func(cmd *cobra.Command, args []string) {
-		client, err := repos.NewRepoManager(repos.WithVerbose(verbose), repos.WithConfig(config))
-		cobra.CheckErr(err)
+		policy, err := repos.ParseBranchPolicy(branchPolicy)
+		checkErr(err)
+		detached, err := repos.ParseBranchPolicy(detachedPolicy)
+		checkErr(err)
+
+		strategy, err := repos.ParseSyncStrategy(syncStrategy)
+		checkErr(err)
+
+		options := []repos.NewRepoManagerClientOptions{repos.WithBranchPolicy(policy), repos.WithDetachedPolicy(detached), repos.WithSyncStrategy(strategy)}
+		if interactive {
+			options = append(options, repos.WithReview(reviewer("Sync")))
+		}
+		client, err := newRepoManager(options...)
+		checkErr(err)
 
 		err = client.Sync()
-		cobra.CheckErr(err)
+		checkErr(err)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(syncCmd)
 
+	syncCmd.Flags().StringVar(&branchPolicy, "branch-policy", "", "What to do when a repo isn't on its configured branch: fail, skip or checkout.")
+	syncCmd.Flags().StringVar(&detachedPolicy, "detached-policy", "", "What to do when a repo has a detached HEAD: fail, skip or checkout.")
+	syncCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Show the commits and diffstat to push for every repo and ask before syncing it.")
+	syncCmd.Flags().StringVar(&syncStrategy, "strategy", "", "How to sync: pull-push, pull-rebase-push, fetch-ff-only-push or push-only-if-ahead.")
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
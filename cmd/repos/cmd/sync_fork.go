@@ -0,0 +1,43 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var syncForkOptions repos.SyncForkOptions
+
+// syncForkCmd represents the sync-fork command
+var syncForkCmd = &cobra.Command{
+	Use:   "sync-fork",
+	Short: "Update the default branch of forks from their upstream and push it to origin.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		policy, err := repos.ParseBranchPolicy(branchPolicy)
+		checkErr(err)
+		detached, err := repos.ParseBranchPolicy(detachedPolicy)
+		checkErr(err)
+
+		client, err := newRepoManager(repos.WithBranchPolicy(policy), repos.WithDetachedPolicy(detached))
+		checkErr(err)
+
+		syncForkOptions.Confirm = func(repo string) bool {
+			return confirm(fmt.Sprintf("%s diverged from origin after the rebase, force push it?", repo))
+		}
+		err = client.SyncFork(syncForkOptions)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncForkCmd)
+
+	syncForkCmd.Flags().StringVar(&branchPolicy, "branch-policy", "", "What to do when a repo isn't on its configured branch: fail, skip or checkout.")
+	syncForkCmd.Flags().StringVar(&detachedPolicy, "detached-policy", "", "What to do when a repo has a detached HEAD: fail, skip or checkout.")
+	syncForkCmd.Flags().BoolVar(&syncForkOptions.Rebase, "rebase", false, "Rebase the branch onto upstream instead of fast-forwarding it.")
+}
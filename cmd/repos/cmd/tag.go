@@ -0,0 +1,34 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"github.com/jerloo/repos"
+	"github.com/spf13/cobra"
+)
+
+var tagOptions repos.TagOptions
+
+// tagCmd represents the tag command
+var tagCmd = &cobra.Command{
+	Use:   "tag <name>",
+	Short: "Create a tag at HEAD of multiple repositories in batch.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		err = client.Tag(args[0], tagOptions)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+
+	tagCmd.Flags().BoolVar(&tagOptions.Push, "push", false, "Push the tag to origin.")
+	tagCmd.Flags().BoolVarP(&tagOptions.Annotate, "annotate", "a", false, "Create an annotated tag.")
+	tagCmd.Flags().BoolVarP(&tagOptions.Sign, "sign", "s", false, "Create a GPG-signed tag.")
+	tagCmd.Flags().StringVarP(&tagOptions.Message, "message", "m", "", "Message of an annotated tag, defaults to the tag name.")
+}
@@ -0,0 +1,25 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// unpushedCmd represents the unpushed command
+var unpushedCmd = &cobra.Command{
+	Use:   "unpushed",
+	Short: "List local commits not on the upstream branch of multiple repositories in batch.",
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		err = client.Unpushed()
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(unpushedCmd)
+}
@@ -0,0 +1,29 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var unshallowDeepen int
+
+// unshallowCmd represents the unshallow command
+var unshallowCmd = &cobra.Command{
+	Use:   "unshallow",
+	Short: "Fetch the full history of shallow repositories.",
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := newRepoManager()
+		checkErr(err)
+
+		err = client.Unshallow(unshallowDeepen)
+		checkErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(unshallowCmd)
+
+	unshallowCmd.Flags().IntVar(&unshallowDeepen, "deepen", 0, "Only fetch this many more commits instead of the full history.")
+}
@@ -0,0 +1,99 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Build metadata, set with e.g.
+//
+//	go build -ldflags "-X github.com/jerloo/repos/cmd/repos/cmd.version=v1.2.3"
+//
+// When they're unset, the module version and the VCS stamp of the build info
+// are used.
+var (
+	version = ""
+	commit  = ""
+	date    = ""
+)
+
+// buildInfo is the build metadata printed by version.
+type buildInfo struct {
+	Version  string
+	Commit   string
+	Date     string
+	GoGit    string
+	Go       string
+	Platform string
+}
+
+func currentBuildInfo() buildInfo {
+	info := buildInfo{
+		Version:  version,
+		Commit:   commit,
+		Date:     date,
+		Go:       runtime.Version(),
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && build.Main.Version != "(devel)" {
+			info.Version = build.Main.Version
+		}
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.Date == "":
+				info.Date = setting.Value
+			case setting.Key == "vcs.modified" && setting.Value == "true" && commit == "" && info.Commit != "":
+				info.Commit += "-dirty"
+			}
+		}
+		for _, dep := range build.Deps {
+			if dep.Path == "github.com/go-git/go-git/v5" {
+				info.GoGit = dep.Version
+			}
+		}
+	}
+	for _, field := range []*string{&info.Version, &info.Commit, &info.Date, &info.GoGit} {
+		if *field == "" {
+			*field = "unknown"
+		}
+	}
+	return info
+}
+
+func (info buildInfo) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "version:   %s\n", info.Version)
+	fmt.Fprintf(&b, "commit:    %s\n", info.Commit)
+	fmt.Fprintf(&b, "built:     %s\n", info.Date)
+	fmt.Fprintf(&b, "go-git:    %s\n", info.GoGit)
+	fmt.Fprintf(&b, "go:        %s\n", info.Go)
+	fmt.Fprintf(&b, "platform:  %s\n", info.Platform)
+	return b.String()
+}
+
+// versionCmd represents the version command
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the version and build details, for bug reports.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(currentBuildInfo())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+
+	rootCmd.Version = currentBuildInfo().Version
+	rootCmd.SetVersionTemplate("{{.Name}} {{.Version}}\n")
+}
@@ -0,0 +1,42 @@
+package repos
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorReset  = "\033[0m"
+)
+
+// WithColor enables ANSI colors: green for clean repos and successes, yellow
+// for skipped or dirty repos and red for failures.
+func WithColor(color bool) NewRepoManagerClientOptions {
+	return func(client *RepoManager) {
+		client.color = color
+	}
+}
+
+func (client *RepoManager) paint(color string, value interface{}) string {
+	if !client.color {
+		return fmt.Sprint(value)
+	}
+	return color + fmt.Sprint(value) + colorReset
+}
+
+// paintResult colors the outcome of a repo: errors red, skips yellow and
+// anything else green.
+func (client *RepoManager) paintResult(value interface{}) string {
+	switch v := value.(type) {
+	case error:
+		return client.paint(colorRed, v)
+	case string:
+		if strings.HasPrefix(v, string(outcomeSkipped)) {
+			return client.paint(colorYellow, v)
+		}
+	}
+	return client.paint(colorGreen, value)
+}
@@ -0,0 +1,166 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// Logger receives the diagnostic messages of a RepoManager. Arguments after
+// the message are alternating keys and values, as with log/slog, so a
+// *slog.Logger can be passed to WithLogger as is.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+func WithLogger(logger Logger) NewRepoManagerClientOptions {
+	return func(client *RepoManager) {
+		client.logger = logger
+	}
+}
+
+// WithLogFile makes the default logger also write JSON logs to path, at
+// least at info level whatever the verbosity, rotating the file when it
+// grows past logFileMaxSize.
+func WithLogFile(path string) NewRepoManagerClientOptions {
+	return func(client *RepoManager) {
+		client.logFile = path
+	}
+}
+
+// newDefaultLogger logs to stderr. Quiet mode only shows errors, the default
+// adds warnings and each verbosity level the next lower slog level. With a
+// log file the logs also go there.
+func newDefaultLogger(verbosity int, logFile string) (Logger, error) {
+	level := slog.LevelWarn
+	switch {
+	case verbosity <= VerbosityQuiet:
+		level = slog.LevelError
+	case verbosity == 1:
+		level = slog.LevelInfo
+	case verbosity >= 2:
+		level = slog.LevelDebug
+	}
+	console := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})
+	if logFile == "" {
+		return slog.New(console), nil
+	}
+	file, err := openRotatingFile(expandPath(logFile))
+	if err != nil {
+		return nil, fmt.Errorf("log file: %w", err)
+	}
+	fileLevel := min(level, slog.LevelInfo)
+	return slog.New(teeHandler{console, slog.NewJSONHandler(file, &slog.HandlerOptions{Level: fileLevel})}), nil
+}
+
+// teeHandler passes records on to every handler that takes their level.
+type teeHandler []slog.Handler
+
+func (handlers teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (handlers teeHandler) Handle(ctx context.Context, record slog.Record) error {
+	for _, handler := range handlers {
+		if handler.Enabled(ctx, record.Level) {
+			if err := handler.Handle(ctx, record.Clone()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (handlers teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	tee := make(teeHandler, len(handlers))
+	for i, handler := range handlers {
+		tee[i] = handler.WithAttrs(attrs)
+	}
+	return tee
+}
+
+func (handlers teeHandler) WithGroup(name string) slog.Handler {
+	tee := make(teeHandler, len(handlers))
+	for i, handler := range handlers {
+		tee[i] = handler.WithGroup(name)
+	}
+	return tee
+}
+
+const (
+	// logFileMaxSize is the size past which the log file is rotated.
+	logFileMaxSize = 10 << 20
+	// logFileBackups is how many rotated log files are kept, as .1 for the
+	// newest up to .5.
+	logFileBackups = 5
+)
+
+// rotatingFile appends to a log file, moving it aside once it gets too big.
+type rotatingFile struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	size int64
+}
+
+func openRotatingFile(path string) (*rotatingFile, error) {
+	f := &rotatingFile{path: path}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// rotate shifts the backups up by one, dropping the oldest, and starts a new
+// file.
+func (f *rotatingFile) rotate() error {
+	f.file.Close()
+	for i := logFileBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+	}
+	if err := os.Rename(f.path, f.path+".1"); err != nil {
+		// Keep logging to the file as it is.
+		if openErr := f.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	return f.open()
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.size > 0 && f.size+int64(len(p)) > logFileMaxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
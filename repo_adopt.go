@@ -0,0 +1,214 @@
+package repos
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+func isGitRepo(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil || isBareRepo(dir)
+}
+
+// isBareRepo tells whether dir is a git dir itself, as mirrors are.
+func isBareRepo(dir string) bool {
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultDiscoveryIgnore are the directories never searched for repos, next
+// to the discovery_ignore patterns of the config.
+var defaultDiscoveryIgnore = []string{"node_modules", ".terraform"}
+
+// ignoredDir reports whether discovery skips dir, a directory below the
+// workspace: patterns with a slash match its path relative to the workspace,
+// the others its name.
+func (client *RepoManager) ignoredDir(dir string) bool {
+	rel, err := filepath.Rel(client.workspace, dir)
+	if err != nil {
+		rel = dir
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range append(defaultDiscoveryIgnore, client.config.DiscoveryIgnore...) {
+		name := path.Base(rel)
+		if strings.Contains(strings.Trim(pattern, "/"), "/") {
+			name = rel
+		}
+		if ok, _ := path.Match(strings.Trim(pattern, "/"), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// discoverRepos returns the git repos found below root, descending at most
+// depth directory levels, and the directories in which none were found.
+// Hidden and ignored directories are skipped and the walk doesn't descend
+// into repos.
+func (client *RepoManager) discoverRepos(root string, depth int) ([]string, []string, error) {
+	if isGitRepo(root) {
+		return []string{root}, nil, nil
+	}
+	if depth <= 0 {
+		return nil, []string{root}, nil
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	var found, nonGit []string
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		if client.ignoredDir(dir) {
+			client.logger.Debug("skipping ignored directory", "dir", dir)
+			continue
+		}
+		dirs, skipped, err := client.discoverRepos(dir, depth-1)
+		if err != nil {
+			client.logger.Warn("skipping unreadable directory", "dir", dir, "error", err)
+			nonGit = append(nonGit, dir)
+			continue
+		}
+		// A directory without any repo is reported rather than all of its
+		// subdirectories.
+		if len(dirs) == 0 {
+			nonGit = append(nonGit, dir)
+			continue
+		}
+		found = append(found, dirs...)
+		nonGit = append(nonGit, skipped...)
+	}
+	return found, nonGit, nil
+}
+
+// discover returns the git repos in the workspace and how many directories
+// were skipped for having none, warning about each.
+func (client *RepoManager) discover(depth int) ([]string, int, error) {
+	found, nonGit, err := client.discoverRepos(client.workspace, depth)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, dir := range nonGit {
+		client.logger.Warn("skipping directory that isn't a git repository", "dir", dir)
+	}
+	return found, len(nonGit), nil
+}
+
+// printSkippedDirs prints how many directories discovery skipped.
+func (client *RepoManager) printSkippedDirs(skipped int) {
+	if skipped == 0 || client.verbosity <= VerbosityQuiet {
+		return
+	}
+	noun := "directories"
+	if skipped == 1 {
+		noun = "directory"
+	}
+	fmt.Println(client.paint(colorYellow, fmt.Sprintf("skipped %d non-git %s", skipped, noun)))
+}
+
+// defaultBranchOf prefers the branch origin/HEAD points to and falls back to
+// the currently checked out branch.
+func defaultBranchOf(dir string) string {
+	if ref, err := runGit(dir, "symbolic-ref", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		return strings.TrimPrefix(ref, "origin/")
+	}
+	if ref, err := runGit(dir, "symbolic-ref", "--short", "HEAD"); err == nil {
+		return ref
+	}
+	return ""
+}
+
+// branchesOfDefault tracks just the default branch of the repo in dir.
+func branchesOfDefault(dir string) Branches {
+	if branch := defaultBranchOf(dir); branch != "" {
+		return Branches{branch}
+	}
+	return nil
+}
+
+func originOf(dir string) string {
+	url, err := runGit(dir, "remote", "get-url", "origin")
+	if err != nil {
+		return ""
+	}
+	return url
+}
+
+// adoptedRepo returns the config of the unconfigured repo in fullDir, dir
+// relative to the workspace, named after its directory. When that name is
+// taken it is named after dir, with a number appended while that is taken
+// too.
+func (client *RepoManager) adoptedRepo(fullDir, dir string) *RepoConfig {
+	name := filepath.Base(fullDir)
+	if _, ok := client.config.Repos[name]; ok {
+		base := strings.ReplaceAll(filepath.ToSlash(dir), "/", "-")
+		name = base
+		for n := 2; client.config.Repos[name] != nil; n++ {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+	}
+	repoConfig := &RepoConfig{
+		Name:   name,
+		Dir:    dir,
+		Url:    originOf(fullDir),
+		Branch: branchesOfDefault(fullDir),
+	}
+	if isBareRepo(fullDir) {
+		mirror := true
+		repoConfig.Mirror = &mirror
+	}
+	return repoConfig
+}
+
+// Adopt scans the workspace for git repos and adds every repo that isn't
+// configured yet, detecting its origin url and default branch. Directories
+// that aren't git repos are skipped with a warning.
+func (client *RepoManager) Adopt(depth int) error {
+	client.logger.Info("adopting repos", "workspace", client.workspace)
+	dirs, skipped, err := client.discover(depth)
+	if err != nil {
+		return err
+	}
+
+	configured := make(map[string]bool)
+	for _, repoConfig := range client.config.Repos {
+		configured[repoConfig.FullDir(client.workspace)] = true
+	}
+
+	max := client.nameWidth()
+	for _, fullDir := range dirs {
+		dir, err := filepath.Rel(client.workspace, fullDir)
+		if err != nil {
+			return err
+		}
+		if configured[fullDir] {
+			client.logger.Debug("skipping configured repo", "dir", dir)
+			continue
+		}
+		repoConfig := client.adoptedRepo(fullDir, dir)
+		name := repoConfig.Name
+		client.config.Repos[name] = repoConfig
+		if note := client.worktreeNote(fullDir); note != "" {
+			client.printRepoLine(max, name, "adopted "+dir+" ("+note+")")
+		} else {
+			client.printRepoLine(max, name, "adopted "+dir)
+		}
+	}
+	client.printSkippedDirs(skipped)
+
+	if err := client.config.Save(); err != nil {
+		return err
+	}
+	client.syncGoWork()
+	return nil
+}
@@ -0,0 +1,39 @@
+package repos
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestAdoptedRepoName(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured []string
+		dir        string
+		want       string
+	}{
+		{"free", []string{"web"}, "api", "api"},
+		{"taken at the top level", []string{"api"}, "api", "api-2"},
+		{"numbered taken too", []string{"api", "api-2"}, "api", "api-3"},
+		{"taken in a subdirectory", []string{"api"}, filepath.Join("team", "api"), "team-api"},
+		{"subdirectory name taken too", []string{"api", "team-api"}, filepath.Join("team", "api"), "team-api-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspace := t.TempDir()
+			config := &ReposConfig{Repos: make(map[string]*RepoConfig)}
+			for _, name := range tt.configured {
+				config.Repos[name] = &RepoConfig{Name: name, Dir: "elsewhere-" + name}
+			}
+			client := &RepoManager{config: config, workspace: workspace}
+
+			repoConfig := client.adoptedRepo(filepath.Join(workspace, tt.dir), tt.dir)
+			if repoConfig.Name != tt.want {
+				t.Errorf("name is %q, want %q", repoConfig.Name, tt.want)
+			}
+			if existing := config.Repos[tt.want]; existing != nil {
+				t.Errorf("%s is already configured as %+v", tt.want, existing)
+			}
+		})
+	}
+}
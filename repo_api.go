@@ -0,0 +1,251 @@
+package repos
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ProgressEvent is streamed by the control API of the daemon while runs are
+// going: a run started, a repo finished or a run finished.
+type ProgressEvent struct {
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	Run     string    `json:"run"`
+	Command string    `json:"command"`
+	Repo    string    `json:"repo,omitempty"`
+	Outcome outcome   `json:"outcome,omitempty"`
+	Reason  string    `json:"reason,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// apiRunRequest is the body of POST /runs: either the name of a configured
+// schedule or a command with an optional workspace and groups.
+type apiRunRequest struct {
+	Schedule  string   `json:"schedule"`
+	Command   string   `json:"command"`
+	Workspace string   `json:"workspace"`
+	Groups    []string `json:"groups"`
+}
+
+// LoadAPIToken returns the token in path, creating the file with a random
+// token readable only by the user when it doesn't exist.
+func LoadAPIToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return "", fmt.Errorf("%s is empty", path)
+		}
+		return token, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// publish sends event to the clients streaming progress. Clients too slow to
+// keep up miss events rather than hold up the runs.
+func (d *daemon) publish(event ProgressEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for events := range d.subscribers {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+func (d *daemon) subscribe() chan ProgressEvent {
+	events := make(chan ProgressEvent, 64)
+	d.mu.Lock()
+	d.subscribers[events] = struct{}{}
+	d.mu.Unlock()
+	return events
+}
+
+func (d *daemon) unsubscribe(events chan ProgressEvent) {
+	d.mu.Lock()
+	delete(d.subscribers, events)
+	d.mu.Unlock()
+}
+
+// listen opens a unix socket for unix:<path> addresses, replacing a stale
+// socket file, and a TCP listener otherwise.
+func listen(address string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(address, "unix:")
+	if !ok {
+		return net.Listen("tcp", address)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// serveAPI serves the control API until ctx is done:
+//
+//	GET  /results  the result of the last run of every schedule
+//	POST /runs     start a run, see apiRunRequest
+//	GET  /events   stream ProgressEvents as JSON lines
+func (d *daemon) serveAPI(ctx context.Context, opts DaemonOptions) error {
+	if opts.Token == "" {
+		return fmt.Errorf("the control API needs a token")
+	}
+	listener, err := listen(opts.Listen)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/results", d.handleResults)
+	mux.HandleFunc("/runs", d.handleRuns)
+	mux.HandleFunc("/events", d.handleEvents)
+	server := &http.Server{
+		Handler: authenticated(opts.Token, mux),
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	d.client.logger.Info("control API listening", "address", opts.Listen)
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// authenticated only lets requests with the bearer token through.
+func authenticated(token string, next http.Handler) http.Handler {
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func (d *daemon) handleResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	d.mu.Lock()
+	results := make([]*RunResult, 0, len(d.results))
+	for _, result := range d.results {
+		results = append(results, result)
+	}
+	d.mu.Unlock()
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+	writeJSON(w, http.StatusOK, results)
+}
+
+func (d *daemon) handleRuns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req apiRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	name, schedule := req.Schedule, &ScheduleConfig{Command: req.Command, Workspace: req.Workspace, Groups: req.Groups}
+	if name != "" {
+		configured, ok := d.client.config.root().Schedules[name]
+		if !ok {
+			http.Error(w, fmt.Sprintf("schedule %s is not configured", name), http.StatusNotFound)
+			return
+		}
+		schedule = configured
+	} else {
+		name = "api"
+		if !contains(scheduleCommands, schedule.Command) {
+			http.Error(w, fmt.Sprintf("invalid command %q, must be one of pull, push or sync", schedule.Command), http.StatusBadRequest)
+			return
+		}
+	}
+	started := d.start(name, schedule, func(err error, took time.Duration) {
+		if err != nil {
+			d.client.logger.Error("run failed", "run", name, "command", schedule.Command, "took", took, "error", err)
+			return
+		}
+		d.client.logger.Info("run finished", "run", name, "command", schedule.Command, "took", took)
+	})
+	if !started {
+		http.Error(w, fmt.Sprintf("%s is already running", name), http.StatusConflict)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"run": name})
+}
+
+func (d *daemon) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	events := d.subscribe()
+	defer d.unsubscribe(events)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			if err := encoder.Encode(event); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
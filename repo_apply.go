@@ -0,0 +1,102 @@
+package repos
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type ApplyFileOptions struct {
+	// Select limits the repos the file is applied to.
+	Select ListOptions
+	// Commit commits the file, with Message or a message naming the file.
+	Commit bool
+	// Message templates the commit message like CommitOptions.Message, with
+	// .File, the path of the file, added.
+	Message string
+	// Push pushes the commit, it needs Commit.
+	Push bool
+}
+
+const defaultApplyFileMessage = "Update {{.File}}"
+
+type applyFileMessageData struct {
+	MessageData
+	File string
+}
+
+// ApplyFile copies the file src to dest, a path relative to the root of every
+// selected repo, and optionally commits and pushes it. Repos where dest already
+// has the same content are left alone.
+func (client *RepoManager) ApplyFile(src, dest string, opts ApplyFileOptions) error {
+	if opts.Push && !opts.Commit {
+		return fmt.Errorf("pushing needs --commit")
+	}
+	dest = filepath.Clean(dest)
+	if filepath.IsAbs(dest) || dest == ".." || strings.HasPrefix(dest, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s must be a path inside the repos", dest)
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	message := opts.Message
+	if message == "" {
+		message = defaultApplyFileMessage
+	}
+	tmpl, err := parseMessage(message)
+	if err != nil {
+		return err
+	}
+
+	client.logger.Info("applying file", "src", src, "dest", dest, "workspace", client.workspace)
+	return client.runBatch("apply-file", client.selectedRepos(opts.Select), func(repoConfig *RepoConfig) (outcome, string, error) {
+		if client.mirrorFor(repoConfig) {
+			return outcomeSkipped, "mirror", nil
+		}
+		dir := repoConfig.FullDir(client.workspace)
+		if err := client.backend.Open(dir); err != nil {
+			return outcomeSkipped, "", err
+		}
+		target := filepath.Join(dir, dest)
+		if current, err := os.ReadFile(target); err == nil && bytes.Equal(current, data) {
+			return outcomeUpToDate, "", nil
+		}
+		message, err := executeMessage(tmpl, applyFileMessageData{MessageData: client.messageData(repoConfig), File: filepath.ToSlash(dest)})
+		if err != nil {
+			return outcomeFailed, "", err
+		}
+		client.logger.Debug("writing", "repo", repoConfig.Name, "file", target)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return outcomeFailed, "", err
+		}
+		if err := os.WriteFile(target, data, info.Mode().Perm()); err != nil {
+			return outcomeFailed, "", err
+		}
+		if !opts.Commit {
+			return outcomeSucceeded, "", nil
+		}
+		// Committing just the path leaves whatever else is staged alone.
+		if _, err := runGit(dir, "add", "--", dest); err != nil {
+			return outcomeFailed, "", err
+		}
+		if _, err := runGit(dir, "commit", "-m", message, "--", dest); err != nil {
+			return outcomeFailed, "", err
+		}
+		if opts.Push && repoConfig.IsReadOnly() {
+			return outcomeSucceeded, reasonNotPushed, nil
+		}
+		if opts.Push {
+			if _, err := client.push(repoConfig, client.pushSpec(repoConfig, PushOptions{}), false); err != nil {
+				return outcomeFailed, "", err
+			}
+		}
+		return outcomeSucceeded, "", nil
+	})
+}
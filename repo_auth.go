@@ -0,0 +1,210 @@
+package repos
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/kevinburke/ssh_config"
+	cssh "golang.org/x/crypto/ssh"
+)
+
+// defaultKeyNames are the private keys looked for in ~/.ssh, in the order
+// ssh itself tries them.
+var defaultKeyNames = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// defaultKeyPath returns the first default private key that exists, or an
+// empty string.
+func defaultKeyPath() string {
+	home := homeDir()
+	if home == "" {
+		return ""
+	}
+	for _, name := range defaultKeyNames {
+		path := filepath.Join(home, ".ssh", name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// newAuth authenticates with the key at sshPath or, when none is configured,
+// with the first default key in ~/.ssh. Without any key it falls back to the
+// ssh agent: SSH_AUTH_SOCK on unix, Pageant or the OpenSSH agent pipe on
+// Windows.
+func newAuth(user string, sshPath string) (transport.AuthMethod, error) {
+	hostKeyCallback := ssh.HostKeyCallbackHelper{
+		HostKeyCallback: cssh.InsecureIgnoreHostKey(),
+	}
+	if sshPath == "" {
+		sshPath = defaultKeyPath()
+	}
+	if sshPath == "" {
+		agentAuth, err := ssh.NewSSHAgentAuth(user)
+		if err != nil {
+			return nil, fmt.Errorf("no ssh key found in %s and %w", filepath.Join(homeDir(), ".ssh"), err)
+		}
+		agentAuth.HostKeyCallbackHelper = hostKeyCallback
+		return agentAuth, nil
+	}
+	publicKey, err := ssh.NewPublicKeysFromFile(user, sshPath, "")
+	if err != nil {
+		return nil, err
+	}
+	publicKey.HostKeyCallbackHelper = hostKeyCallback
+	return publicKey, nil
+}
+
+// authenticator hands out the auth for a remote URL, creating one per ssh
+// user and key on first use.
+type authenticator struct {
+	// keyPath is the configured key_file, empty to use the defaults.
+	keyPath string
+	// hosts maps host patterns to the auth configured for remotes on them.
+	hosts map[string]*HostAuth
+
+	mu    sync.Mutex
+	auths map[string]transport.AuthMethod
+}
+
+// authFor returns the auth for url, or nil when its transport needs none or
+// no credentials are known. For ssh remotes the User and IdentityFile of the
+// matching Host entry in ~/.ssh/config are honored, go-git applies HostName
+// and Port itself.
+func (a *authenticator) authFor(url string) (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(url)
+	if err != nil {
+		return nil, err
+	}
+	switch endpoint.Protocol {
+	case "http", "https":
+		return a.httpAuthFor(endpoint)
+	case "ssh":
+	default:
+		return nil, nil
+	}
+
+	hostAuth := hostAuthFor(a.hosts, endpoint.Host)
+	user := endpoint.User
+	if user == "" && hostAuth != nil {
+		user = hostAuth.User
+	}
+	if user == "" {
+		user = ssh_config.Get(endpoint.Host, "User")
+	}
+	if user == "" {
+		user = ssh.DefaultUsername
+	}
+	keyPath := a.keyPath
+	if identity := ssh_config.Get(endpoint.Host, "IdentityFile"); identity != ssh_config.Default("IdentityFile") {
+		keyPath = expandPath(identity)
+	}
+	if hostAuth != nil && hostAuth.KeyFile != "" {
+		keyPath = hostAuth.keyPath()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	key := user + "\x00" + keyPath
+	if auth, ok := a.auths[key]; ok {
+		return auth, nil
+	}
+	auth, err := newAuth(user, keyPath)
+	if err != nil {
+		return nil, err
+	}
+	if a.auths == nil {
+		a.auths = make(map[string]transport.AuthMethod)
+	}
+	a.auths[key] = auth
+	return auth, nil
+}
+
+// httpAuthFor returns basic auth for an HTTP(S) remote, taken from the URL,
+// the token of the auth config for its host, the OS keyring, where repos
+// auth login stores tokens, the .netrc file, or else from the git
+// credential helpers, such as osxkeychain, libsecret or manager-core. Without
+// credentials the remote is accessed anonymously.
+func (a *authenticator) httpAuthFor(endpoint *transport.Endpoint) (transport.AuthMethod, error) {
+	if endpoint.Password != "" {
+		return &http.BasicAuth{Username: endpoint.User, Password: endpoint.Password}, nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	key := endpoint.Protocol + "://" + endpoint.User + "@" + endpoint.Host
+	if auth, ok := a.auths[key]; ok {
+		return auth, nil
+	}
+	var auth transport.AuthMethod
+	if hostAuth := hostAuthFor(a.hosts, endpoint.Host); hostAuth != nil && hostAuth.token() != "" {
+		username := endpoint.User
+		if username == "" {
+			username = hostAuth.User
+		}
+		if username == "" {
+			username = "git"
+		}
+		auth = &http.BasicAuth{Username: username, Password: hostAuth.token()}
+	} else if credential, ok := storedCredential(endpoint.Host); ok {
+		username := credential.User
+		if username == "" {
+			username = endpoint.User
+		}
+		if username == "" {
+			// Forges ignore the user name when the password is a token.
+			username = "git"
+		}
+		auth = &http.BasicAuth{Username: username, Password: credential.Token}
+	} else if username, password, ok := netrcCredential(endpoint.Host); ok {
+		if username == "" {
+			username = endpoint.User
+		}
+		auth = &http.BasicAuth{Username: username, Password: password}
+	} else if username, password, err := credentialFill(endpoint); err == nil && password != "" {
+		auth = &http.BasicAuth{Username: username, Password: password}
+	}
+	if a.auths == nil {
+		a.auths = make(map[string]transport.AuthMethod)
+	}
+	a.auths[key] = auth
+	return auth, nil
+}
+
+// credentialFill asks git for the credentials of an endpoint using the
+// git-credential protocol. Prompting is disabled, so it fails unless a
+// helper knows them.
+func credentialFill(endpoint *transport.Endpoint) (string, string, error) {
+	input := fmt.Sprintf("protocol=%s\nhost=%s\n", endpoint.Protocol, endpoint.Host)
+	if endpoint.Port != 0 {
+		input = fmt.Sprintf("protocol=%s\nhost=%s:%d\n", endpoint.Protocol, endpoint.Host, endpoint.Port)
+	}
+	if endpoint.User != "" {
+		input += "username=" + endpoint.User + "\n"
+	}
+	cmd := exec.Command("git", "credential", "fill")
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=")
+	cmd.Stdin = strings.NewReader(input + "\n")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", "", err
+	}
+	var username, password string
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, _ := strings.Cut(line, "=")
+		switch key {
+		case "username":
+			username = value
+		case "password":
+			password = value
+		}
+	}
+	return username, password, nil
+}
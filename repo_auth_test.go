@@ -0,0 +1,182 @@
+package repos
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func TestHomeDir(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"home", map[string]string{"HOME": "/home/me", "USERPROFILE": `C:\Users\other`}, "/home/me"},
+		{"userprofile", map[string]string{"USERPROFILE": `C:\Users\me`, "HOMEDRIVE": "D:", "HOMEPATH": `\Users\other`}, `C:\Users\me`},
+		{"homedrive and homepath", map[string]string{"HOMEDRIVE": "D:", "HOMEPATH": `\Users\me`}, `D:\Users\me`},
+		{"homedrive only", map[string]string{"HOMEDRIVE": "D:"}, ""},
+		{"nothing", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+				t.Skip("os.UserHomeDir reads other variables here")
+			}
+			for _, key := range []string{"HOME", "USERPROFILE", "HOMEDRIVE", "HOMEPATH"} {
+				t.Setenv(key, tt.env[key])
+			}
+			if got := homeDir(); got != tt.want {
+				t.Errorf("homeDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// setHome points the home directory at a temp dir with the given keys in
+// its .ssh directory, and returns the home directory.
+func setHome(t *testing.T, keys ...string) string {
+	t.Helper()
+	home := t.TempDir()
+	for _, key := range []string{"HOME", "USERPROFILE"} {
+		t.Setenv(key, home)
+	}
+	if err := os.MkdirAll(filepath.Join(home, ".ssh"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		writeKey(t, filepath.Join(home, ".ssh", key))
+	}
+	return home
+}
+
+// writeKey writes a new unencrypted private key to path.
+func writeKey(t *testing.T, path string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDefaultKeyPath(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		want string
+	}{
+		{"all", []string{"id_rsa", "id_ecdsa", "id_ed25519"}, "id_ed25519"},
+		{"ecdsa before rsa", []string{"id_rsa", "id_ecdsa"}, "id_ecdsa"},
+		{"rsa", []string{"id_rsa"}, "id_rsa"},
+		{"other names", []string{"id_dsa", "work"}, ""},
+		{"none", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := setHome(t, tt.keys...)
+			want := ""
+			if tt.want != "" {
+				want = filepath.Join(home, ".ssh", tt.want)
+			}
+			if got := defaultKeyPath(); got != want {
+				t.Errorf("defaultKeyPath() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// serveAgent runs an ssh agent without keys for the test and points
+// SSH_AUTH_SOCK at it.
+func serveAgent(t *testing.T) {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skip("no unix sockets:", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	keyring := agent.NewKeyring()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				agent.ServeAgent(keyring, conn)
+			}()
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", socket)
+}
+
+func TestNewAuth(t *testing.T) {
+	tests := []struct {
+		name string
+		// keys are created in ~/.ssh, configured is a key file elsewhere.
+		keys       []string
+		configured bool
+		agent      bool
+		// want is the method of the auth, empty for an error.
+		want string
+	}{
+		{"configured key", []string{"id_ed25519"}, true, true, ssh.PublicKeysName},
+		{"default key", []string{"id_rsa"}, false, true, ssh.PublicKeysName},
+		{"agent", nil, false, true, ssh.PublicKeysCallbackName},
+		{"no key and no agent", nil, false, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.agent && runtime.GOOS == "windows" {
+				t.Skip("the agent is a named pipe on Windows")
+			}
+			setHome(t, tt.keys...)
+			t.Setenv("SSH_AUTH_SOCK", "")
+			if tt.agent {
+				serveAgent(t)
+			}
+			keyPath := ""
+			if tt.configured {
+				keyPath = filepath.Join(t.TempDir(), "deploy_key")
+				writeKey(t, keyPath)
+			}
+
+			auth, err := newAuth("git", keyPath)
+			if tt.want == "" {
+				if err == nil || !strings.Contains(err.Error(), "no ssh key found") {
+					t.Fatalf("newAuth() = %v, %v, want no ssh key found", auth, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if auth.Name() != tt.want {
+				t.Errorf("newAuth() is %s, want %s", auth.Name(), tt.want)
+			}
+			if keys, ok := auth.(*ssh.PublicKeys); ok && keys.User != "git" {
+				t.Errorf("user is %q, want git", keys.User)
+			}
+		})
+	}
+}
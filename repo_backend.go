@@ -0,0 +1,276 @@
+package repos
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Backend names accepted by the backend config setting.
+const (
+	BackendGoGit = "go-git"
+	BackendGit   = "git"
+)
+
+// RepoStatus is what a GitBackend reports about a working tree.
+type RepoStatus struct {
+	// Branch is the checked out branch, empty for a detached HEAD.
+	Branch string
+	// Commit is the abbreviated hash of HEAD.
+	Commit string
+	// Changed is set when tracked files have uncommitted changes.
+	Changed bool
+	// Untracked is set when there are untracked files.
+	Untracked bool
+}
+
+// Clean reports whether there is nothing to commit at all.
+func (status *RepoStatus) Clean() bool {
+	return !status.Changed && !status.Untracked
+}
+
+// Head describes what is checked out: the branch name or, for a detached
+// HEAD, the commit.
+func (status *RepoStatus) Head() string {
+	if status.Branch != "" {
+		return status.Branch
+	}
+	return "detached at " + status.Commit
+}
+
+// CloneSpec describes a clone to a GitBackend.
+type CloneSpec struct {
+	URL    string
+	Dir    string
+	Branch string
+	// Depth limits the history to that many commits when positive.
+	Depth        int
+	SingleBranch bool
+	// Filter makes a partial clone, e.g. blob:none, whose missing objects
+	// are fetched when git needs them.
+	Filter string
+	// Mirror makes a bare clone of every ref of the remote. Branch and
+	// SingleBranch don't apply then.
+	Mirror bool
+}
+
+// PushSpec describes a push to a GitBackend. Without AllBranches only the
+// checked out branch is pushed.
+type PushSpec struct {
+	Tags           bool
+	AllBranches    bool
+	ForceWithLease bool
+}
+
+// GitBackend performs the git operations of batch commands on a single
+// working tree.
+type GitBackend interface {
+	// Open fails unless dir is a git repository.
+	Open(dir string) error
+	Status(dir string) (*RepoStatus, error)
+	// Pull fast-forwards the checked out branch from origin and reports
+	// whether it was up to date already.
+	Pull(dir string, singleBranch bool) (bool, error)
+	// Push pushes to origin and reports whether there was nothing to push.
+	Push(dir string, spec PushSpec) (bool, error)
+	Clone(spec CloneSpec) error
+}
+
+// WithBackend makes the RepoManager use backend instead of the one named in
+// the config.
+func WithBackend(backend GitBackend) NewRepoManagerClientOptions {
+	return func(client *RepoManager) {
+		client.backend = backend
+	}
+}
+
+// newBackend creates the backend named in the config, go-git by default.
+func (client *RepoManager) newBackend() (GitBackend, error) {
+	name := ""
+	if client.config != nil {
+		name = client.config.Backend
+	}
+	var proxies map[string]string
+	var auths map[string]*HostAuth
+	multiplex := false
+	if client.config != nil {
+		proxies = client.config.Proxies
+		auths = client.config.Auth
+		multiplex = client.config.SSHMultiplex
+	}
+	cli := &cliBackend{proxies: proxies, multiplex: multiplex, auths: auths}
+	switch name {
+	case "", BackendGoGit:
+		keyPath := ""
+		if client.config != nil {
+			keyPath = client.config.KeyPath()
+		}
+		installHTTPProxies(proxies)
+		installSSHPorts(auths)
+		return &goGitBackend{
+			auth:       &authenticator{keyPath: keyPath, hosts: auths},
+			progress:   client.progeess(),
+			progressOf: client.liveProgressOf,
+			proxies:    proxies,
+			cli:        cli,
+		}, nil
+	case BackendGit:
+		return cli, nil
+	}
+	return nil, fmt.Errorf("invalid backend %q, must be %s or %s", name, BackendGoGit, BackendGit)
+}
+
+// gitCLI returns a cliBackend for the git commands that only the command line
+// tool can run, with the proxies and auth of the config.
+func (client *RepoManager) gitCLI() *cliBackend {
+	return &cliBackend{proxies: client.config.Proxies, auths: client.config.Auth}
+}
+
+// cliBackend runs the git command line tool, so it picks up the user's git
+// configuration, credential helpers and extensions such as LFS.
+type cliBackend struct {
+	// proxies maps host patterns to the proxy remotes on them go through.
+	proxies map[string]string
+	// multiplex shares one ssh connection per host among git commands.
+	multiplex bool
+	// auths maps host patterns to the auth for remotes on them.
+	auths map[string]*HostAuth
+}
+
+// remoteGit runs a git command that talks to remoteURL, through its proxy if
+// one is configured.
+func (backend *cliBackend) remoteGit(dir string, remoteURL string, args ...string) (string, error) {
+	proxyArgs, err := gitProxyArgs(backend.proxies, remoteURL)
+	if err != nil {
+		return "", err
+	}
+	proxyArgs, env := gitHostAuthArgs(backend.auths, remoteURL, proxyArgs)
+	if backend.multiplex {
+		proxyArgs = withSSHMultiplexing(proxyArgs, remoteURL)
+	}
+	return runGitEnv(dir, env, append(proxyArgs, args...)...)
+}
+
+func (backend *cliBackend) originGit(dir string, args ...string) (string, error) {
+	origin, err := runGit(dir, "remote", "get-url", "origin")
+	if err != nil {
+		return "", err
+	}
+	return backend.remoteGit(dir, origin, args...)
+}
+
+func (backend *cliBackend) Open(dir string) error {
+	_, err := runGit(dir, "rev-parse", "--git-dir")
+	return err
+}
+
+func (backend *cliBackend) Status(dir string) (*RepoStatus, error) {
+	commit, err := runGit(dir, "rev-parse", "--short", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	status := &RepoStatus{Commit: commit}
+	status.Branch, _ = runGit(dir, "symbolic-ref", "--short", "--quiet", "HEAD")
+	out, err := runGit(dir, "status", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "??") {
+			status.Untracked = true
+		} else if line != "" {
+			status.Changed = true
+		}
+	}
+	return status, nil
+}
+
+// Pull fast-forwards the checked out branch. Whether anything changed is told
+// by comparing HEAD, since the messages of git depend on its locale.
+func (backend *cliBackend) Pull(dir string, singleBranch bool) (bool, error) {
+	before, err := runGit(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return false, err
+	}
+	args := []string{"pull", "--ff-only", "origin"}
+	if singleBranch {
+		// Naming the branch HEAD tracks fetches only that one.
+		merge, err := trackedBranch(dir)
+		if err != nil {
+			return false, err
+		}
+		args = append(args, merge)
+	}
+	if _, err := backend.originGit(dir, args...); err != nil {
+		return false, err
+	}
+	after, err := runGit(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return false, err
+	}
+	return before == after, nil
+}
+
+// trackedBranch returns the remote ref the checked out branch in dir merges.
+func trackedBranch(dir string) (string, error) {
+	branch, err := runGit(dir, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return runGit(dir, "config", "branch."+branch+".merge")
+}
+
+func (backend *cliBackend) Push(dir string, spec PushSpec) (bool, error) {
+	args := []string{"push", "--porcelain"}
+	if spec.ForceWithLease {
+		args = append(args, "--force-with-lease")
+	}
+	if spec.AllBranches {
+		args = append(args, "--all")
+	}
+	upToDate, err := backend.push(dir, append(args, "origin")...)
+	if err != nil || !spec.Tags {
+		return upToDate, err
+	}
+	// git refuses --tags together with --all, so tags go in a push of their own.
+	tagsUpToDate, err := backend.push(dir, "push", "--porcelain", "--tags", "origin")
+	return upToDate && tagsUpToDate, err
+}
+
+func (backend *cliBackend) push(dir string, args ...string) (bool, error) {
+	out, err := backend.originGit(dir, args...)
+	if err != nil {
+		return false, err
+	}
+	// Every ref line starts with a flag, = meaning up to date.
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, "\t") && !strings.HasPrefix(line, "=") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (backend *cliBackend) Clone(spec CloneSpec) error {
+	args := []string{"clone"}
+	if spec.Mirror {
+		args = append(args, "--mirror")
+	}
+	if spec.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(spec.Depth))
+	}
+	if spec.SingleBranch && !spec.Mirror {
+		args = append(args, "--single-branch")
+	} else if spec.Depth > 0 {
+		// --depth implies --single-branch.
+		args = append(args, "--no-single-branch")
+	}
+	if spec.Filter != "" {
+		args = append(args, "--filter="+spec.Filter)
+	}
+	if spec.Branch != "" && !spec.Mirror {
+		args = append(args, "--branch", spec.Branch)
+	}
+	_, err := backend.remoteGit(".", spec.URL, append(args, "--", spec.URL, spec.Dir)...)
+	return err
+}
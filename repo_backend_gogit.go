@@ -0,0 +1,193 @@
+package repos
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// goGitBackend works in process with go-git, authenticating with an SSH key
+// or the ssh agent.
+type goGitBackend struct {
+	auth     *authenticator
+	progress io.Writer
+	// progressOf returns where the progress of the repo in a dir goes
+	// instead of progress, or nil.
+	progressOf func(dir string) io.Writer
+	proxies    map[string]string
+	// cli handles what go-git can't: shallow fetches and ssh through proxies.
+	cli *cliBackend
+}
+
+// progressFor returns the writer for the transfer progress of the repo in
+// dir.
+func (backend *goGitBackend) progressFor(dir string) io.Writer {
+	if backend.progressOf != nil {
+		if progress := backend.progressOf(dir); progress != nil {
+			return progress
+		}
+	}
+	return backend.progress
+}
+
+func originURL(repo *git.Repository) (string, error) {
+	origin, err := repo.Remote("origin")
+	if err != nil {
+		return "", err
+	}
+	return origin.Config().URLs[0], nil
+}
+
+// plainOpen opens the repo in dir, following the commondir of linked
+// worktrees to the object store of their main repo.
+func plainOpen(dir string) (*git.Repository, error) {
+	return git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+}
+
+// needsCLI tells whether the repo in dir is one go-git can't handle: it
+// can't fetch into shallow clones reliably nor fetch the objects a partial
+// clone left out.
+func needsCLI(dir string) bool {
+	if shallow, _ := runGit(dir, "rev-parse", "--is-shallow-repository"); shallow == "true" {
+		return true
+	}
+	promisor, _ := runGit(dir, "config", "--get", "remote.origin.promisor")
+	return promisor == "true"
+}
+
+func (backend *goGitBackend) Open(dir string) error {
+	_, err := plainOpen(dir)
+	return err
+}
+
+func (backend *goGitBackend) Status(dir string) (*RepoStatus, error) {
+	if needsCLI(dir) {
+		return backend.cli.Status(dir)
+	}
+	repo, err := plainOpen(dir)
+	if err != nil {
+		return nil, err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	status := &RepoStatus{Commit: head.Hash().String()[:7]}
+	if head.Name().IsBranch() {
+		status.Branch = head.Name().Short()
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	files, err := w.Status()
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if file.Worktree == git.Untracked {
+			status.Untracked = true
+		} else if file.Worktree != git.Unmodified || file.Staging != git.Unmodified {
+			status.Changed = true
+		}
+	}
+	return status, nil
+}
+
+func (backend *goGitBackend) Pull(dir string, singleBranch bool) (bool, error) {
+	repo, err := plainOpen(dir)
+	if err != nil {
+		return false, err
+	}
+	remoteURL, err := originURL(repo)
+	if err != nil {
+		return false, err
+	}
+	if needsCLI(dir) || sshNeedsCLI(backend.proxies, remoteURL) {
+		return backend.cli.Pull(dir, singleBranch)
+	}
+	auth, err := backend.auth.authFor(remoteURL)
+	if err != nil {
+		return false, err
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return false, err
+	}
+	err = w.Pull(&git.PullOptions{
+		RemoteName:   "origin",
+		Auth:         auth,
+		Progress:     backend.progressFor(dir),
+		SingleBranch: singleBranch,
+	})
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return true, nil
+	}
+	return false, err
+}
+
+func (backend *goGitBackend) Push(dir string, spec PushSpec) (bool, error) {
+	repo, err := plainOpen(dir)
+	if err != nil {
+		return false, err
+	}
+	remoteURL, err := originURL(repo)
+	if err != nil {
+		return false, err
+	}
+	// go-git has no force with lease.
+	if spec.ForceWithLease || needsCLI(dir) || sshNeedsCLI(backend.proxies, remoteURL) {
+		return backend.cli.Push(dir, spec)
+	}
+	auth, err := backend.auth.authFor(remoteURL)
+	if err != nil {
+		return false, err
+	}
+	// go-git pushes every branch unless told otherwise.
+	refSpecs := []config.RefSpec{"refs/heads/*:refs/heads/*"}
+	if !spec.AllBranches {
+		head, err := repo.Head()
+		if err != nil {
+			return false, err
+		}
+		if !head.Name().IsBranch() {
+			return false, fmt.Errorf("HEAD is detached, nothing to push")
+		}
+		refSpecs = []config.RefSpec{config.RefSpec(head.Name() + ":" + head.Name())}
+	}
+	if spec.Tags {
+		refSpecs = append(refSpecs, "refs/tags/*:refs/tags/*")
+	}
+	err = repo.Push(&git.PushOptions{RemoteName: "origin", RefSpecs: refSpecs, Auth: auth, Progress: backend.progressFor(dir)})
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return true, nil
+	}
+	return false, err
+}
+
+func (backend *goGitBackend) Clone(spec CloneSpec) error {
+	// go-git has no partial or mirror clones.
+	if spec.Filter != "" || spec.Mirror || sshNeedsCLI(backend.proxies, spec.URL) {
+		return backend.cli.Clone(spec)
+	}
+	auth, err := backend.auth.authFor(spec.URL)
+	if err != nil {
+		return err
+	}
+	opts := &git.CloneOptions{
+		URL:          spec.URL,
+		Auth:         auth,
+		Depth:        spec.Depth,
+		SingleBranch: spec.SingleBranch,
+		Progress:     backend.progressFor(spec.Dir),
+	}
+	if spec.Branch != "" {
+		opts.ReferenceName = plumbing.NewBranchReferenceName(spec.Branch)
+	}
+	_, err = git.PlainClone(spec.Dir, false, opts)
+	return err
+}
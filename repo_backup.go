@@ -0,0 +1,373 @@
+package repos
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	backupConfigDir  = "config"
+	backupBundlesDir = "bundles"
+	// backupIncludesDir holds the included config files, relative to the
+	// directory of the config file, and backupExternalDir those outside of
+	// it by their absolute path.
+	backupIncludesDir = "includes"
+	backupExternalDir = "external"
+	// backupNestedDir holds the nested config files, relative to the
+	// workspace.
+	backupNestedDir = "nested"
+)
+
+// backupNameOf returns the name of an included or nested config file in the
+// archive of Backup.
+func (client *RepoManager) backupNameOf(file *includedFile) (string, error) {
+	if file.dir != "" {
+		return path.Join(backupNestedDir, filepath.ToSlash(file.dir), filepath.Base(file.path)), nil
+	}
+	abs, err := filepath.Abs(file.path)
+	if err != nil {
+		return "", err
+	}
+	cfgDir, err := filepath.Abs(filepath.Dir(client.config.root().CfgFile))
+	if err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(cfgDir, abs); err == nil && !strings.HasPrefix(rel, "..") {
+		return path.Join(backupIncludesDir, filepath.ToSlash(rel)), nil
+	}
+	return path.Join(backupExternalDir, strings.TrimPrefix(filepath.ToSlash(abs), filepath.VolumeName(abs)+"/")), nil
+}
+
+// restoreTargetOf returns where Restore writes the file of the archive
+// named name, empty for the main config file and bundles. Names are clean and
+// relative, as Restore checks.
+func (client *RepoManager) restoreTargetOf(name string) string {
+	dir, rel, _ := strings.Cut(name, "/")
+	switch dir {
+	case backupIncludesDir:
+		return filepath.Join(filepath.Dir(client.config.CfgFile), filepath.FromSlash(rel))
+	case backupExternalDir:
+		return filepath.Join(string(filepath.Separator), filepath.FromSlash(rel))
+	case backupNestedDir:
+		return filepath.Join(client.workspace, filepath.FromSlash(rel))
+	}
+	return ""
+}
+
+func addFileToTar(tw *tar.Writer, name string, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// Backup writes a gzipped tarball containing the config file, the included
+// and nested config files loaded with it and a git bundle with all refs of
+// every repo. Repos without commits have nothing to bundle and are skipped.
+func (client *RepoManager) Backup(out string) error {
+	client.logger.Info("backing up", "workspace", client.workspace, "out", out)
+	tmpDir, err := os.MkdirTemp("", "repos-backup")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	if err := addFileToTar(tw, path.Join(backupConfigDir, filepath.Base(client.config.CfgFile)), client.config.CfgFile); err != nil {
+		return err
+	}
+	for _, file := range client.config.root().includes {
+		name, err := client.backupNameOf(file)
+		if err != nil {
+			return err
+		}
+		client.logger.Debug("archiving", "config", file.path)
+		if err := addFileToTar(tw, name, file.path); err != nil {
+			return err
+		}
+	}
+	max := client.nameWidth()
+	for _, repoConfig := range client.sortedRepos() {
+		dir := repoConfig.FullDir(client.workspace)
+		if refs, err := runGit(dir, "for-each-ref", "--count=1"); err == nil && refs == "" {
+			client.logger.Warn("skipping repo without commits", "repo", repoConfig.Name)
+			client.printRepoLine(max, repoConfig.Name, client.paint(colorYellow, "skipped (no commits)"))
+			continue
+		}
+		client.logger.Debug("bundling", "repo", repoConfig.Name)
+		bundle := filepath.Join(tmpDir, repoConfig.Name+".bundle")
+		if _, err := runGit(dir, "bundle", "create", bundle, "--all"); err != nil {
+			return fmt.Errorf("%s: %w", repoConfig.Name, err)
+		}
+		if err := addFileToTar(tw, path.Join(backupBundlesDir, repoConfig.Name+".bundle"), bundle); err != nil {
+			return err
+		}
+		client.printRepoLine(max, repoConfig.Name, "bundled")
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+type RestoreOptions struct {
+	// Confirm approves writing an included config file that was outside the
+	// directory of the config file, at the absolute path it had. Such files
+	// are skipped, with their repos, when it is nil or declines.
+	Confirm func(path string) bool
+}
+
+// Restore extracts an archive written by Backup into the workspace, writing the
+// config files unless they exist and cloning every bundle into its configured
+// directory, mirrors as mirrors. Repos whose directory already exists or
+// that have no bundle in the archive are left out, repos failing to clone
+// are reported once the others are restored.
+func (client *RepoManager) Restore(archive string, opts RestoreOptions) error {
+	workspace := client.workspace
+	client.logger.Info("restoring", "archive", archive, "workspace", workspace)
+	tmpDir, err := os.MkdirTemp("", "repos-restore")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gr)
+
+	cfgFile := ""
+	// configs maps the included and nested config files extracted to where
+	// they are restored.
+	configs := make(map[string]string)
+	var nested []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name := path.Clean(header.Name)
+		if strings.HasPrefix(name, "..") || path.IsAbs(name) {
+			return fmt.Errorf("invalid path %s in archive", header.Name)
+		}
+		dest := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		out, err := os.Create(dest)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, tr); err != nil {
+			out.Close()
+			return err
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+		if path.Dir(name) == backupConfigDir {
+			cfgFile = dest
+		}
+		if target := client.restoreTargetOf(name); target != "" {
+			if strings.HasPrefix(name, backupExternalDir+"/") && (opts.Confirm == nil || !opts.Confirm(target)) {
+				client.logger.Warn("skipping config file outside the config directory", "file", target)
+				continue
+			}
+			configs[dest] = target
+			if strings.HasPrefix(name, backupNestedDir+"/") {
+				nested = append(nested, dest)
+			}
+		}
+	}
+	if cfgFile == "" {
+		return fmt.Errorf("%s contains no config file", archive)
+	}
+
+	cfgBytes, err := os.ReadFile(cfgFile)
+	if err != nil {
+		return err
+	}
+	v := newViper()
+	v.SetConfigFile(cfgFile)
+	if err := v.ReadInConfig(); err != nil {
+		return err
+	}
+	var config ReposConfig
+	if err := v.Unmarshal(&config); err != nil {
+		return err
+	}
+	if err := mergeArchivedConfigs(&config, configs, nested, tmpDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(workspace, 0755); err != nil {
+		return err
+	}
+	target := client.config.CfgFile
+	if _, err := os.Stat(target); os.IsNotExist(err) {
+		if err := os.WriteFile(target, cfgBytes, 0644); err != nil {
+			return err
+		}
+	} else {
+		client.logger.Warn("keeping existing config", "file", target)
+	}
+	for dest, target := range configs {
+		if _, err := os.Stat(target); err == nil {
+			client.logger.Warn("keeping existing config", "file", target)
+			continue
+		}
+		if err := restoreFile(dest, target); err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, 0, len(config.Repos))
+	for name := range config.Repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	errs := make(map[string]error)
+	for _, name := range names {
+		repoConfig := config.Repos[name]
+		dir := repoConfig.FullDir(workspace)
+		if _, err := os.Stat(dir); err == nil {
+			fmt.Printf("%s exists, skipped\n", dir)
+			continue
+		}
+		bundle := filepath.Join(tmpDir, backupBundlesDir, name+".bundle")
+		if _, err := os.Stat(bundle); err != nil {
+			fmt.Printf("%s has no bundle in the archive, skipped\n", dir)
+			continue
+		}
+		mirror := config.Mirror
+		if repoConfig.Mirror != nil {
+			mirror = *repoConfig.Mirror
+		}
+		client.logger.Debug("cloning bundle", "bundle", bundle, "dir", dir, "mirror", mirror)
+		if err := restoreBundle(workspace, bundle, dir, repoConfig, mirror); err != nil {
+			errs[name] = err
+			continue
+		}
+		fmt.Printf("%s restored\n", dir)
+	}
+	if len(errs) > 0 {
+		return &BatchError{Errors: errs}
+	}
+	return nil
+}
+
+// restoreBundle clones bundle into dir, as a mirror with mirror, and points
+// origin back at the url of repoConfig.
+func restoreBundle(workspace, bundle, dir string, repoConfig *RepoConfig, mirror bool) error {
+	args := []string{"clone", bundle, dir}
+	switch {
+	case mirror:
+		args = []string{"clone", "--mirror", bundle, dir}
+	case repoConfig.Branch.Main() != "":
+		args = append(args, "--branch", repoConfig.Branch.Main())
+	}
+	if _, err := runGit(workspace, args...); err != nil {
+		return err
+	}
+	if repoConfig.Url != "" {
+		if _, err := runGit(dir, "remote", "set-url", "origin", repoConfig.RemoteURL()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeArchivedConfigs adds the repos of the included and nested config
+// files extracted from an archive to config, with the dirs of nested repos
+// made relative to the workspace and the nested files overriding the others,
+// as LoadNested does.
+func mergeArchivedConfigs(config *ReposConfig, configs map[string]string, nested []string, tmpDir string) error {
+	if config.Repos == nil {
+		config.Repos = make(map[string]*RepoConfig)
+	}
+	for dest := range configs {
+		if contains(nested, dest) {
+			continue
+		}
+		included, err := readIncludedConfig(dest)
+		if err != nil {
+			return err
+		}
+		for name, repoConfig := range included.Repos {
+			config.Repos[name] = repoConfig
+		}
+	}
+	// Files further up the workspace go first, for those below to override
+	// them.
+	sort.Slice(nested, func(i, j int) bool {
+		return strings.Count(nested[i], string(filepath.Separator)) < strings.Count(nested[j], string(filepath.Separator))
+	})
+	for _, dest := range nested {
+		file, err := readIncludedConfig(dest)
+		if err != nil {
+			return err
+		}
+		dir, err := filepath.Rel(filepath.Join(tmpDir, backupNestedDir), filepath.Dir(dest))
+		if err != nil {
+			return err
+		}
+		for name, repoConfig := range file.Repos {
+			if repoDir := expandPath(repoConfig.Dir); !filepath.IsAbs(repoDir) {
+				repoConfig.Dir = filepath.Join(dir, repoConfig.Dir)
+			}
+			config.Repos[name] = repoConfig
+		}
+	}
+	return nil
+}
+
+// restoreFile copies the config file dest extracted from an archive to
+// target.
+func restoreFile(dest, target string) error {
+	data, err := os.ReadFile(dest)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(target, data, 0644)
+}
@@ -0,0 +1,132 @@
+package repos
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Branches are the branches tracked in a repo, written as a single branch or
+// a list in the config. The first one is the branch the working tree is
+// expected to be on, sync fast-forwards the others too.
+type Branches []string
+
+// Main returns the branch the working tree is expected to be on, if any.
+func (branches Branches) Main() string {
+	if len(branches) == 0 {
+		return ""
+	}
+	return branches[0]
+}
+
+func (branches *Branches) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var branch string
+		if err := node.Decode(&branch); err != nil {
+			return err
+		}
+		*branches = nil
+		if branch != "" {
+			*branches = Branches{branch}
+		}
+		return nil
+	}
+	return node.Decode((*[]string)(branches))
+}
+
+// MarshalYAML keeps a single branch a plain string.
+func (branches Branches) MarshalYAML() (interface{}, error) {
+	if len(branches) <= 1 {
+		return branches.Main(), nil
+	}
+	return []string(branches), nil
+}
+
+// BranchPolicy decides what happens when a repo isn't on its configured branch.
+type BranchPolicy string
+
+const (
+	// BranchPolicyFail aborts the operation for the repo.
+	BranchPolicyFail BranchPolicy = "fail"
+	// BranchPolicySkip leaves the repo alone and reports it as skipped.
+	BranchPolicySkip BranchPolicy = "skip"
+	// BranchPolicyCheckout checks out the configured branch first.
+	BranchPolicyCheckout BranchPolicy = "checkout"
+)
+
+func ParseBranchPolicy(s string) (BranchPolicy, error) {
+	switch policy := BranchPolicy(s); policy {
+	case "", BranchPolicyFail, BranchPolicySkip, BranchPolicyCheckout:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid branch policy %q, must be one of fail, skip or checkout", s)
+}
+
+func WithBranchPolicy(policy BranchPolicy) NewRepoManagerClientOptions {
+	return func(client *RepoManager) {
+		client.branchPolicy = policy
+	}
+}
+
+func WithDetachedPolicy(policy BranchPolicy) NewRepoManagerClientOptions {
+	return func(client *RepoManager) {
+		client.detachedPolicy = policy
+	}
+}
+
+// resolvePolicy returns the first policy that is set: the command line wins
+// over the repo config, which wins over the workspace config.
+func resolvePolicy(policies ...BranchPolicy) BranchPolicy {
+	for _, policy := range policies {
+		if policy != "" {
+			return policy
+		}
+	}
+	return BranchPolicyFail
+}
+
+func (client *RepoManager) branchPolicyFor(repoConfig *RepoConfig) BranchPolicy {
+	return resolvePolicy(client.branchPolicy, repoConfig.BranchPolicy, client.config.BranchPolicy)
+}
+
+func (client *RepoManager) detachedPolicyFor(repoConfig *RepoConfig) BranchPolicy {
+	return resolvePolicy(client.detachedPolicy, repoConfig.DetachedPolicy, client.config.DetachedPolicy)
+}
+
+// ensureBranch makes sure the repo is on a branch, and on its configured
+// branch if it has one, before it is pulled or pushed. It returns why the repo
+// should be skipped, if it should.
+func (client *RepoManager) ensureBranch(repoConfig *RepoConfig, status *RepoStatus) (string, error) {
+	if status.Branch == "" {
+		problem := fmt.Sprintf("HEAD is detached at %s", status.Commit)
+		return client.applyBranchPolicy(repoConfig, client.detachedPolicyFor(repoConfig), problem)
+	}
+	main := repoConfig.Branch.Main()
+	if main == "" || status.Branch == main {
+		return "", nil
+	}
+	problem := fmt.Sprintf("on %s instead of %s", status.Branch, main)
+	return client.applyBranchPolicy(repoConfig, client.branchPolicyFor(repoConfig), problem)
+}
+
+func (client *RepoManager) applyBranchPolicy(repoConfig *RepoConfig, policy BranchPolicy, problem string) (string, error) {
+	switch policy {
+	case BranchPolicySkip:
+		client.logger.Info("skipping", "repo", repoConfig.Name, "reason", problem)
+		return problem, nil
+	case BranchPolicyCheckout:
+		dir := repoConfig.FullDir(client.workspace)
+		branch := repoConfig.Branch.Main()
+		if branch == "" {
+			branch = defaultBranchOf(dir)
+		}
+		if branch == "" {
+			return "", fmt.Errorf("%s: %s and no branch to check out is configured", repoConfig.Name, problem)
+		}
+		client.logger.Info("checking out", "repo", repoConfig.Name, "branch", branch)
+		_, err := runGit(dir, "checkout", branch)
+		return "", err
+	default:
+		return "", fmt.Errorf("%s: %s", repoConfig.Name, problem)
+	}
+}
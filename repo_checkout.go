@@ -0,0 +1,65 @@
+package repos
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Checkout switches the selected repos to branch. Dirty repos and repos
+// without the branch are skipped.
+func (client *RepoManager) Checkout(branch string, opts ListOptions) error {
+	client.logger.Info("checking out", "branch", branch, "workspace", client.workspace)
+	return client.runBatch("checkout", client.selectedRepos(opts), func(repoConfig *RepoConfig) (outcome, string, error) {
+		if client.mirrorFor(repoConfig) {
+			return outcomeSkipped, "mirror", nil
+		}
+		dir := repoConfig.FullDir(client.workspace)
+		if err := client.backend.Open(dir); err != nil {
+			return outcomeSkipped, "", err
+		}
+		status, err := client.backend.Status(dir)
+		if err != nil {
+			return outcomeFailed, "", err
+		}
+		if status.Branch == branch {
+			return outcomeUpToDate, "", nil
+		}
+		if status.Changed {
+			return outcomeSkipped, "dirty", nil
+		}
+		branches, err := branchesOf(dir)
+		if err != nil {
+			return outcomeFailed, "", err
+		}
+		if !contains(branches, branch) {
+			return outcomeSkipped, fmt.Sprintf("no branch %s", branch), nil
+		}
+		client.logger.Info("checking out", "repo", repoConfig.Name, "branch", branch)
+		if _, err := runGit(dir, "checkout", branch); err != nil {
+			return outcomeFailed, "", err
+		}
+		return outcomeSucceeded, "", nil
+	})
+}
+
+// Branches returns the local and origin branches of the selected repos that
+// are checked out, sorted and without duplicates.
+func (client *RepoManager) Branches(opts ListOptions) []string {
+	seen := make(map[string]bool)
+	var all []string
+	for _, repoConfig := range client.selectedRepos(opts) {
+		branches, err := branchesOf(repoConfig.FullDir(client.workspace))
+		if err != nil {
+			client.logger.Debug("listing branches failed", "repo", repoConfig.Name, "error", err)
+			continue
+		}
+		for _, branch := range branches {
+			if !seen[branch] {
+				seen[branch] = true
+				all = append(all, branch)
+			}
+		}
+	}
+	sort.Strings(all)
+	return all
+}
@@ -0,0 +1,107 @@
+package repos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ErrInterrupted matches, with errors.Is, the error of a batch operation that
+// stopped early because its context was canceled, e.g. on Ctrl-C.
+var ErrInterrupted = errors.New("interrupted")
+
+// statusFile is the file in the workspace directory that records which repos
+// the last batch operation got through.
+const statusFile = ".status.json"
+
+// reasonInterrupted is the skip reason of repos that weren't started because
+// the batch operation was interrupted.
+const reasonInterrupted = "interrupted"
+
+// WithContext makes batch operations stop starting repos once ctx is done.
+// The repos in progress are finished.
+func WithContext(ctx context.Context) NewRepoManagerClientOptions {
+	return func(client *RepoManager) {
+		client.ctx = ctx
+	}
+}
+
+func (client *RepoManager) interrupted() bool {
+	return client.ctx != nil && client.ctx.Err() != nil
+}
+
+// batchStatus is the content of the status file.
+type batchStatus struct {
+	Command     string    `json:"command"`
+	Finished    time.Time `json:"finished"`
+	Interrupted bool      `json:"interrupted"`
+	// Done lists the repos the operation ran for, whatever the outcome.
+	Done []string `json:"done"`
+	// Undone lists the repos it didn't get to.
+	Undone []string `json:"undone"`
+	// NeedsAttention lists the repos that stopped on conflicts or a
+	// diverged branch.
+	NeedsAttention []string `json:"needs_attention,omitempty"`
+}
+
+// writeStatus records which repos a batch operation got through. The file is
+// replaced atomically, so an interruption never leaves half of it behind.
+func (client *RepoManager) writeStatus(command string, repoConfigs []*RepoConfig, summary *runSummary) {
+	status := &batchStatus{
+		Command:     command,
+		Finished:    time.Now(),
+		Interrupted: client.interrupted(),
+		Done:        []string{},
+		Undone:      []string{},
+	}
+	seen := make(map[string]bool)
+	for _, o := range summary.outcomes {
+		seen[o.name] = true
+		if o.conflict != nil {
+			status.NeedsAttention = append(status.NeedsAttention, o.name)
+		}
+		if o.outcome == outcomeSkipped && o.reason == reasonInterrupted {
+			status.Undone = append(status.Undone, o.name)
+		} else {
+			status.Done = append(status.Done, o.name)
+		}
+	}
+	for _, repoConfig := range repoConfigs {
+		if !seen[repoConfig.Name] {
+			status.Undone = append(status.Undone, repoConfig.Name)
+		}
+	}
+	sort.Strings(status.Done)
+	sort.Strings(status.Undone)
+	sort.Strings(status.NeedsAttention)
+
+	if err := writeFileAtomic(filepath.Join(client.workspace, statusFile), status); err != nil {
+		client.logger.Warn("writing the status file failed", "error", err)
+	}
+}
+
+// writeFileAtomic writes value as JSON to a temporary file next to path and
+// renames it over path.
+func writeFileAtomic(path string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
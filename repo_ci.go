@@ -0,0 +1,92 @@
+package repos
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CIEntry is the CI state of the head of the base branch of a repo.
+type CIEntry struct {
+	Repo   string `json:"repo"`
+	Branch string `json:"branch"`
+	Commit string `json:"commit"`
+	// Checks is ChecksSuccess, ChecksFailure, ChecksPending or StateNone.
+	Checks string `json:"checks"`
+}
+
+// remoteHead returns the commit branch points to on origin, as of now
+// rather than of the last fetch.
+func (client *RepoManager) remoteHead(dir, branch string) (string, error) {
+	out, err := client.gitCLI().originGit(dir, "ls-remote", "origin", "refs/heads/"+branch)
+	if err != nil {
+		return "", err
+	}
+	sha, _, _ := strings.Cut(out, "\t")
+	if sha == "" {
+		return "", fmt.Errorf("origin has no branch %s", branch)
+	}
+	return sha, nil
+}
+
+// CI returns the state of the CI checks of the head of the configured or
+// default branch on origin of every selected repo on a host with a provider
+// configured. Repos whose state couldn't be looked up are reported in a
+// *BatchError next to the entries of the others.
+func (client *RepoManager) CI(opts ListOptions) ([]*CIEntry, error) {
+	client.logger.Info("looking up CI states", "workspace", client.workspace)
+	targets, err := client.providerRepos(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	entries := []*CIEntry{}
+	errs := make(map[string]error)
+	slots := make(chan struct{}, client.jobsLimit())
+	wg := sync.WaitGroup{}
+	for _, t := range targets {
+		wg.Add(1)
+		go func(t providerRepo) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			client.logger.Debug("looking up CI state", "repo", t.repoConfig.Name)
+			entry, err := client.ciEntry(t)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[t.repoConfig.Name] = err
+				return
+			}
+			entries = append(entries, entry)
+		}(t)
+	}
+	wg.Wait()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Repo < entries[j].Repo
+	})
+	if len(errs) > 0 {
+		return entries, &BatchError{Errors: errs}
+	}
+	return entries, nil
+}
+
+func (client *RepoManager) ciEntry(t providerRepo) (*CIEntry, error) {
+	dir := t.repoConfig.FullDir(client.workspace)
+	branch := client.baseOf(t.repoConfig, dir)
+	if branch == "" {
+		return nil, fmt.Errorf("can't determine the default branch")
+	}
+	sha, err := client.remoteHead(dir, branch)
+	if err != nil {
+		return nil, err
+	}
+	checks, err := t.provider.commitChecks(t.project, sha)
+	if err != nil {
+		return nil, err
+	}
+	return &CIEntry{Repo: t.repoConfig.Name, Branch: branch, Commit: sha, Checks: checks}, nil
+}
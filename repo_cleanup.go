@@ -0,0 +1,121 @@
+package repos
+
+import (
+	"fmt"
+	"strings"
+)
+
+type CleanupOptions struct {
+	// DryRun only lists the branches that would be deleted.
+	DryRun bool
+	// Prune also removes remote-tracking refs whose branch is gone from origin.
+	Prune bool
+}
+
+// mergedBranches lists the local branches merged into base, except base, the
+// checked out branch and those kept.
+func mergedBranches(dir string, base string, keep func(branch string) bool) ([]string, error) {
+	current, _ := runGit(dir, "symbolic-ref", "--short", "HEAD")
+	out, err := runGit(dir, "branch", "--format=%(refname:short)", "--merged", base)
+	if err != nil {
+		return nil, err
+	}
+	var branches []string
+	for _, branch := range strings.Split(out, "\n") {
+		if branch == "" || branch == base || branch == current || branch == strings.TrimPrefix(base, "origin/") || keep(branch) {
+			continue
+		}
+		branches = append(branches, branch)
+	}
+	return branches, nil
+}
+
+func (client *RepoManager) cleanupSingleRepo(repoConfig *RepoConfig, opts CleanupOptions) ([]string, error) {
+	dir := repoConfig.FullDir(client.workspace)
+	base := repoConfig.Branch.Main()
+	if base == "" {
+		base = defaultBranchOf(dir)
+	}
+	if base == "" {
+		return nil, fmt.Errorf("can't determine the default branch")
+	}
+	if _, err := runGit(dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+base); err != nil {
+		base = "origin/" + base
+	}
+
+	// The tracked and protected branches are there to stay, merged or not.
+	branches, err := mergedBranches(dir, base, func(branch string) bool {
+		return contains(repoConfig.Branch, branch) || client.protectedBranch([]string{branch}) != ""
+	})
+	if err != nil {
+		return nil, err
+	}
+	var done []string
+	for _, branch := range branches {
+		if !opts.DryRun {
+			if _, err := runGit(dir, "branch", "--delete", branch); err != nil {
+				return done, err
+			}
+		}
+		done = append(done, branch)
+	}
+
+	if opts.Prune {
+		args := []string{"remote", "prune", "origin"}
+		if opts.DryRun {
+			args = []string{"remote", "prune", "--dry-run", "origin"}
+		}
+		out, err := runGit(dir, args...)
+		if err != nil {
+			return done, err
+		}
+		for _, line := range strings.Split(out, "\n") {
+			if i := strings.Index(line, "[would prune] "); i >= 0 {
+				done = append(done, line[i+len("[would prune] "):])
+			} else if i := strings.Index(line, "[pruned] "); i >= 0 {
+				done = append(done, line[i+len("[pruned] "):])
+			}
+		}
+	}
+	return done, nil
+}
+
+// CleanupBranches deletes local branches already merged into the configured
+// (or default) branch of every repo.
+func (client *RepoManager) CleanupBranches(opts CleanupOptions) error {
+	client.logger.Info("cleaning up merged branches", "workspace", client.workspace)
+	max := client.nameWidth()
+	verb := "deleted"
+	if opts.DryRun {
+		verb = "would delete"
+	}
+	history := client.newHistory("cleanup-branches")
+	if !opts.DryRun {
+		defer history.record()
+	}
+	var failed []string
+	for _, repoConfig := range client.sortedRepos() {
+		if client.failingFast(failed) {
+			break
+		}
+		head := headOf(repoConfig.FullDir(client.workspace))
+		branches, err := client.cleanupSingleRepo(repoConfig, opts)
+		for _, branch := range branches {
+			client.printRepoLine(max, repoConfig.Name, verb+" "+branch)
+		}
+		switch {
+		case err != nil:
+			client.printRepoLine(max, repoConfig.Name, err)
+			history.fail(repoConfig, head, err)
+			failed = append(failed, repoConfig.Name)
+		case len(branches) > 0:
+			history.add(repoConfig, head, outcomeSucceeded, "deleted "+strings.Join(branches, ", "))
+		default:
+			history.add(repoConfig, head, outcomeUpToDate, "")
+		}
+	}
+	if len(failed) > 0 {
+		return failedIn("cleanup", failed)
+	}
+	return nil
+}
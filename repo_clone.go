@@ -0,0 +1,156 @@
+package repos
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+type CloneOptions struct {
+	// Depth overrides the configured clone depth when positive.
+	Depth int
+	// SingleBranch overrides the configured single_branch setting when set.
+	SingleBranch *bool
+	// Filter overrides the configured partial clone filter when set.
+	Filter string
+	// All also clones the lazy repos, which are otherwise cloned on first
+	// use.
+	All bool
+}
+
+var cloneFilterPattern = regexp.MustCompile(`^(blob:none|blob:limit=[0-9]+[kmg]?|tree:[0-9]+)$`)
+
+// ParseCloneFilter checks a partial clone filter such as blob:none, which
+// leaves out every blob until it is needed, or tree:0.
+func ParseCloneFilter(s string) (string, error) {
+	if s == "" || cloneFilterPattern.MatchString(s) {
+		return s, nil
+	}
+	return "", fmt.Errorf("invalid clone filter %q, must be blob:none, blob:limit=<size> or tree:<depth>", s)
+}
+
+// filterFor returns the partial clone filter of a repo, empty for a full clone.
+func (client *RepoManager) filterFor(repoConfig *RepoConfig) string {
+	if repoConfig.Filter != "" {
+		return repoConfig.Filter
+	}
+	return client.config.Filter
+}
+
+// depthFor returns the clone depth of a repo, 0 means full history.
+func (client *RepoManager) depthFor(repoConfig *RepoConfig) int {
+	if repoConfig.Depth > 0 {
+		return repoConfig.Depth
+	}
+	return client.config.Depth
+}
+
+func (client *RepoManager) singleBranchFor(repoConfig *RepoConfig) bool {
+	if repoConfig.SingleBranch != nil {
+		return *repoConfig.SingleBranch
+	}
+	return client.config.SingleBranch
+}
+
+func (client *RepoManager) cloneSpec(repoConfig *RepoConfig, opts CloneOptions) CloneSpec {
+	spec := CloneSpec{
+		URL:          repoConfig.RemoteURL(),
+		Dir:          repoConfig.FullDir(client.workspace),
+		Branch:       repoConfig.Branch.Main(),
+		Depth:        client.depthFor(repoConfig),
+		SingleBranch: client.singleBranchFor(repoConfig),
+		Filter:       client.filterFor(repoConfig),
+		Mirror:       client.mirrorFor(repoConfig),
+	}
+	if opts.Depth > 0 {
+		spec.Depth = opts.Depth
+	}
+	if opts.SingleBranch != nil {
+		spec.SingleBranch = *opts.SingleBranch
+	}
+	if opts.Filter != "" {
+		spec.Filter = opts.Filter
+	}
+	return spec
+}
+
+// Clone clones every configured repo whose directory doesn't exist yet. Lazy
+// repos are left for their first use unless opts.All is set.
+func (client *RepoManager) Clone(opts CloneOptions) error {
+	client.logger.Info("cloning missing repos", "workspace", client.workspace)
+	max := client.nameWidth()
+	history := client.newHistory("clone")
+	defer history.record()
+	var failed []string
+	for _, repoConfig := range client.configuredRepos() {
+		if client.failingFast(failed) {
+			break
+		}
+		dir := repoConfig.FullDir(client.workspace)
+		if _, err := os.Stat(dir); err == nil {
+			client.logger.Debug("skipping existing repo", "dir", dir)
+			continue
+		}
+		if client.lazyFor(repoConfig) && !opts.All {
+			client.logger.Debug("skipping lazy repo", "repo", repoConfig.Name)
+			continue
+		}
+		if err := client.cloneRepo(repoConfig, opts); err != nil {
+			client.printRepoLine(max, repoConfig.Name, err)
+			history.fail(repoConfig, "", err)
+			failed = append(failed, repoConfig.Name)
+			continue
+		}
+		client.printRepoLine(max, repoConfig.Name, "cloned")
+		history.add(repoConfig, "", outcomeSucceeded, "")
+	}
+	client.syncGoWork()
+	if len(failed) > 0 {
+		return failedIn("clone", failed)
+	}
+	return nil
+}
+
+// Unshallow fetches the missing history of shallow repos. With a positive
+// deepen only that many more commits are fetched.
+func (client *RepoManager) Unshallow(deepen int) error {
+	client.logger.Info("unshallowing", "workspace", client.workspace)
+	max := client.nameWidth()
+	history := client.newHistory("unshallow")
+	defer history.record()
+	var failed []string
+	for _, repoConfig := range client.sortedRepos() {
+		if client.failingFast(failed) {
+			break
+		}
+		dir := repoConfig.FullDir(client.workspace)
+		head := headOf(dir)
+		shallow, err := runGit(dir, "rev-parse", "--is-shallow-repository")
+		if err != nil {
+			client.printRepoLine(max, repoConfig.Name, err)
+			history.fail(repoConfig, head, err)
+			failed = append(failed, repoConfig.Name)
+			continue
+		}
+		if shallow != "true" {
+			continue
+		}
+		args := []string{"fetch", "--unshallow", "origin"}
+		if deepen > 0 {
+			args = []string{"fetch", "--deepen=" + strconv.Itoa(deepen), "origin"}
+		}
+		if _, err := runGit(dir, args...); err != nil {
+			client.printRepoLine(max, repoConfig.Name, err)
+			history.fail(repoConfig, head, err)
+			failed = append(failed, repoConfig.Name)
+			continue
+		}
+		client.printRepoLine(max, repoConfig.Name, "deepened")
+		history.add(repoConfig, head, outcomeSucceeded, "")
+	}
+	if len(failed) > 0 {
+		return failedIn("unshallow", failed)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package repos
+
+import "fmt"
+
+type CommitOptions struct {
+	// Select limits the repos to commit in.
+	Select ListOptions
+	// Message is the commit message template, see MessageData.
+	Message string
+	// Push pushes the commits to origin.
+	Push bool
+}
+
+// Commit commits every change of the selected repos, untracked files
+// included, with the message opts.Message renders for each, and optionally
+// pushes the commits. Repos without changes are left alone.
+func (client *RepoManager) Commit(opts CommitOptions) error {
+	if opts.Message == "" {
+		return fmt.Errorf("a commit message is required")
+	}
+	tmpl, err := parseMessage(opts.Message)
+	if err != nil {
+		return err
+	}
+
+	client.logger.Info("committing", "workspace", client.workspace)
+	return client.runBatch("commit", client.selectedRepos(opts.Select), func(repoConfig *RepoConfig) (outcome, string, error) {
+		if client.mirrorFor(repoConfig) {
+			return outcomeSkipped, "mirror", nil
+		}
+		dir := repoConfig.FullDir(client.workspace)
+		if err := client.backend.Open(dir); err != nil {
+			return outcomeSkipped, "", err
+		}
+		clean, err := IfRepoIsClean(dir)
+		if err != nil {
+			return outcomeFailed, "", err
+		}
+		if clean {
+			return outcomeUpToDate, "", nil
+		}
+		message, err := executeMessage(tmpl, client.messageData(repoConfig))
+		if err != nil {
+			return outcomeFailed, "", err
+		}
+		if ok, err := client.reviewedCommit(repoConfig, message); !ok || err != nil {
+			return outcomeSkipped, reasonDeclined, err
+		}
+		if _, err := runGit(dir, "add", "--all"); err != nil {
+			return outcomeFailed, "", err
+		}
+		if _, err := runGit(dir, "commit", "-m", message); err != nil {
+			return outcomeFailed, "", err
+		}
+		if opts.Push && repoConfig.IsReadOnly() {
+			return outcomeSucceeded, reasonNotPushed, nil
+		}
+		if opts.Push {
+			if _, err := client.push(repoConfig, client.pushSpec(repoConfig, PushOptions{}), false); err != nil {
+				return outcomeFailed, "", err
+			}
+		}
+		return outcomeSucceeded, "", nil
+	})
+}
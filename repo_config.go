@@ -1,20 +1,352 @@
 package repos
 
-import "path/filepath"
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
 
 type ReposConfig struct {
-	CfgFile string                 `yaml:"-"`
-	Version string                 `yaml:"version"`
+	CfgFile         string                         `yaml:"-"`
+	Version         string                         `yaml:"version"`
+	Root            string                         `yaml:"root,omitempty"`
+	KeyFile         string                         `yaml:"key_file,omitempty" mapstructure:"key_file"`
+	BranchPolicy    BranchPolicy                   `yaml:"branch_policy,omitempty" mapstructure:"branch_policy"`
+	DetachedPolicy  BranchPolicy                   `yaml:"detached_policy,omitempty" mapstructure:"detached_policy"`
+	SyncStrategy    SyncStrategy                   `yaml:"sync_strategy,omitempty" mapstructure:"sync_strategy"`
+	Depth           int                            `yaml:"depth,omitempty"`
+	Filter          string                         `yaml:"filter,omitempty"`
+	Mirror          bool                           `yaml:"mirror,omitempty"`
+	Lazy            bool                           `yaml:"lazy,omitempty"`
+	SingleBranch    bool                           `yaml:"single_branch,omitempty" mapstructure:"single_branch"`
+	SkipUnchanged   bool                           `yaml:"skip_unchanged,omitempty" mapstructure:"skip_unchanged"`
+	PushTags        bool                           `yaml:"push_tags,omitempty" mapstructure:"push_tags"`
+	PushAllBranches bool                           `yaml:"push_all_branches,omitempty" mapstructure:"push_all_branches"`
+	Jobs            int                            `yaml:"jobs,omitempty"`
+	Backend         string                         `yaml:"backend,omitempty"`
+	Notify          NotifyPolicy                   `yaml:"notify,omitempty"`
+	OnError         ErrorPolicy                    `yaml:"on_error,omitempty" mapstructure:"on_error"`
+	Proxies         map[string]string              `yaml:"proxies,omitempty"`
+	Auth            map[string]*HostAuth           `yaml:"auth,omitempty"`
+	HostJobs        map[string]int                 `yaml:"host_jobs,omitempty" mapstructure:"host_jobs"`
+	SSHMultiplex    bool                           `yaml:"ssh_multiplex,omitempty" mapstructure:"ssh_multiplex"`
+	GoWork          bool                           `yaml:"go_work,omitempty" mapstructure:"go_work"`
+	MaxFileSize     string                         `yaml:"max_file_size,omitempty" mapstructure:"max_file_size"`
+	DiscoveryIgnore []string                       `yaml:"discovery_ignore,omitempty" mapstructure:"discovery_ignore"`
+	Protected       []string                       `yaml:"protected_branches,omitempty" mapstructure:"protected_branches"`
+	Providers       map[string]*ProviderConfig     `yaml:"providers,omitempty"`
+	Schedules       map[string]*ScheduleConfig     `yaml:"schedules,omitempty"`
+	Notifications   map[string]*NotificationConfig `yaml:"notifications,omitempty"`
+	Include         []string                       `yaml:"include,omitempty"`
+	Repos           map[string]*RepoConfig         `yaml:"repos"`
+	Workspaces      map[string]*WorkspaceConfig    `yaml:"workspaces,omitempty"`
+
+	// parent is the full config when this is the view of a named workspace.
+	parent *ReposConfig
+	// secrets are the decrypted values by their path in the file, see
+	// secretPath.
+	secrets map[string]secret
+	// includes are the config files loaded through Include and the nested
+	// ones.
+	includes []*includedFile
+	// shadowed are the repos overridden by nested config files.
+	shadowed []*RepoConfig
+}
+
+// WorkspaceConfig is a named workspace with its own root, auth and repos,
+// selected with the --workspace flag.
+type WorkspaceConfig struct {
+	Root    string                 `yaml:"root"`
+	KeyFile string                 `yaml:"key_file,omitempty" mapstructure:"key_file"`
 	Repos   map[string]*RepoConfig `yaml:"repos"`
 }
 
 type RepoConfig struct {
-	Name   string `yaml:"-"`
-	Dir    string `yaml:"dir"`
-	Url    string `yaml:"url"`
-	Branch string `yaml:"branch"`
+	Name            string       `yaml:"-"`
+	Enabled         *bool        `yaml:"enabled,omitempty"`
+	Dir             string       `yaml:"dir"`
+	Url             string       `yaml:"url"`
+	Upstream        string       `yaml:"upstream,omitempty"`
+	Branch          Branches     `yaml:"branch"`
+	BranchPolicy    BranchPolicy `yaml:"branch_policy,omitempty" mapstructure:"branch_policy"`
+	DetachedPolicy  BranchPolicy `yaml:"detached_policy,omitempty" mapstructure:"detached_policy"`
+	SyncStrategy    SyncStrategy `yaml:"sync_strategy,omitempty" mapstructure:"sync_strategy"`
+	Depth           int          `yaml:"depth,omitempty"`
+	Filter          string       `yaml:"filter,omitempty"`
+	Mirror          *bool        `yaml:"mirror,omitempty"`
+	Lazy            *bool        `yaml:"lazy,omitempty"`
+	ReadOnly        bool         `yaml:"readonly,omitempty" mapstructure:"readonly"`
+	SingleBranch    *bool        `yaml:"single_branch,omitempty" mapstructure:"single_branch"`
+	PushTags        *bool        `yaml:"push_tags,omitempty" mapstructure:"push_tags"`
+	PushAllBranches *bool        `yaml:"push_all_branches,omitempty" mapstructure:"push_all_branches"`
+	After           []string     `yaml:"after,omitempty"`
+	Groups          []string     `yaml:"groups,omitempty"`
+	// SerialGroup names a set of repos batch operations never work on at
+	// the same time, e.g. because they share a cache or a mount.
+	SerialGroup string `yaml:"serial_group,omitempty" mapstructure:"serial_group"`
+
+	// source is the included config file the repo is defined in, empty for
+	// the main one.
+	source string
+}
+
+// homeDir returns the home directory of the user. On Windows, where HOME is
+// usually unset, it falls back to USERPROFILE and HOMEDRIVE with HOMEPATH.
+func homeDir() string {
+	if home, err := os.UserHomeDir(); err == nil {
+		return home
+	}
+	if home := os.Getenv("USERPROFILE"); home != "" {
+		return home
+	}
+	if drive, path := os.Getenv("HOMEDRIVE"), os.Getenv("HOMEPATH"); drive != "" && path != "" {
+		return drive + path
+	}
+	return ""
+}
+
+// expandPath expands ${VAR} and $VAR references and a leading ~ to the home
+// directory, so one config file works across machines.
+func expandPath(value string) string {
+	value = os.ExpandEnv(value)
+	if value == "~" || strings.HasPrefix(value, "~/") || strings.HasPrefix(value, "~"+string(filepath.Separator)) {
+		if home := homeDir(); home != "" {
+			value = home + value[1:]
+		}
+	}
+	return value
+}
+
+// IsEnabled reports whether the repo takes part in batch operations. Repos are
+// enabled unless the config says enabled: false.
+func (config *RepoConfig) IsEnabled() bool {
+	return config.Enabled == nil || *config.Enabled
+}
+
+// IsReadOnly reports whether the repo is never pushed, e.g. because it is
+// vendored or belongs to a third party. It is still pulled and fetched.
+func (config *RepoConfig) IsReadOnly() bool {
+	return config.ReadOnly
 }
 
 func (config *RepoConfig) FullDir(workspace string) string {
-	return filepath.Join(workspace, config.Dir)
+	dir := expandPath(config.Dir)
+	if filepath.IsAbs(dir) {
+		return filepath.Clean(dir)
+	}
+	return filepath.Join(workspace, dir)
+}
+
+// RemoteURL returns Url with environment variables and ~ expanded.
+func (config *RepoConfig) RemoteURL() string {
+	return expandPath(config.Url)
+}
+
+// KeyPath returns the configured ssh key with environment variables and ~
+// expanded, or an empty string if none is configured.
+func (config *ReposConfig) KeyPath() string {
+	return expandPath(config.KeyFile)
+}
+
+// WorkspaceDir returns the directory repo dirs are relative to: Root when it's
+// set, otherwise the directory of the config file.
+func (config *ReposConfig) WorkspaceDir() string {
+	cfgDir := filepath.Dir(config.CfgFile)
+	root := expandPath(config.Root)
+	if root == "" {
+		return cfgDir
+	}
+	if filepath.IsAbs(root) {
+		return filepath.Clean(root)
+	}
+	return filepath.Join(cfgDir, root)
+}
+
+// root returns the full config a workspace view was made from.
+func (config *ReposConfig) root() *ReposConfig {
+	if config.parent != nil {
+		return config.parent
+	}
+	return config
+}
+
+// Workspace returns a view of the named workspace. Repos added to or removed
+// from the view end up in the workspace section when the view is saved.
+func (config *ReposConfig) Workspace(name string) (*ReposConfig, error) {
+	workspace, ok := config.Workspaces[name]
+	if !ok {
+		return nil, fmt.Errorf("workspace %s is not configured", name)
+	}
+	if workspace.Repos == nil {
+		workspace.Repos = make(map[string]*RepoConfig)
+	}
+	keyFile := workspace.KeyFile
+	if keyFile == "" {
+		keyFile = config.KeyFile
+	}
+	return &ReposConfig{
+		CfgFile:         config.CfgFile,
+		Version:         config.Version,
+		Root:            workspace.Root,
+		KeyFile:         keyFile,
+		BranchPolicy:    config.BranchPolicy,
+		DetachedPolicy:  config.DetachedPolicy,
+		SyncStrategy:    config.SyncStrategy,
+		Depth:           config.Depth,
+		Filter:          config.Filter,
+		Mirror:          config.Mirror,
+		Lazy:            config.Lazy,
+		SingleBranch:    config.SingleBranch,
+		SkipUnchanged:   config.SkipUnchanged,
+		PushTags:        config.PushTags,
+		PushAllBranches: config.PushAllBranches,
+		Jobs:            config.Jobs,
+		Backend:         config.Backend,
+		Notify:          config.Notify,
+		OnError:         config.OnError,
+		Proxies:         config.Proxies,
+		Auth:            config.Auth,
+		HostJobs:        config.HostJobs,
+		SSHMultiplex:    config.SSHMultiplex,
+		GoWork:          config.GoWork,
+		MaxFileSize:     config.MaxFileSize,
+		DiscoveryIgnore: config.DiscoveryIgnore,
+		Protected:       config.Protected,
+		Providers:       config.Providers,
+		Repos:           workspace.Repos,
+		parent:          config,
+	}, nil
+}
+
+// newViper returns a viper that doesn't split keys at dots, as the host
+// patterns that key proxies, host_jobs and auth contain them.
+func newViper() *viper.Viper {
+	return viper.NewWithOptions(viper.KeyDelimiter("::"))
+}
+
+// LoadConfigFile reads the config in cfgFile, in any format viper supports.
+func LoadConfigFile(cfgFile string) (*ReposConfig, error) {
+	v := newViper()
+	v.SetConfigFile(cfgFile)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	config := &ReposConfig{}
+	if err := v.Unmarshal(config); err != nil {
+		return nil, fmt.Errorf("%s: %w", cfgFile, err)
+	}
+	config.CfgFile = cfgFile
+	return config, nil
+}
+
+// Save writes the config back to CfgFile in the format given by its
+// extension. Repos from included files are written back to those.
+func (config *ReposConfig) Save() error {
+	if config.parent != nil {
+		return config.parent.Save()
+	}
+	main := *config
+	main.Repos = config.mainRepos()
+	data, err := main.Marshal()
+	if err != nil {
+		return err
+	}
+	var settings map[string]interface{}
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return err
+	}
+	if err := writeSettings(config.CfgFile, settings); err != nil {
+		return err
+	}
+	return config.saveIncludes()
+}
+
+// writeSettings writes settings to cfgFile. YAML files are merged into the
+// existing document so that comments on untouched entries survive, the other
+// formats are written by viper.
+func writeSettings(cfgFile string, settings map[string]interface{}) error {
+	switch strings.ToLower(filepath.Ext(cfgFile)) {
+	case ".yaml", ".yml":
+		return writeYAMLSettings(cfgFile, settings)
+	}
+	v := newViper()
+	v.SetConfigFile(cfgFile)
+	for key, value := range settings {
+		v.Set(key, value)
+	}
+	return v.WriteConfig()
+}
+
+func writeYAMLSettings(cfgFile string, settings map[string]interface{}) error {
+	var fresh yaml.Node
+	if err := fresh.Encode(settings); err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	if data, err := os.ReadFile(cfgFile); err == nil {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) == 1 && doc.Content[0].Kind == yaml.MappingNode {
+		mergeYAMLNode(doc.Content[0], &fresh)
+	} else {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&fresh}}
+	}
+
+	f, err := os.Create(cfgFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	encoder := yaml.NewEncoder(f)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// mergeYAMLNode makes the mapping dst hold the values of src while keeping the
+// order and comments of the keys dst already has.
+func mergeYAMLNode(dst *yaml.Node, src *yaml.Node) {
+	values := make(map[string]*yaml.Node)
+	var order []string
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		values[src.Content[i].Value] = src.Content[i+1]
+		order = append(order, src.Content[i].Value)
+	}
+
+	seen := make(map[string]bool)
+	var content []*yaml.Node
+	for i := 0; i+1 < len(dst.Content); i += 2 {
+		key, value := dst.Content[i], dst.Content[i+1]
+		fresh, ok := values[key.Value]
+		if !ok {
+			continue
+		}
+		seen[key.Value] = true
+		switch {
+		case value.Kind == yaml.MappingNode && fresh.Kind == yaml.MappingNode:
+			mergeYAMLNode(value, fresh)
+		case value.Kind == yaml.ScalarNode && fresh.Kind == yaml.ScalarNode && value.Value == fresh.Value:
+		default:
+			fresh.HeadComment, fresh.LineComment, fresh.FootComment = value.HeadComment, value.LineComment, value.FootComment
+			value = fresh
+		}
+		content = append(content, key, value)
+	}
+	for _, key := range order {
+		if !seen[key] {
+			content = append(content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, values[key])
+		}
+	}
+	dst.Content = content
 }
@@ -0,0 +1,121 @@
+package repos
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigVersion is the config format written by this version of repos.
+const ConfigVersion = "2"
+
+type configMigration struct {
+	from    int
+	migrate func(settings map[string]interface{}) error
+}
+
+// configMigrations upgrade the settings of version from to version from+1.
+var configMigrations = []configMigration{
+	// Version 1 duplicated the map key of every repo in a name field.
+	{from: 1, migrate: func(settings map[string]interface{}) error {
+		repos, _ := settings["repos"].(map[string]interface{})
+		for _, repo := range repos {
+			if fields, ok := repo.(map[string]interface{}); ok {
+				delete(fields, "name")
+			}
+		}
+		return nil
+	}},
+}
+
+func parseConfigVersion(version string) (int, error) {
+	if version == "" {
+		return 1, nil
+	}
+	v, err := strconv.Atoi(version)
+	if err != nil {
+		return 0, fmt.Errorf("invalid config version %q", version)
+	}
+	return v, nil
+}
+
+// readSettings reads the settings of cfgFile as they are written, without
+// splitting keys at dots as the global viper does: repo names such as a.js
+// and the host patterns keying auth, proxies and host_jobs contain them.
+func readSettings(cfgFile string) (map[string]interface{}, error) {
+	switch strings.ToLower(filepath.Ext(cfgFile)) {
+	case ".yaml", ".yml":
+		data, err := os.ReadFile(cfgFile)
+		if err != nil {
+			return nil, err
+		}
+		settings := make(map[string]interface{})
+		if err := yaml.Unmarshal(data, &settings); err != nil {
+			return nil, fmt.Errorf("%s: %w", cfgFile, err)
+		}
+		return settings, nil
+	}
+	v := newViper()
+	v.SetConfigFile(cfgFile)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	return v.AllSettings(), nil
+}
+
+// migrateFile upgrades the settings of cfgFile from version to
+// ConfigVersion and writes them back.
+func migrateFile(cfgFile string, version int) error {
+	settings, err := readSettings(cfgFile)
+	if err != nil {
+		return err
+	}
+	for _, migration := range configMigrations {
+		if migration.from < version {
+			continue
+		}
+		if err := migration.migrate(settings); err != nil {
+			return fmt.Errorf("migrating config from version %d: %w", migration.from, err)
+		}
+	}
+	settings["version"] = ConfigVersion
+	return writeSettings(cfgFile, settings)
+}
+
+// MigrateConfig upgrades the config loaded into viper to ConfigVersion and
+// returns where the original file was backed up to, or an empty string if
+// the config was already up to date. Configs written by a newer version of
+// repos are rejected.
+func MigrateConfig() (string, error) {
+	current, _ := strconv.Atoi(ConfigVersion)
+	version, err := parseConfigVersion(viper.GetString("version"))
+	if err != nil {
+		return "", err
+	}
+	if version > current {
+		return "", fmt.Errorf("config version %d is newer than the supported version %d, please upgrade repos", version, current)
+	}
+	if version == current {
+		return "", nil
+	}
+
+	cfgFile := viper.ConfigFileUsed()
+	original, err := os.ReadFile(cfgFile)
+	if err != nil {
+		return "", err
+	}
+	backup := fmt.Sprintf("%s.v%d.bak", cfgFile, version)
+	if err := os.WriteFile(backup, original, 0644); err != nil {
+		return "", err
+	}
+
+	if err := migrateFile(cfgFile, version); err != nil {
+		return "", err
+	}
+	return backup, viper.ReadInConfig()
+}
@@ -0,0 +1,75 @@
+package repos
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateFileKeepsDottedKeys(t *testing.T) {
+	for _, name := range []string{"repos.yaml", "repos.json"} {
+		t.Run(name, func(t *testing.T) {
+			cfgFile := filepath.Join(t.TempDir(), name)
+			v1 := `version: "1"
+root: /src
+auth:
+  github.com:
+    user: git
+host_jobs:
+  gitlab.example.com: 2
+repos:
+  a.js:
+    name: a.js
+    url: https://github.com/org/a.js.git
+    dir: a.js
+  b:
+    name: b
+    url: https://github.com/org/b.git
+`
+			if filepath.Ext(name) == ".json" {
+				v1 = `{"version": "1", "root": "/src", "auth": {"github.com": {"user": "git"}},
+"host_jobs": {"gitlab.example.com": 2},
+"repos": {"a.js": {"name": "a.js", "url": "https://github.com/org/a.js.git", "dir": "a.js"},
+"b": {"name": "b", "url": "https://github.com/org/b.git"}}}`
+			}
+			if err := os.WriteFile(cfgFile, []byte(v1), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := migrateFile(cfgFile, 1); err != nil {
+				t.Fatal(err)
+			}
+
+			config, err := LoadConfigFile(cfgFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Version != ConfigVersion {
+				t.Errorf("version is %q, want %q", config.Version, ConfigVersion)
+			}
+			if len(config.Repos) != 2 {
+				t.Fatalf("repos are %v, want a.js and b", config.Repos)
+			}
+			repo := config.Repos["a.js"]
+			if repo == nil || repo.RemoteURL() != "https://github.com/org/a.js.git" || repo.Dir != "a.js" {
+				t.Errorf("repo a.js is %+v", repo)
+			}
+			if auth := config.Auth["github.com"]; auth == nil || auth.User != "git" {
+				t.Errorf("auth of github.com is %+v", auth)
+			}
+			if config.HostJobs["gitlab.example.com"] != 2 {
+				t.Errorf("host_jobs are %v", config.HostJobs)
+			}
+
+			settings, err := readSettings(cfgFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			repos, _ := settings["repos"].(map[string]interface{})
+			for repoName, fields := range repos {
+				if _, ok := fields.(map[string]interface{})["name"]; ok {
+					t.Errorf("repo %s still has a name field", repoName)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,342 @@
+package repos
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ConfigSchema is the JSON Schema of the config file, for editors and CI
+// linters. Validate implements the same rules plus the checks a schema can't
+// express.
+const ConfigSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "repos config",
+  "type": "object",
+  "properties": {
+    "version": { "type": "string" },
+    "root": { "type": "string" },
+    "key_file": { "type": "string" },
+    "branch_policy": { "$ref": "#/$defs/branch_policy" },
+    "detached_policy": { "$ref": "#/$defs/branch_policy" },
+    "sync_strategy": { "$ref": "#/$defs/sync_strategy" },
+    "depth": { "type": "integer", "minimum": 0 },
+    "filter": { "$ref": "#/$defs/filter" },
+    "mirror": { "type": "boolean" },
+    "lazy": { "type": "boolean" },
+    "single_branch": { "type": "boolean" },
+    "skip_unchanged": { "type": "boolean" },
+    "push_tags": { "type": "boolean" },
+    "push_all_branches": { "type": "boolean" },
+    "jobs": { "type": "integer", "minimum": 0 },
+    "backend": { "enum": ["go-git", "git"] },
+    "notify": { "enum": ["never", "always", "failure"] },
+    "on_error": { "enum": ["continue", "fail-fast"] },
+    "proxies": { "type": "object", "additionalProperties": { "type": "string" } },
+    "host_jobs": { "type": "object", "additionalProperties": { "type": "integer", "minimum": 1 } },
+    "ssh_multiplex": { "type": "boolean" },
+    "go_work": { "type": "boolean" },
+    "max_file_size": { "type": ["string", "integer"], "pattern": "^[0-9.]+ *([kKmMgGtT]([iI]?[bB])?|[bB])?$" },
+    "discovery_ignore": { "type": "array", "items": { "type": "string" } },
+    "protected_branches": { "type": "array", "items": { "type": "string" } },
+    "auth": {
+      "type": "object",
+      "additionalProperties": {
+        "type": "object",
+        "properties": {
+          "key_file": { "type": "string" },
+          "user": { "type": "string" },
+          "port": { "type": "integer", "minimum": 1, "maximum": 65535 },
+          "token": { "type": "string" },
+          "token_env": { "type": "string" }
+        }
+      }
+    },
+    "providers": {
+      "type": "object",
+      "additionalProperties": {
+        "type": "object",
+        "required": ["type"],
+        "properties": {
+          "type": { "enum": ["github", "gitlab", "gitea"] },
+          "url": { "type": "string" },
+          "owner": { "type": "string" },
+          "token_env": { "type": "string" },
+          "token": { "type": "string" },
+          "protocol": { "enum": ["ssh", "https"] }
+        }
+      }
+    },
+    "include": { "type": "array", "items": { "type": "string" } },
+    "repos": { "$ref": "#/$defs/repos" },
+    "schedules": {
+      "type": "object",
+      "additionalProperties": {
+        "type": "object",
+        "required": ["cron", "command"],
+        "properties": {
+          "cron": { "type": "string" },
+          "command": { "enum": ["pull", "push", "sync"] },
+          "workspace": { "type": "string" },
+          "groups": { "type": "array", "items": { "type": "string" } }
+        }
+      }
+    },
+    "notifications": {
+      "type": "object",
+      "additionalProperties": {
+        "type": "object",
+        "required": ["type"],
+        "properties": {
+          "type": { "enum": ["slack", "discord", "webhook"] },
+          "url": { "type": "string" },
+          "url_env": { "type": "string" },
+          "when": { "enum": ["always", "failure"] },
+          "template": { "type": "string" }
+        }
+      }
+    },
+    "workspaces": {
+      "type": "object",
+      "additionalProperties": {
+        "type": "object",
+        "required": ["root"],
+        "properties": {
+          "root": { "type": "string" },
+          "key_file": { "type": "string" },
+          "repos": { "$ref": "#/$defs/repos" }
+        }
+      }
+    }
+  },
+  "$defs": {
+    "branch_policy": { "enum": ["fail", "skip", "checkout"] },
+    "sync_strategy": { "enum": ["pull-push", "pull-rebase-push", "fetch-ff-only-push", "push-only-if-ahead"] },
+    "filter": { "type": "string", "pattern": "^(blob:none|blob:limit=[0-9]+[kmg]?|tree:[0-9]+)$" },
+    "repos": {
+      "type": "object",
+      "additionalProperties": {
+        "type": "object",
+        "required": ["dir", "url"],
+        "properties": {
+          "name": { "type": "string" },
+          "enabled": { "type": "boolean" },
+          "dir": { "type": "string", "minLength": 1 },
+          "url": { "type": "string", "minLength": 1 },
+          "upstream": { "type": "string" },
+          "branch": {
+            "oneOf": [
+              { "type": "string" },
+              { "type": "array", "items": { "type": "string" }, "minItems": 1 }
+            ]
+          },
+          "branch_policy": { "$ref": "#/$defs/branch_policy" },
+          "detached_policy": { "$ref": "#/$defs/branch_policy" },
+          "sync_strategy": { "$ref": "#/$defs/sync_strategy" },
+          "depth": { "type": "integer", "minimum": 0 },
+          "filter": { "$ref": "#/$defs/filter" },
+          "mirror": { "type": "boolean" },
+          "lazy": { "type": "boolean" },
+          "readonly": { "type": "boolean" },
+          "single_branch": { "type": "boolean" },
+          "push_tags": { "type": "boolean" },
+          "push_all_branches": { "type": "boolean" },
+          "after": { "type": "array", "items": { "type": "string" } },
+          "groups": { "type": "array", "items": { "type": "string" } },
+          "serial_group": { "type": "string" }
+        }
+      }
+    }
+  }
+}
+`
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+type ValidationIssue struct {
+	Repo     string
+	Severity Severity
+	Message  string
+}
+
+func (issue *ValidationIssue) Error() string {
+	return fmt.Sprintf("%s: %s: %s", issue.Severity, issue.Repo, issue.Message)
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j] + 1
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+			if prev[j-1]+cost < cur[j] {
+				cur[j] = prev[j-1] + cost
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func closest(candidates []string, s string) string {
+	best, bestDistance := "", len(s)/2+1
+	for _, candidate := range candidates {
+		if d := levenshtein(candidate, s); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+func branchesOf(dir string) ([]string, error) {
+	out, err := runGit(dir, "for-each-ref", "--format=%(refname:short)", "refs/heads", "refs/remotes/origin")
+	if err != nil {
+		return nil, err
+	}
+	var branches []string
+	for _, ref := range strings.Split(out, "\n") {
+		if ref == "" || ref == "origin/HEAD" || ref == "origin" {
+			continue
+		}
+		branches = append(branches, strings.TrimPrefix(ref, "origin/"))
+	}
+	return branches, nil
+}
+
+// Validate checks the config for mistakes: missing fields, duplicate or nested
+// directories and branches that don't exist in the checked out repo. When
+// checkRemotes is set every url is also contacted with git ls-remote.
+func (config *ReposConfig) Validate(checkRemotes bool) []*ValidationIssue {
+	workspace := config.WorkspaceDir()
+	var issues []*ValidationIssue
+	report := func(repo string, severity Severity, format string, args ...interface{}) {
+		issues = append(issues, &ValidationIssue{Repo: repo, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	names := make([]string, 0, len(config.Repos))
+	for name := range config.Repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for name, schedule := range config.root().Schedules {
+		if err := schedule.validate(); err != nil {
+			report("schedule "+name, SeverityError, "%v", err)
+		} else if schedule.Workspace != "" {
+			if _, ok := config.root().Workspaces[schedule.Workspace]; !ok {
+				report("schedule "+name, SeverityError, "workspace %s is not configured", schedule.Workspace)
+			}
+		}
+	}
+
+	for name, notification := range config.root().Notifications {
+		if err := notification.validate(); err != nil {
+			report("notification "+name, SeverityError, "%v", err)
+		}
+	}
+
+	for pattern, auth := range config.Auth {
+		if auth.KeyFile == "" {
+			continue
+		}
+		if _, err := os.Stat(auth.keyPath()); err != nil {
+			report("auth "+pattern, SeverityError, "key_file %s doesn't exist", auth.KeyFile)
+		}
+	}
+
+	dirs := make(map[string]string)
+	for _, name := range names {
+		repoConfig := config.Repos[name]
+		if repoConfig.Url == "" {
+			report(name, SeverityError, "url is missing")
+		}
+		if _, err := ParseCloneFilter(repoConfig.Filter); err != nil {
+			report(name, SeverityError, "%v", err)
+		}
+		if repoConfig.Dir == "" {
+			report(name, SeverityError, "dir is missing")
+			continue
+		}
+		dir, err := filepath.Rel(workspace, repoConfig.FullDir(workspace))
+		if err != nil || dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+			report(name, SeverityWarning, "dir %s is outside of the workspace", repoConfig.Dir)
+		}
+		if other, ok := dirs[dir]; ok {
+			report(name, SeverityError, "dir %s is also used by %s", repoConfig.Dir, other)
+			continue
+		}
+		dirs[dir] = name
+	}
+	for dir, name := range dirs {
+		for otherDir, other := range dirs {
+			if dir != otherDir && strings.HasPrefix(dir, otherDir+string(filepath.Separator)) {
+				report(name, SeverityError, "dir %s is nested inside %s of %s", dir, otherDir, other)
+			}
+		}
+	}
+
+	for _, name := range names {
+		repoConfig := config.Repos[name]
+		if repoConfig.Dir == "" {
+			continue
+		}
+		fullDir := repoConfig.FullDir(workspace)
+		if _, err := os.Stat(fullDir); os.IsNotExist(err) {
+			report(name, SeverityWarning, "%s doesn't exist", fullDir)
+		} else if !isGitRepo(fullDir) {
+			report(name, SeverityError, "%s is not a git repository", fullDir)
+		} else if len(repoConfig.Branch) > 0 {
+			branches, err := branchesOf(fullDir)
+			if err != nil {
+				report(name, SeverityError, "%v", err)
+			}
+			for _, branch := range repoConfig.Branch {
+				if err != nil || contains(branches, branch) {
+					continue
+				}
+				if suggestion := closest(branches, branch); suggestion != "" {
+					report(name, SeverityError, "branch %s doesn't exist, did you mean %s?", branch, suggestion)
+				} else {
+					report(name, SeverityError, "branch %s doesn't exist", branch)
+				}
+			}
+		}
+		if checkRemotes && repoConfig.Url != "" {
+			if _, err := runGit(workspace, "ls-remote", "--heads", repoConfig.RemoteURL()); err != nil {
+				report(name, SeverityError, "remote %s is unreachable: %s", repoConfig.Url, strings.SplitN(err.Error(), "\n", 2)[0])
+			}
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Repo < issues[j].Repo
+	})
+	return issues
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package repos
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ConflictError is a pull or sync that stopped on conflicting files or
+// because the branch diverged from its upstream. The repo needs a person to
+// look at it.
+type ConflictError struct {
+	// Files are the files with conflicts, empty when the branch diverged.
+	Files []string
+	// Upstream, Ahead and Behind describe a diverged branch.
+	Upstream string
+	Ahead    int
+	Behind   int
+	// Err is the error of git, if any.
+	Err error
+}
+
+func (e *ConflictError) Error() string {
+	if len(e.Files) > 0 {
+		return "conflicts in " + strings.Join(e.Files, ", ")
+	}
+	return fmt.Sprintf("diverged from %s, %d ahead and %d behind", e.Upstream, e.Ahead, e.Behind)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
+
+// conflictedFiles returns the files of the repo in dir left with conflicts.
+func conflictedFiles(dir string) []string {
+	out, err := runGit(dir, "diff", "--name-only", "--diff-filter=U")
+	if err != nil || out == "" {
+		return nil
+	}
+	return strings.Split(out, "\n")
+}
+
+// explainConflict turns err, the failure of a pull or sync of the repo in
+// dir, into a *ConflictError when files were left with conflicts or the
+// branch diverged from its upstream, and returns it as is otherwise.
+func explainConflict(dir string, err error) error {
+	var conflict *ConflictError
+	if err == nil || errors.As(err, &conflict) {
+		return err
+	}
+	if files := conflictedFiles(dir); len(files) > 0 {
+		return &ConflictError{Files: files, Err: err}
+	}
+	upstream := upstreamOf(dir)
+	if upstream == "" {
+		return err
+	}
+	if ahead, behind, aheadErr := aheadBehind(dir, upstream); aheadErr == nil && ahead > 0 && behind > 0 {
+		return &ConflictError{Upstream: upstream, Ahead: ahead, Behind: behind, Err: err}
+	}
+	return err
+}
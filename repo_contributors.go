@@ -0,0 +1,131 @@
+package repos
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContributorOptions choose the repos and commits contributor statistics
+// are aggregated over.
+type ContributorOptions struct {
+	Select ListOptions
+	// Since and Until limit the commits by date, e.g. 2024-01-01 or 4w.
+	Since string
+	Until string
+}
+
+// RepoContribution is what one author contributed to one repo.
+type RepoContribution struct {
+	Repo    string `json:"repo"`
+	Commits int    `json:"commits"`
+	Added   int    `json:"added"`
+	Deleted int    `json:"deleted"`
+}
+
+// AuthorStats is what one author contributed across the repos, with the
+// breakdown per repo, most commits first.
+type AuthorStats struct {
+	Name    string              `json:"name"`
+	Email   string              `json:"email"`
+	Commits int                 `json:"commits"`
+	Added   int                 `json:"added"`
+	Deleted int                 `json:"deleted"`
+	Repos   []*RepoContribution `json:"repos"`
+}
+
+// logContributions parses git log output of commit headers, a NUL followed
+// by the author name and email, and numstat lines, into contributions per
+// author email.
+func logContributions(repo, out string) map[string]*AuthorStats {
+	authors := make(map[string]*AuthorStats)
+	var current *RepoContribution
+	for _, line := range strings.Split(out, "\n") {
+		if header, ok := strings.CutPrefix(line, "\x00"); ok {
+			name, email, _ := strings.Cut(header, "\x00")
+			key := strings.ToLower(email)
+			author, ok := authors[key]
+			if !ok {
+				author = &AuthorStats{Name: name, Email: email}
+				current = &RepoContribution{Repo: repo}
+				author.Repos = []*RepoContribution{current}
+				authors[key] = author
+			}
+			current = author.Repos[0]
+			current.Commits++
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 || current == nil {
+			continue
+		}
+		// Binary files have - instead of line counts.
+		added, _ := strconv.Atoi(fields[0])
+		deleted, _ := strconv.Atoi(fields[1])
+		current.Added += added
+		current.Deleted += deleted
+	}
+	return authors
+}
+
+// Contributors aggregates the commits and changed lines per author over the
+// checked out branches of the repos, merges left out. Authors are told apart
+// by email, after applying the .mailmap of each repo. The authors with most
+// commits come first.
+func (client *RepoManager) Contributors(opts ContributorOptions) ([]*AuthorStats, error) {
+	args := []string{"log", "--no-merges", "--use-mailmap", "--numstat", "--format=%x00%aN%x00%aE"}
+	now := time.Now()
+	for _, bound := range []struct{ flag, value string }{{"--since", opts.Since}, {"--until", opts.Until}} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := parseSince(bound.value, now)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, bound.flag+"="+strconv.FormatInt(t.Unix(), 10))
+	}
+
+	authors := make(map[string]*AuthorStats)
+	for _, repoConfig := range client.selectedRepos(opts.Select) {
+		dir := repoConfig.FullDir(client.workspace)
+		if err := client.backend.Open(dir); err != nil {
+			client.logger.Warn("skipping", "repo", repoConfig.Name, "err", err)
+			continue
+		}
+		out, err := runGit(dir, append(args, "HEAD")...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", repoConfig.Name, err)
+		}
+		for key, contributed := range logContributions(repoConfig.Name, out) {
+			author, ok := authors[key]
+			if !ok {
+				author = &AuthorStats{Name: contributed.Name, Email: contributed.Email}
+				authors[key] = author
+			}
+			for _, repo := range contributed.Repos {
+				author.Commits += repo.Commits
+				author.Added += repo.Added
+				author.Deleted += repo.Deleted
+				author.Repos = append(author.Repos, repo)
+			}
+		}
+	}
+
+	stats := make([]*AuthorStats, 0, len(authors))
+	for _, author := range authors {
+		sort.SliceStable(author.Repos, func(i, j int) bool {
+			return author.Repos[i].Commits > author.Repos[j].Commits
+		})
+		stats = append(stats, author)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Commits != stats[j].Commits {
+			return stats[i].Commits > stats[j].Commits
+		}
+		return stats[i].Email < stats[j].Email
+	})
+	return stats, nil
+}
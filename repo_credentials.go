@@ -0,0 +1,104 @@
+package repos
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+)
+
+// credentialService is the service the secrets of repos are stored under in
+// the OS keyring.
+const credentialService = "repos"
+
+// ErrCredentialNotFound is returned when the store has no secret of a name.
+var ErrCredentialNotFound = errors.New("credential not found")
+
+// CredentialStore keeps secrets such as API tokens out of the config file.
+// Secrets are named after the host they are for, e.g. github.com.
+type CredentialStore interface {
+	Get(name string) (string, error)
+	Set(name, secret string) error
+	Delete(name string) error
+}
+
+// keyringStore keeps the secrets in the keychain on macOS, the secret
+// service on Linux and the credential manager on Windows.
+type keyringStore struct{}
+
+func (keyringStore) Get(name string) (string, error) {
+	secret, err := keyring.Get(credentialService, name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrCredentialNotFound
+	}
+	return secret, err
+}
+
+func (keyringStore) Set(name, secret string) error {
+	return keyring.Set(credentialService, name, secret)
+}
+
+func (keyringStore) Delete(name string) error {
+	err := keyring.Delete(credentialService, name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return ErrCredentialNotFound
+	}
+	return err
+}
+
+// credentials is where login stores tokens and where provider tokens and
+// HTTPS credentials are looked up.
+var credentials CredentialStore = keyringStore{}
+
+// Credential is a token for a host, with the user name HTTPS remotes
+// authenticate as.
+type Credential struct {
+	User  string
+	Token string
+}
+
+// stored credentials are the token, prefixed with the user and a colon when
+// there is one.
+func (credential Credential) String() string {
+	if credential.User == "" {
+		return credential.Token
+	}
+	return credential.User + ":" + credential.Token
+}
+
+func parseCredential(secret string) Credential {
+	if user, token, ok := strings.Cut(secret, ":"); ok {
+		return Credential{User: user, Token: token}
+	}
+	return Credential{Token: secret}
+}
+
+// Login stores the credential for host in the OS keyring.
+func Login(host string, credential Credential) error {
+	if host == "" || credential.Token == "" {
+		return fmt.Errorf("a host and a token are required")
+	}
+	if strings.Contains(credential.User, ":") {
+		return fmt.Errorf("invalid user %q", credential.User)
+	}
+	return credentials.Set(host, credential.String())
+}
+
+// Logout removes the credential for host from the OS keyring.
+func Logout(host string) error {
+	if err := credentials.Delete(host); err != nil {
+		return fmt.Errorf("%s: %w", host, err)
+	}
+	return nil
+}
+
+// storedCredential returns the credential for host from the OS keyring. A
+// missing or unavailable keyring is treated as not knowing the host.
+func storedCredential(host string) (Credential, bool) {
+	secret, err := credentials.Get(host)
+	if err != nil || secret == "" {
+		return Credential{}, false
+	}
+	return parseCredential(secret), true
+}
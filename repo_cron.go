@@ -0,0 +1,152 @@
+package repos
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed cron expression: minute, hour, day of month, month
+// and day of week, or a fixed interval given as @every <duration>.
+type cronSchedule struct {
+	every  time.Duration
+	minute []bool
+	hour   []bool
+	dom    []bool
+	month  []bool
+	dow    []bool
+	// domAny and dowAny record a * field. When both day fields are
+	// restricted, a day matching either of them matches, as in cron.
+	domAny bool
+	dowAny bool
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// parseCron parses a five field cron expression such as "*/15 9-17 * * 1-5",
+// one of the descriptors like @daily, or @every followed by a duration.
+func parseCron(expr string) (*cronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "@every ") {
+		every, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(expr, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+		if every < time.Minute {
+			return nil, fmt.Errorf("invalid cron expression %q: the interval must be at least a minute", expr)
+		}
+		return &cronSchedule{every: every}, nil
+	}
+	if descriptor, ok := cronDescriptors[expr]; ok {
+		expr = descriptor
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q, want 5 fields", expr)
+	}
+	schedule := &cronSchedule{
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}
+	var err error
+	ranges := []struct {
+		field    *[]bool
+		min, max int
+	}{
+		{&schedule.minute, 0, 59},
+		{&schedule.hour, 0, 23},
+		{&schedule.dom, 1, 31},
+		{&schedule.month, 1, 12},
+		{&schedule.dow, 0, 7},
+	}
+	for i, r := range ranges {
+		if *r.field, err = parseCronField(fields[i], r.min, r.max); err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+	}
+	// Both 0 and 7 are Sunday.
+	schedule.dow[0] = schedule.dow[0] || schedule.dow[7]
+	return schedule, nil
+}
+
+// parseCronField parses a comma separated list of *, values and ranges, each
+// with an optional /step.
+func parseCronField(field string, min, max int) ([]bool, error) {
+	matches := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			step, part = n, part[:i]
+		}
+		lo, hi := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value %q", part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value %q", part)
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			matches[v] = true
+		}
+	}
+	return matches, nil
+}
+
+func (schedule *cronSchedule) dayMatches(t time.Time) bool {
+	dom, dow := schedule.dom[t.Day()], schedule.dow[int(t.Weekday())]
+	if schedule.domAny || schedule.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// next returns the first time after t the schedule fires, or the zero time
+// if it never does, e.g. for February 30.
+func (schedule *cronSchedule) next(t time.Time) time.Time {
+	if schedule.every > 0 {
+		return t.Add(schedule.every)
+	}
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		year, month, day := t.Date()
+		switch {
+		case !schedule.month[int(month)]:
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, t.Location())
+		case !schedule.dayMatches(t):
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+		case !schedule.hour[t.Hour()]:
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, t.Location())
+		case !schedule.minute[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
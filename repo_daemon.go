@@ -0,0 +1,244 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ScheduleConfig runs a batch command on a cron schedule in daemon mode.
+type ScheduleConfig struct {
+	// Cron is a five field cron expression, a descriptor such as @hourly or
+	// @every followed by a duration.
+	Cron string `yaml:"cron"`
+	// Command is pull, push or sync.
+	Command string `yaml:"command"`
+	// Workspace names the workspace to run in instead of the current one.
+	Workspace string `yaml:"workspace,omitempty"`
+	// Groups limits the run to the repos in these groups.
+	Groups []string `yaml:"groups,omitempty"`
+}
+
+var scheduleCommands = []string{"pull", "push", "sync"}
+
+func (schedule *ScheduleConfig) validate() error {
+	if _, err := parseCron(schedule.Cron); err != nil {
+		return err
+	}
+	if !contains(scheduleCommands, schedule.Command) {
+		return fmt.Errorf("invalid command %q, must be one of pull, push or sync", schedule.Command)
+	}
+	return nil
+}
+
+// forSchedule returns a RepoManager working on the workspace and groups of
+// a schedule.
+func (client *RepoManager) forSchedule(name string, schedule *ScheduleConfig) (*RepoManager, error) {
+	scheduled := *client
+	scheduled.schedule = name
+	if schedule.Workspace != "" {
+		view, err := client.config.root().Workspace(schedule.Workspace)
+		if err != nil {
+			return nil, err
+		}
+		scheduled.config = view
+		scheduled.workspace = view.WorkspaceDir()
+	}
+	scheduled.selection = ListOptions{Groups: schedule.Groups}
+	return &scheduled, nil
+}
+
+func (client *RepoManager) runCommand(command string) error {
+	switch command {
+	case "pull":
+		return client.Pull()
+	case "push":
+		return client.Push(PushOptions{})
+	case "sync":
+		return client.Sync()
+	}
+	return fmt.Errorf("invalid command %q", command)
+}
+
+// DaemonOptions configures the control API of the daemon.
+type DaemonOptions struct {
+	// Listen is the address of the control API, host:port for TCP or
+	// unix:<path> for a unix socket. The API is off when it's empty.
+	Listen string
+	// Token authenticates the API requests, which send it as a bearer token.
+	Token string
+}
+
+// daemon is the state the schedules and the control API share.
+type daemon struct {
+	client *RepoManager
+	// turn makes runs take turns so that they never work on the same repos
+	// at once.
+	turn sync.Mutex
+	// runs tracks the runs in progress, which are waited for on shutdown.
+	runs sync.WaitGroup
+
+	mu          sync.Mutex
+	running     map[string]bool
+	results     map[string]*RunResult
+	subscribers map[chan ProgressEvent]struct{}
+}
+
+// RunResult is how the last run of a schedule went.
+type RunResult struct {
+	Name      string    `json:"name"`
+	Command   string    `json:"command"`
+	Started   time.Time `json:"started"`
+	Finished  time.Time `json:"finished"`
+	Error     string    `json:"error,omitempty"`
+	Succeeded int       `json:"succeeded"`
+	UpToDate  int       `json:"up_to_date"`
+	Skipped   int       `json:"skipped"`
+	Failed    []string  `json:"failed"`
+}
+
+// Daemon runs the configured schedules until ctx is done, then waits for the
+// runs in progress. A schedule whose previous run is still going when it
+// fires again is skipped, and runs of different schedules take turns so that
+// they never work on the same repos at once. With opts.Listen set, the
+// control API triggers runs and reports their results and progress.
+func (client *RepoManager) Daemon(ctx context.Context, opts DaemonOptions) error {
+	schedules := client.config.root().Schedules
+	if len(schedules) == 0 && opts.Listen == "" {
+		return fmt.Errorf("no schedules configured")
+	}
+	names := make([]string, 0, len(schedules))
+	crons := make(map[string]*cronSchedule, len(schedules))
+	for name, schedule := range schedules {
+		if err := schedule.validate(); err != nil {
+			return fmt.Errorf("schedule %s: %w", name, err)
+		}
+		crons[name], _ = parseCron(schedule.Cron)
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	d := &daemon{
+		client:      client,
+		running:     make(map[string]bool),
+		results:     make(map[string]*RunResult),
+		subscribers: make(map[chan ProgressEvent]struct{}),
+	}
+	wg := sync.WaitGroup{}
+	errs := make(chan error, 1)
+	if opts.Listen != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- d.serveAPI(ctx, opts)
+		}()
+	}
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			d.runSchedule(ctx, name, schedules[name], crons[name])
+		}(name)
+	}
+	wg.Wait()
+	d.runs.Wait()
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return nil
+	}
+}
+
+func (d *daemon) runSchedule(ctx context.Context, name string, schedule *ScheduleConfig, cron *cronSchedule) {
+	client := d.client
+	for {
+		next := cron.next(time.Now())
+		if next.IsZero() {
+			client.logger.Warn("schedule never fires", "schedule", name, "cron", schedule.Cron)
+			return
+		}
+		client.logger.Info("scheduled", "schedule", name, "command", schedule.Command, "at", next.Format(time.RFC3339))
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if !d.start(name, schedule, func(err error, took time.Duration) {
+			d.reportScheduled(name, schedule, cron, err, took)
+		}) {
+			client.logger.Warn("skipping, the previous run is still going", "schedule", name)
+		}
+	}
+}
+
+// start runs schedule in the background under name unless a run of that name
+// is still going, and calls done with how it went.
+func (d *daemon) start(name string, schedule *ScheduleConfig, done func(err error, took time.Duration)) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.running[name] {
+		return false
+	}
+	d.running[name] = true
+	d.runs.Add(1)
+	go func() {
+		defer d.runs.Done()
+		defer func() {
+			d.mu.Lock()
+			delete(d.running, name)
+			d.mu.Unlock()
+		}()
+		d.turn.Lock()
+		defer d.turn.Unlock()
+		started := time.Now()
+		err := d.run(name, schedule)
+		done(err, time.Since(started).Round(time.Millisecond))
+	}()
+	return true
+}
+
+// run runs a schedule once and records the result.
+func (d *daemon) run(name string, schedule *ScheduleConfig) error {
+	result := &RunResult{Name: name, Command: schedule.Command, Started: time.Now(), Failed: []string{}}
+	d.publish(ProgressEvent{Type: "started", Time: result.Started, Run: name, Command: schedule.Command})
+	scheduled, err := d.client.forSchedule(name, schedule)
+	if err == nil {
+		scheduled.progress = d.publish
+		err = scheduled.runCommand(schedule.Command)
+		if summary := scheduled.lastSummary; summary != nil {
+			result.Succeeded = summary.count(outcomeSucceeded)
+			result.UpToDate = summary.count(outcomeUpToDate)
+			result.Skipped = summary.count(outcomeSkipped)
+			if failed := summary.failed(); failed != nil {
+				result.Failed = failed
+			}
+		}
+	}
+	result.Finished = time.Now()
+	if err != nil {
+		result.Error = err.Error()
+	}
+	d.mu.Lock()
+	d.results[name] = result
+	d.mu.Unlock()
+	d.publish(ProgressEvent{Type: "finished", Time: result.Finished, Run: name, Command: schedule.Command, Error: result.Error})
+	return err
+}
+
+// reportScheduled prints how a scheduled run went.
+func (d *daemon) reportScheduled(name string, schedule *ScheduleConfig, cron *cronSchedule, err error, took time.Duration) {
+	client := d.client
+	next := cron.next(time.Now()).Format(time.RFC3339)
+	width := len(name) + 2
+	if err != nil {
+		client.logger.Error("schedule failed", "schedule", name, "command", schedule.Command, "took", took, "error", err)
+		client.printRepoLine(width, name, fmt.Errorf("%s failed after %s, next run at %s", schedule.Command, took, next))
+		return
+	}
+	client.printRepoLine(width, name, fmt.Sprintf("%s finished in %s, next run at %s", schedule.Command, took, next))
+}
@@ -0,0 +1,204 @@
+package repos
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// trashDir is where delete moves the directories of repos, in the workspace.
+const trashDir = ".repos-trash"
+
+type DeleteOptions struct {
+	// KeepDir only removes the repo from the config.
+	KeepDir bool
+	// Purge deletes the directory instead of moving it to the trash.
+	Purge bool
+	// Force deletes the repo even when it has work that isn't pushed.
+	Force bool
+}
+
+// unsavedWork returns what would be lost with the repo in dir: uncommitted
+// changes, stashes and commits of local branches that no remote has. Bare
+// repos have no working tree or stashes, and the refs of a mirror are the
+// ones of its remote, so only branches ahead of remote-tracking refs count.
+func unsavedWork(dir string) ([]string, error) {
+	var work []string
+	bare, err := runGit(dir, "rev-parse", "--is-bare-repository")
+	if err != nil {
+		return nil, err
+	}
+	if bare != "true" {
+		status, err := runGit(dir, "status", "--porcelain")
+		if err != nil {
+			return nil, err
+		}
+		if status != "" {
+			work = append(work, "uncommitted changes")
+		}
+		if stashes, err := runGit(dir, "stash", "list"); err == nil && stashes != "" {
+			work = append(work, fmt.Sprintf("%d stash(es)", len(strings.Split(stashes, "\n"))))
+		}
+	} else if remotes, err := runGit(dir, "for-each-ref", "--count=1", "refs/remotes"); err != nil || remotes == "" {
+		return work, err
+	}
+	unpushed, err := runGit(dir, "log", "--oneline", "--branches", "--not", "--remotes")
+	if err != nil {
+		return nil, err
+	}
+	if unpushed != "" {
+		work = append(work, fmt.Sprintf("%d unpushed commit(s)", len(strings.Split(unpushed, "\n"))))
+	}
+	return work, nil
+}
+
+// moveDir renames src to dst, copying and removing it when they are on
+// different filesystems.
+func moveDir(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	if copyErr := copyTree(src, dst); copyErr != nil {
+		os.RemoveAll(dst)
+		return fmt.Errorf("%w, and copying failed: %v", err, copyErr)
+	}
+	return os.RemoveAll(src)
+}
+
+// copyTree copies the directory src to dst, keeping modes and symlinks.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+// Delete removes a repo from the config and its directory from disk, moving
+// it to the .repos-trash directory of the workspace unless opts.Purge is set.
+// Repos with uncommitted changes, stashes or unpushed commits are refused
+// unless opts.Force is set. The directory is moved back when the config
+// can't be saved.
+func (client *RepoManager) Delete(name string, opts DeleteOptions) error {
+	client.logger.Info("deleting", "repo", name, "workspace", client.workspace)
+	repoConfig, err := client.repoConfigOf(name)
+	if err != nil {
+		return err
+	}
+	history := client.newHistory("delete")
+	defer history.record()
+	head := headOf(repoConfig.FullDir(client.workspace))
+	if err := client.deleteRepo(name, repoConfig, opts); err != nil {
+		history.fail(repoConfig, head, err)
+		return err
+	}
+	history.add(repoConfig, head, outcomeSucceeded, "")
+	return nil
+}
+
+func (client *RepoManager) deleteRepo(name string, repoConfig *RepoConfig, opts DeleteOptions) error {
+	dir := repoConfig.FullDir(client.workspace)
+	_, statErr := os.Stat(dir)
+	hasDir := statErr == nil && !opts.KeepDir
+
+	if hasDir && !opts.Force {
+		work, err := unsavedWork(dir)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if len(work) > 0 {
+			return fmt.Errorf("%s has %s, push or discard it first, or use --force", name, strings.Join(work, ", "))
+		}
+	}
+
+	var trashed string
+	if hasDir && !opts.Purge {
+		trashed = filepath.Join(client.workspace, trashDir, name+"-"+time.Now().Format("20060102-150405"))
+		if err := os.MkdirAll(filepath.Dir(trashed), 0755); err != nil {
+			return err
+		}
+		client.logger.Debug("moving to the trash", "repo", name, "dir", dir, "to", trashed)
+		if err := moveDir(dir, trashed); err != nil {
+			return err
+		}
+	}
+
+	delete(client.config.Repos, name)
+	dependents := make(map[*RepoConfig][]string)
+	for _, other := range client.config.Repos {
+		var after []string
+		for _, dep := range other.After {
+			if dep != name {
+				after = append(after, dep)
+			}
+		}
+		if len(after) != len(other.After) {
+			dependents[other] = other.After
+			other.After = after
+		}
+	}
+	if err := client.config.Save(); err != nil {
+		client.config.Repos[name] = repoConfig
+		for other, after := range dependents {
+			other.After = after
+		}
+		if trashed != "" {
+			if undoErr := moveDir(trashed, dir); undoErr != nil {
+				return fmt.Errorf("%w, and moving %s back failed: %v", err, trashed, undoErr)
+			}
+		}
+		return err
+	}
+
+	client.syncGoWork()
+	switch {
+	case trashed != "":
+		client.logger.Info("deleted", "repo", name, "trash", trashed)
+	case hasDir:
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+		client.logger.Info("deleted", "repo", name, "dir", dir)
+	default:
+		client.logger.Info("removed from the config", "repo", name)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package repos
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUnsavedWork(t *testing.T) {
+	for _, key := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+		t.Setenv(key+"_NAME", "test")
+		t.Setenv(key+"_EMAIL", "test@example.com")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	root := t.TempDir()
+	origin := filepath.Join(root, "origin")
+	mustGit(t, root, "init", "--initial-branch=main", origin)
+	commitFile(t, origin, "a", "a")
+
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, dir string)
+		args  []string
+		want  []string
+	}{
+		{"clean clone", nil, nil, nil},
+		{"mirror", nil, []string{"--mirror"}, nil},
+		{"bare clone", nil, []string{"--bare"}, nil},
+		{"changes", func(t *testing.T, dir string) {
+			if err := os.WriteFile(filepath.Join(dir, "b"), []byte("b"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}, nil, []string{"uncommitted changes"}},
+		{"unpushed commit", func(t *testing.T, dir string) {
+			commitFile(t, dir, "b", "b")
+		}, nil, []string{"1 unpushed commit(s)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "repo")
+			mustGit(t, root, append(append([]string{"clone"}, tt.args...), origin, dir)...)
+			if tt.setup != nil {
+				tt.setup(t, dir)
+			}
+			work, err := unsavedWork(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(work, tt.want) {
+				t.Errorf("unsavedWork() = %q, want %q", work, tt.want)
+			}
+		})
+	}
+}
+
+func TestCopyTree(t *testing.T) {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "sub", "run.sh"), []byte("echo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("sub", "run.sh"), filepath.Join(src, "link")); err != nil {
+		t.Skip("no symlinks:", err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "copy")
+	if err := copyTree(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(dst, "sub", "run.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("mode is %v, want 0755", info.Mode().Perm())
+	}
+	if link, err := os.Readlink(filepath.Join(dst, "link")); err != nil || link != filepath.Join("sub", "run.sh") {
+		t.Errorf("link is %q, %v", link, err)
+	}
+}
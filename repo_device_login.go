@@ -0,0 +1,204 @@
+package repos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// githubClientIDEnv names the environment variable holding the client ID of
+// the GitHub OAuth app repos auth login github authorizes.
+const githubClientIDEnv = "REPOS_GITHUB_CLIENT_ID"
+
+// defaultGitHubScopes lets the token clone and push private repos and list
+// the repos of organizations.
+var defaultGitHubScopes = []string{"repo", "read:org"}
+
+// DeviceCode is what the user enters, at VerificationURI, to authorize a
+// device flow login.
+type DeviceCode struct {
+	UserCode        string
+	VerificationURI string
+	ExpiresIn       time.Duration
+}
+
+// GitHubLoginOptions configure the device flow of LoginGitHub.
+type GitHubLoginOptions struct {
+	// Host is github.com or the host of a GitHub Enterprise Server.
+	Host string
+	// ClientID is the OAuth app to authorize, defaulting to the
+	// REPOS_GITHUB_CLIENT_ID environment variable.
+	ClientID string
+	// Scopes of the token, repo and read:org by default.
+	Scopes []string
+	// Prompt shows the code to the user once GitHub has issued it.
+	Prompt func(code DeviceCode)
+}
+
+// deviceResponse holds the fields of the responses of both device flow
+// endpoints, which report errors with a 200 status.
+type deviceResponse struct {
+	DeviceCode       string `json:"device_code"`
+	UserCode         string `json:"user_code"`
+	VerificationURI  string `json:"verification_uri"`
+	ExpiresIn        int    `json:"expires_in"`
+	Interval         int    `json:"interval"`
+	AccessToken      string `json:"access_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (resp *deviceResponse) err() error {
+	if resp.ErrorDescription != "" {
+		return fmt.Errorf("%s: %s", resp.Error, resp.ErrorDescription)
+	}
+	return fmt.Errorf("%s", resp.Error)
+}
+
+func postDeviceForm(ctx context.Context, endpoint string, form url.Values) (*deviceResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("POST %s: %s: %s", endpoint, resp.Status, strings.TrimSpace(string(data)))
+	}
+	out := &deviceResponse{}
+	if err := json.Unmarshal(data, out); err != nil {
+		return nil, fmt.Errorf("POST %s: %w", endpoint, err)
+	}
+	return out, nil
+}
+
+// LoginGitHub runs the OAuth device flow of GitHub: it asks for a code, has
+// opts.Prompt show it, waits for the user to authorize it in the browser and
+// stores the token with the login of its user in the OS keyring. The wait
+// ends early when ctx is done.
+func LoginGitHub(ctx context.Context, opts GitHubLoginOptions) error {
+	if opts.Host == "" {
+		opts.Host = "github.com"
+	}
+	if opts.ClientID == "" {
+		opts.ClientID = os.Getenv(githubClientIDEnv)
+	}
+	if opts.ClientID == "" {
+		return fmt.Errorf("no OAuth app client ID, pass --client-id or set %s", githubClientIDEnv)
+	}
+	if len(opts.Scopes) == 0 {
+		opts.Scopes = defaultGitHubScopes
+	}
+	base := "https://" + opts.Host
+
+	code, err := postDeviceForm(ctx, base+"/login/device/code", url.Values{
+		"client_id": {opts.ClientID},
+		"scope":     {strings.Join(opts.Scopes, " ")},
+	})
+	if err != nil {
+		return err
+	}
+	if code.Error != "" {
+		return code.err()
+	}
+	if opts.Prompt != nil {
+		opts.Prompt(DeviceCode{
+			UserCode:        code.UserCode,
+			VerificationURI: code.VerificationURI,
+			ExpiresIn:       time.Duration(code.ExpiresIn) * time.Second,
+		})
+	}
+
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+	var token string
+	for token == "" {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+		if code.ExpiresIn > 0 && time.Now().After(deadline) {
+			return fmt.Errorf("the code expired before it was authorized, run the login again")
+		}
+		resp, err := postDeviceForm(ctx, base+"/login/oauth/access_token", url.Values{
+			"client_id":   {opts.ClientID},
+			"device_code": {code.DeviceCode},
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		})
+		if err != nil {
+			return err
+		}
+		switch resp.Error {
+		case "":
+			token = resp.AccessToken
+		case "authorization_pending":
+		case "slow_down":
+			if resp.Interval > 0 {
+				interval = time.Duration(resp.Interval) * time.Second
+			} else {
+				interval += 5 * time.Second
+			}
+		case "expired_token":
+			return fmt.Errorf("the code expired before it was authorized, run the login again")
+		case "access_denied":
+			return fmt.Errorf("the login was denied")
+		default:
+			return resp.err()
+		}
+	}
+
+	login, err := githubLogin(ctx, opts.Host, token)
+	if err != nil {
+		return err
+	}
+	return Login(opts.Host, Credential{User: login, Token: token})
+}
+
+// githubLogin returns the login of the user of token, which HTTPS remotes
+// authenticate as.
+func githubLogin(ctx context.Context, host, token string) (string, error) {
+	endpoint := "https://api.github.com/user"
+	if host != "github.com" {
+		endpoint = "https://" + host + "/api/v3/user"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("GET %s: %s", endpoint, resp.Status)
+	}
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return "", fmt.Errorf("GET %s: %w", endpoint, err)
+	}
+	return user.Login, nil
+}
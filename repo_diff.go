@@ -0,0 +1,72 @@
+package repos
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Diff prints, per repo, the diffstat of uncommitted changes and of the commits
+// a push would publish to the upstream branch. Repos that can't be diffed are
+// returned in a BatchError after the others are printed.
+func (client *RepoManager) Diff() error {
+	client.logger.Info("diffing", "workspace", client.workspace)
+	errs := make(map[string]error)
+	for _, repoConfig := range client.sortedRepos() {
+		sections, err := diffSections(repoConfig.FullDir(client.workspace))
+		if err != nil {
+			client.logger.Error("failed to diff", "repo", repoConfig.Name, "error", err)
+			errs[repoConfig.Name] = err
+			continue
+		}
+		if len(sections) == 0 {
+			continue
+		}
+		fmt.Printf("== %s\n%s\n\n", repoConfig.Name, strings.Join(sections, "\n"))
+	}
+	if len(errs) > 0 {
+		return &BatchError{Errors: errs}
+	}
+	return nil
+}
+
+// diffSections describes the uncommitted changes in dir and the commits it
+// has that its upstream doesn't, one section each. Nothing to report is no
+// sections.
+func diffSections(dir string) ([]string, error) {
+	var sections []string
+	uncommitted, err := runGit(dir, "diff", "--stat", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := runGit(dir, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	if uncommitted != "" || untracked != "" {
+		lines := []string{"uncommitted changes:"}
+		if uncommitted != "" {
+			lines = append(lines, uncommitted)
+		}
+		if untracked != "" {
+			lines = append(lines, fmt.Sprintf(" %d untracked file(s)", len(strings.Split(untracked, "\n"))))
+		}
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+
+	upstream := upstreamOf(dir)
+	if upstream == "" {
+		return append(sections, "no upstream branch configured"), nil
+	}
+	count, err := runGit(dir, "rev-list", "--count", upstream+"..HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if count != "0" {
+		ahead, err := runGit(dir, "diff", "--stat", upstream+"...HEAD")
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, fmt.Sprintf("%s commit(s) ahead of %s:\n%s", count, upstream, ahead))
+	}
+	return sections, nil
+}
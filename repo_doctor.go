@@ -0,0 +1,185 @@
+package repos
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// staleLockAge is how old a lock file must be before doctor considers the git
+// process that created it gone.
+const staleLockAge = 10 * time.Minute
+
+type DoctorOptions struct {
+	// Fix applies the fixes instead of only describing them.
+	Fix bool
+}
+
+// doctorIssue is a problem found in a repo and, if it can be fixed, how.
+type doctorIssue struct {
+	problem string
+	fix     string
+	apply   func() error
+}
+
+// staleLocks returns the lock files in gitDir older than staleLockAge.
+func staleLocks(gitDir string) []string {
+	var locks []string
+	filepath.WalkDir(gitDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && d.Name() == "objects" {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".lock") {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			locks = append(locks, path)
+		}
+		return nil
+	})
+	return locks
+}
+
+func (client *RepoManager) diagnose(repoConfig *RepoConfig) []*doctorIssue {
+	dir := repoConfig.FullDir(client.workspace)
+	if _, err := os.Stat(dir); err != nil {
+		issue := &doctorIssue{problem: "directory " + dir + " doesn't exist"}
+		if repoConfig.Url != "" {
+			issue.fix = "clone " + repoConfig.RemoteURL()
+			issue.apply = func() error {
+				if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+					return err
+				}
+				return client.backend.Clone(client.cloneSpec(repoConfig, CloneOptions{}))
+			}
+		}
+		return []*doctorIssue{issue}
+	}
+	if !isGitRepo(dir) {
+		return []*doctorIssue{{problem: dir + " is not a git repository"}}
+	}
+
+	var issues []*doctorIssue
+	cli := client.gitCLI()
+
+	origin := originOf(dir)
+	switch {
+	case repoConfig.Url == "":
+	case origin == "":
+		issues = append(issues, &doctorIssue{
+			problem: "no origin remote",
+			fix:     "add origin " + repoConfig.RemoteURL(),
+			apply: func() error {
+				_, err := runGit(dir, "remote", "add", "origin", repoConfig.RemoteURL())
+				return err
+			},
+		})
+	case origin != repoConfig.RemoteURL():
+		issues = append(issues, &doctorIssue{
+			problem: fmt.Sprintf("origin is %s instead of %s", origin, repoConfig.RemoteURL()),
+			fix:     "set origin to " + repoConfig.RemoteURL(),
+			apply: func() error {
+				_, err := runGit(dir, "remote", "set-url", "origin", repoConfig.RemoteURL())
+				return err
+			},
+		})
+	}
+
+	remote := repoConfig.RemoteURL()
+	if remote == "" {
+		remote = origin
+	}
+	remoteHeads, err := cli.remoteGit(dir, remote, "ls-remote", "--heads", remote)
+	if err != nil {
+		issues = append(issues, &doctorIssue{
+			problem: "can't reach " + remote + ": " + strings.SplitN(err.Error(), "\n", 2)[0],
+			fix:     "check the url and credentials",
+		})
+	}
+
+	for _, branch := range repoConfig.Branch {
+		branch := branch
+		_, localErr := runGit(dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+		onRemote := err != nil || strings.Contains(remoteHeads+"\n", "refs/heads/"+branch+"\n")
+		if !onRemote {
+			issues = append(issues, &doctorIssue{problem: "branch " + branch + " doesn't exist on origin"})
+		}
+		if localErr != nil {
+			issue := &doctorIssue{problem: "branch " + branch + " doesn't exist locally"}
+			if onRemote {
+				issue.fix = "create it from origin/" + branch
+				issue.apply = func() error {
+					if _, err := cli.originGit(dir, "fetch", "origin", branch); err != nil {
+						return err
+					}
+					_, err := runGit(dir, "branch", "--track", branch, "origin/"+branch)
+					return err
+				}
+			}
+			issues = append(issues, issue)
+		}
+	}
+
+	if gitDir, err := gitDirOf(dir); err == nil {
+		for _, lock := range staleLocks(gitDir) {
+			lock := lock
+			issues = append(issues, &doctorIssue{
+				problem: "stale lock file " + lock,
+				fix:     "remove it",
+				apply: func() error {
+					return os.Remove(lock)
+				},
+			})
+		}
+	}
+	return issues
+}
+
+// Doctor checks every repo: the directory exists and is a git repository,
+// origin matches the config, the configured branch exists locally and on
+// origin, origin is reachable and no stale lock files are left. Problems are
+// printed with their fix, which is applied with Fix.
+func (client *RepoManager) Doctor(opts DoctorOptions) error {
+	client.logger.Info("checking", "workspace", client.workspace)
+	max := client.nameWidth()
+	var failed []string
+	for _, repoConfig := range client.sortedRepos() {
+		client.logger.Debug("checking", "repo", repoConfig.Name)
+		issues := client.diagnose(repoConfig)
+		if len(issues) == 0 {
+			client.printRepoLine(max, repoConfig.Name, client.paint(colorGreen, "ok"))
+			continue
+		}
+		unresolved := false
+		for _, issue := range issues {
+			switch {
+			case issue.apply != nil && opts.Fix:
+				if err := issue.apply(); err != nil {
+					client.printRepoLine(max, repoConfig.Name, fmt.Errorf("%s, fixing failed: %w", issue.problem, err))
+					unresolved = true
+					continue
+				}
+				client.printRepoLine(max, repoConfig.Name, issue.problem+", fixed: "+issue.fix)
+			case issue.fix != "":
+				client.printRepoLine(max, repoConfig.Name, client.paint(colorYellow, issue.problem)+", fix: "+issue.fix)
+				unresolved = true
+			default:
+				client.printRepoLine(max, repoConfig.Name, client.paint(colorRed, issue.problem))
+				unresolved = true
+			}
+		}
+		if unresolved {
+			failed = append(failed, repoConfig.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return failedIn("doctor", failed)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package repos
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// ErrorKind is the category a failure of git is classified in, for hints on
+// how to fix it.
+type ErrorKind string
+
+const (
+	KindAuth           ErrorKind = "auth"
+	KindNetwork        ErrorKind = "network"
+	KindNonFastForward ErrorKind = "non-fast-forward"
+	KindDirtyWorktree  ErrorKind = "dirty worktree"
+	KindMergeConflict  ErrorKind = "merge conflict"
+)
+
+// errorHints tell what to do about the errors of each kind.
+var errorHints = map[ErrorKind]string{
+	KindAuth:           "check the credentials for the host, e.g. with `repos auth login <host>`, then run `repos doctor`",
+	KindNetwork:        "check the connection and the proxies config, then retry",
+	KindNonFastForward: "rebase required: pull or sync the repo, then push again",
+	KindDirtyWorktree:  "commit or stash the local changes first, e.g. with `repos stash`",
+	KindMergeConflict:  "resolve the conflicts in the repo and commit, or abort the merge or rebase",
+}
+
+// errorPatterns recognize the kinds in the messages of the git command line,
+// lower cased.
+var errorPatterns = []struct {
+	kind     ErrorKind
+	patterns []string
+}{
+	{KindMergeConflict, []string{"conflict", "could not apply", "unmerged files", "fix them up in the work tree"}},
+	{KindDirtyWorktree, []string{"your local changes", "would be overwritten", "uncommitted changes", "unstaged changes", "worktree is not clean", "please commit or stash"}},
+	{KindNonFastForward, []string{"non-fast-forward", "[rejected]", "fetch first", "not possible to fast-forward", "diverged", "some refs were not updated"}},
+	{KindAuth, []string{"authentication failed", "authentication required", "authorization failed", "permission denied", "could not read username", "could not read password", "terminal prompts disabled", "invalid credentials", "returned error: 401", "returned error: 403"}},
+	{KindNetwork, []string{"could not resolve host", "connection refused", "timed out", "network is unreachable", "no route to host", "connection reset", "the remote end hung up", "early eof", "ssl_error", "tls handshake"}},
+}
+
+// ClassifiedError is an error of git put in a category with a hint on how
+// to fix it.
+type ClassifiedError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *ClassifiedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ClassifiedError) Unwrap() error {
+	return e.Err
+}
+
+// Hint tells what to do about the error.
+func (e *ClassifiedError) Hint() string {
+	return errorHints[e.Kind]
+}
+
+// classifyError wraps err in a *ClassifiedError when it is recognized as one
+// of the kinds, and returns it as is otherwise.
+func classifyError(err error) error {
+	var classified *ClassifiedError
+	if err == nil || errors.As(err, &classified) {
+		return err
+	}
+	kind := errorKindOf(err)
+	if kind == "" {
+		return err
+	}
+	return &ClassifiedError{Kind: kind, Err: err}
+}
+
+func errorKindOf(err error) ErrorKind {
+	var netErr net.Error
+	var conflict *ConflictError
+	switch {
+	case errors.As(err, &conflict) && len(conflict.Files) > 0:
+		return KindMergeConflict
+	case errors.As(err, &conflict):
+		return KindNonFastForward
+	case errors.Is(err, transport.ErrAuthenticationRequired), errors.Is(err, transport.ErrAuthorizationFailed),
+		errors.Is(err, transport.ErrInvalidAuthMethod):
+		return KindAuth
+	case errors.Is(err, git.ErrNonFastForwardUpdate), errors.Is(err, git.ErrForceNeeded):
+		return KindNonFastForward
+	case errors.Is(err, git.ErrWorktreeNotClean), errors.Is(err, git.ErrUnstagedChanges):
+		return KindDirtyWorktree
+	case errors.As(err, &netErr):
+		return KindNetwork
+	}
+	msg := strings.ToLower(err.Error())
+	for _, p := range errorPatterns {
+		for _, pattern := range p.patterns {
+			if strings.Contains(msg, pattern) {
+				return p.kind
+			}
+		}
+	}
+	return ""
+}
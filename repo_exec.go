@@ -0,0 +1,134 @@
+package repos
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+type ExecOptions struct {
+	// Select limits the repos to run the command in.
+	Select ListOptions
+	// OutputDir gets the output of every repo in <repo>.log and the exit
+	// codes in index.json, instead of the console.
+	OutputDir string
+}
+
+// execIndexFile lists the exit codes of the repos in the OutputDir of exec.
+const execIndexFile = "index.json"
+
+// ExecResult is the entry of a repo in the index.json of exec.
+type ExecResult struct {
+	Repo string `json:"repo"`
+	Dir  string `json:"dir"`
+	// Log is the file with the output, relative to the output dir.
+	Log string `json:"log"`
+	// ExitCode is -1 when the command couldn't be started.
+	ExitCode int     `json:"exit_code"`
+	Error    string  `json:"error,omitempty"`
+	Seconds  float64 `json:"seconds"`
+}
+
+// execIn runs command in the dir of a repo with its output going to out.
+// REPOS_REPO and REPOS_DIR tell the command the repo it runs for.
+func execIn(name, dir string, command []string, out io.Writer) (int, error) {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = out, out
+	cmd.Env = append(os.Environ(), "REPOS_REPO="+name, "REPOS_DIR="+dir)
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), err
+	}
+	if err != nil {
+		return -1, err
+	}
+	return 0, nil
+}
+
+// Exec runs command in the dir of every selected repo in parallel. The
+// output of every repo is printed in one piece once its command exits, or
+// with opts.OutputDir written to <repo>.log there, next to an index.json
+// with the exit codes. Repos whose command exits with an error fail.
+func (client *RepoManager) Exec(command []string, opts ExecOptions) error {
+	if len(command) == 0 {
+		return fmt.Errorf("no command to run")
+	}
+	if opts.OutputDir != "" {
+		if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+			return err
+		}
+	}
+
+	client.logger.Info("running", "command", command, "workspace", client.workspace)
+	var mu sync.Mutex
+	results := []*ExecResult{}
+	err := client.runBatch("exec", client.selectedRepos(opts.Select), func(repoConfig *RepoConfig) (outcome, string, error) {
+		dir := repoConfig.FullDir(client.workspace)
+		if err := client.backend.Open(dir); err != nil {
+			return outcomeSkipped, "", err
+		}
+		result := &ExecResult{Repo: repoConfig.Name, Dir: dir}
+		started := time.Now()
+		var exitCode int
+		var err error
+		if opts.OutputDir != "" {
+			result.Log = repoConfig.Name + ".log"
+			exitCode, err = execToFile(repoConfig.Name, dir, command, filepath.Join(opts.OutputDir, result.Log))
+		} else {
+			var out bytes.Buffer
+			exitCode, err = execIn(repoConfig.Name, dir, command, &out)
+			mu.Lock()
+			if client.verbosity > VerbosityQuiet || err != nil {
+				fmt.Printf("== %s\n%s", repoConfig.Name, out.String())
+			}
+			mu.Unlock()
+		}
+		result.ExitCode, result.Seconds = exitCode, time.Since(started).Seconds()
+		if err != nil {
+			result.Error = err.Error()
+		}
+		mu.Lock()
+		results = append(results, result)
+		mu.Unlock()
+		if err != nil {
+			return outcomeFailed, "", err
+		}
+		return outcomeSucceeded, "", nil
+	})
+	if opts.OutputDir == "" {
+		return err
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Repo < results[j].Repo
+	})
+	data, indexErr := json.MarshalIndent(results, "", "  ")
+	if indexErr == nil {
+		indexErr = os.WriteFile(filepath.Join(opts.OutputDir, execIndexFile), data, 0644)
+	}
+	if err != nil {
+		return err
+	}
+	return indexErr
+}
+
+func execToFile(name, dir string, command []string, path string) (int, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return -1, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return -1, err
+	}
+	defer file.Close()
+	return execIn(name, dir, command, file)
+}
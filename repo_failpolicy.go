@@ -0,0 +1,51 @@
+package repos
+
+import "fmt"
+
+// ErrorPolicy is what batch operations do once a repo failed.
+type ErrorPolicy string
+
+const (
+	// ErrorContinue works on the remaining repos and reports every failure
+	// at the end.
+	ErrorContinue ErrorPolicy = "continue"
+	// ErrorFailFast doesn't start any more repos after the first failure,
+	// letting the ones in progress finish.
+	ErrorFailFast ErrorPolicy = "fail-fast"
+)
+
+// reasonCancelled is the skip reason of repos that weren't started because
+// another repo failed under the fail-fast policy.
+const reasonCancelled = "cancelled after a failure"
+
+func ParseErrorPolicy(s string) (ErrorPolicy, error) {
+	switch policy := ErrorPolicy(s); policy {
+	case "", ErrorContinue, ErrorFailFast:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid error policy %q, must be %s or %s", s, ErrorContinue, ErrorFailFast)
+}
+
+// WithErrorPolicy sets what batch operations do after a failure, overriding
+// the on_error config setting.
+func WithErrorPolicy(policy ErrorPolicy) NewRepoManagerClientOptions {
+	return func(client *RepoManager) {
+		client.errorPolicy = policy
+	}
+}
+
+func (client *RepoManager) errorPolicyFor() ErrorPolicy {
+	if client.errorPolicy != "" {
+		return client.errorPolicy
+	}
+	if client.config.OnError != "" {
+		return client.config.OnError
+	}
+	return ErrorContinue
+}
+
+// failingFast reports whether an operation going through the repos one by
+// one should stop, as one of them failed under the fail-fast policy.
+func (client *RepoManager) failingFast(failed []string) bool {
+	return len(failed) > 0 && client.errorPolicyFor() == ErrorFailFast
+}
@@ -0,0 +1,119 @@
+package repos
+
+import (
+	"fmt"
+)
+
+type SyncForkOptions struct {
+	// Rebase rebases the branch onto upstream instead of fast-forwarding it.
+	Rebase bool
+	// Confirm approves the forced push a rebase may need, per repo.
+	Confirm func(repo string) bool
+}
+
+// setUpstreamRemote points the upstream remote of the repo in dir at url,
+// adding it when missing.
+func setUpstreamRemote(dir, url string) error {
+	current, err := runGit(dir, "remote", "get-url", "upstream")
+	if err != nil {
+		_, err = runGit(dir, "remote", "add", "upstream", url)
+		return err
+	}
+	if current == url {
+		return nil
+	}
+	_, err = runGit(dir, "remote", "set-url", "upstream", url)
+	return err
+}
+
+// SyncFork brings the default branch of every repo with an upstream up to
+// date with upstream and pushes it to origin, for workspaces of forks.
+func (client *RepoManager) SyncFork(opts SyncForkOptions) error {
+	client.logger.Info("syncing forks", "workspace", client.workspace)
+	var forks []*RepoConfig
+	for _, repoConfig := range client.sortedRepos() {
+		if repoConfig.Upstream != "" && !client.mirrorFor(repoConfig) {
+			forks = append(forks, repoConfig)
+		}
+	}
+	if len(forks) == 0 {
+		client.logger.Warn("no repo has an upstream configured")
+		return nil
+	}
+	return client.runBatch("sync-fork", forks, func(repoConfig *RepoConfig) (outcome, string, error) {
+		reason, err := client.prepareRepo(repoConfig, true)
+		if reason != "" || err != nil {
+			return outcomeSkipped, reason, err
+		}
+		return client.syncForkSingleRepo(repoConfig, opts)
+	})
+}
+
+func (client *RepoManager) syncForkSingleRepo(repoConfig *RepoConfig, opts SyncForkOptions) (outcome, string, error) {
+	dir := repoConfig.FullDir(client.workspace)
+	cli := client.gitCLI()
+	branch := repoConfig.Branch.Main()
+	if branch == "" {
+		branch = defaultBranchOf(dir)
+	}
+	if branch == "" {
+		return outcomeFailed, "", fmt.Errorf("can't determine the default branch")
+	}
+	if err := setUpstreamRemote(dir, repoConfig.Upstream); err != nil {
+		return outcomeFailed, "", err
+	}
+	client.logger.Debug("fetching upstream", "repo", repoConfig.Name, "url", repoConfig.Upstream)
+	if _, err := cli.remoteGit(dir, repoConfig.Upstream, "fetch", "upstream"); err != nil {
+		return outcomeFailed, "", err
+	}
+
+	ref := "refs/heads/" + branch
+	before, _ := runGit(dir, "rev-parse", "--verify", "--quiet", ref)
+	current, _ := runGit(dir, "symbolic-ref", "--short", "--quiet", "HEAD")
+	switch {
+	case current != branch && opts.Rebase:
+		return outcomeSkipped, "rebasing needs " + branch + " checked out", nil
+	case current != branch:
+		// Updates the branch without checking it out, refusing anything but
+		// a fast-forward.
+		if _, err := runGit(dir, "fetch", ".", "refs/remotes/upstream/"+branch+":"+ref); err != nil {
+			return outcomeFailed, "", err
+		}
+	case opts.Rebase:
+		if _, err := runGit(dir, "rebase", "upstream/"+branch); err != nil {
+			runGit(dir, "rebase", "--abort")
+			return outcomeFailed, "", err
+		}
+	default:
+		if _, err := runGit(dir, "merge", "--ff-only", "upstream/"+branch); err != nil {
+			return outcomeFailed, "", err
+		}
+	}
+	after, _ := runGit(dir, "rev-parse", ref)
+	if repoConfig.IsReadOnly() {
+		if before == after {
+			return outcomeUpToDate, reasonNotPushed, nil
+		}
+		return outcomeSucceeded, reasonNotPushed, nil
+	}
+
+	args := []string{"push", "--porcelain", "origin", ref + ":" + ref}
+	// A rebase may have rewritten commits origin already has.
+	originRef := "refs/remotes/origin/" + branch
+	if _, err := runGit(dir, "rev-parse", "--verify", "--quiet", originRef); err == nil && opts.Rebase {
+		if _, err := runGit(dir, "merge-base", "--is-ancestor", originRef, ref); err != nil {
+			if opts.Confirm == nil || !opts.Confirm(repoConfig.Name) {
+				return outcomeSkipped, "force push not confirmed", nil
+			}
+			args = []string{"push", "--porcelain", "--force-with-lease", "origin", ref + ":" + ref}
+		}
+	}
+	pushedNothing, err := cli.push(dir, args...)
+	if err != nil {
+		return outcomeFailed, "", err
+	}
+	if before == after && pushedNothing {
+		return outcomeUpToDate, "", nil
+	}
+	return outcomeSucceeded, "", nil
+}
@@ -0,0 +1,55 @@
+package repos
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// FormatTemplate parses the Go template of a --format flag, which is
+// executed for every item printed and ended with a newline. Next to the
+// built-in functions, join joins a list with a separator and json encodes a
+// value as JSON.
+func FormatTemplate(format string) (*template.Template, error) {
+	return template.New("format").Funcs(template.FuncMap{
+		"join": strings.Join,
+		"json": func(value interface{}) (string, error) {
+			data, err := json.Marshal(value)
+			return string(data), err
+		},
+	}).Parse(format + "\n")
+}
+
+// Delimited formats of --output, for spreadsheets and simple pipelines.
+const (
+	OutputCSV = "csv"
+	OutputTSV = "tsv"
+)
+
+// ParseOutput checks the --output of a command, empty for its usual table.
+func ParseOutput(value string) (string, error) {
+	switch value {
+	case "", OutputCSV, OutputTSV:
+		return value, nil
+	}
+	return "", fmt.Errorf("unknown output %q, must be csv or tsv", value)
+}
+
+// WriteDelimited writes header and rows as CSV, or with OutputTSV separated
+// by tabs.
+func WriteDelimited(out io.Writer, output string, header []string, rows [][]string) error {
+	w := csv.NewWriter(out)
+	if output == OutputTSV {
+		w.Comma = '\t'
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	return w.Error()
+}
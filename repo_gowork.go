@@ -0,0 +1,195 @@
+package repos
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// goWorkFile is the Go workspace file gowork keeps at the workspace root.
+const goWorkFile = "go.work"
+
+// goModules returns the directories with a go.mod file in the repo in dir.
+func goModules(dir string) ([]string, error) {
+	return dirsWith(dir, "go.mod")
+}
+
+// dirsWith returns the directories with a file named file in the repo in
+// dir, skipping vendored code, test data, hidden directories and nested
+// repos.
+func dirsWith(dir, file string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			if entry.Name() == file {
+				dirs = append(dirs, filepath.Dir(path))
+			}
+			return nil
+		}
+		name := entry.Name()
+		if path != dir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+			name == "vendor" || name == "testdata" || name == "node_modules" || isGitRepo(path)) {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+// runGo runs the go command in dir like runGit runs git.
+func runGo(dir string, args ...string) (string, error) {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("go %s: %s", strings.Join(args, " "), msg)
+	}
+	return stdout.String(), nil
+}
+
+// goWorkUses returns the use directives of the go.work file in dir.
+func goWorkUses(dir string) ([]string, error) {
+	out, err := runGo(dir, "work", "edit", "-json")
+	if err != nil {
+		return nil, err
+	}
+	var work struct {
+		Use []struct {
+			DiskPath string
+		}
+	}
+	if err := json.Unmarshal([]byte(out), &work); err != nil {
+		return nil, err
+	}
+	uses := make([]string, len(work.Use))
+	for i, use := range work.Use {
+		uses[i] = use.DiskPath
+	}
+	return uses, nil
+}
+
+// useDir is how go.work refers to a module directory: relative to the
+// workspace, starting with ./, unless it is outside of it.
+func (client *RepoManager) useDir(dir string) string {
+	rel := client.configDir(dir)
+	if filepath.IsAbs(rel) {
+		return filepath.ToSlash(rel)
+	}
+	if rel == "." {
+		return "."
+	}
+	return "./" + filepath.ToSlash(rel)
+}
+
+// GoWork creates or updates the go.work file at the workspace root so it
+// uses the Go modules of every repo. Use directives of directories in the
+// repos without a go.mod anymore, or of directories that are gone, are
+// dropped, others are left alone. It returns the directives added and
+// dropped.
+func (client *RepoManager) GoWork() ([]string, []string, error) {
+	workspace := client.workspace
+	if _, err := os.Stat(filepath.Join(workspace, goWorkFile)); os.IsNotExist(err) {
+		client.logger.Info("creating", "file", filepath.Join(workspace, goWorkFile))
+		if _, err := runGo(workspace, "work", "init"); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	wanted := make(map[string]bool)
+	var repoDirs []string
+	for _, repoConfig := range client.sortedRepos() {
+		dir := repoConfig.FullDir(workspace)
+		if _, err := os.Stat(dir); err != nil || client.mirrorFor(repoConfig) {
+			continue
+		}
+		repoDirs = append(repoDirs, client.useDir(dir))
+		modules, err := goModules(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, module := range modules {
+			wanted[client.useDir(module)] = true
+		}
+	}
+
+	uses, err := goWorkUses(workspace)
+	if err != nil {
+		return nil, nil, err
+	}
+	inRepo := func(use string) bool {
+		for _, repoDir := range repoDirs {
+			if use == repoDir || strings.HasPrefix(use, repoDir+"/") {
+				return true
+			}
+		}
+		return false
+	}
+	present := make(map[string]bool)
+	var added, dropped []string
+	for _, use := range uses {
+		present[use] = true
+		path := use
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workspace, path)
+		}
+		_, statErr := os.Stat(path)
+		if !wanted[use] && (os.IsNotExist(statErr) || inRepo(use)) {
+			dropped = append(dropped, use)
+		}
+	}
+	for use := range wanted {
+		if !present[use] {
+			added = append(added, use)
+		}
+	}
+	sort.Strings(added)
+	if len(added) == 0 && len(dropped) == 0 {
+		return nil, nil, nil
+	}
+
+	args := []string{"work", "edit"}
+	for _, use := range added {
+		args = append(args, "-use="+use)
+	}
+	for _, use := range dropped {
+		args = append(args, "-dropuse="+use)
+	}
+	if _, err := runGo(workspace, args...); err != nil {
+		return nil, nil, err
+	}
+	return added, dropped, nil
+}
+
+// syncGoWork updates go.work after repos were added or removed when the
+// go_work config setting asks for it. Failing to is only logged.
+func (client *RepoManager) syncGoWork() {
+	if !client.config.GoWork {
+		return
+	}
+	added, dropped, err := client.GoWork()
+	if err != nil {
+		client.logger.Warn("updating go.work failed", "error", err)
+		return
+	}
+	for _, use := range added {
+		client.logger.Info("go.work uses", "dir", use)
+	}
+	for _, use := range dropped {
+		client.logger.Info("go.work dropped", "dir", use)
+	}
+}
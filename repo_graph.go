@@ -0,0 +1,241 @@
+package repos
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type GraphOptions struct {
+	Select ListOptions
+	// PackageJSON also looks at the package.json files of the repos.
+	PackageJSON bool
+}
+
+// Dependency is a repo depending on a module or package of another repo.
+type Dependency struct {
+	Repo      string `json:"repo"`
+	DependsOn string `json:"depends_on"`
+	// Via is the Go module or npm package that is depended on.
+	Via string `json:"via"`
+}
+
+// DependencyGraph is how the repos of the workspace depend on each other.
+type DependencyGraph struct {
+	Repos        []string     `json:"repos"`
+	Dependencies []Dependency `json:"dependencies"`
+}
+
+// repoPackages are the modules and packages a repo provides and requires.
+type repoPackages struct {
+	provides []string
+	requires []string
+}
+
+// goModPackages reads the module path and the direct requirements of the
+// go.mod in dir. Indirect ones are left out, the graph has them through the
+// repos in between.
+func goModPackages(dir string) (provides string, requires []string, err error) {
+	out, err := runGo(dir, "mod", "edit", "-json")
+	if err != nil {
+		return "", nil, err
+	}
+	var mod struct {
+		Module struct {
+			Path string
+		}
+		Require []struct {
+			Path     string
+			Indirect bool
+		}
+	}
+	if err := json.Unmarshal([]byte(out), &mod); err != nil {
+		return "", nil, err
+	}
+	for _, require := range mod.Require {
+		if !require.Indirect {
+			requires = append(requires, require.Path)
+		}
+	}
+	return mod.Module.Path, requires, nil
+}
+
+// packageJSONPackages reads the name and the dependencies of any kind of the
+// package.json in dir.
+func packageJSONPackages(dir string) (provides string, requires []string, err error) {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return "", nil, err
+	}
+	var pkg struct {
+		Name                 string            `json:"name"`
+		Dependencies         map[string]string `json:"dependencies"`
+		DevDependencies      map[string]string `json:"devDependencies"`
+		PeerDependencies     map[string]string `json:"peerDependencies"`
+		OptionalDependencies map[string]string `json:"optionalDependencies"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return "", nil, fmt.Errorf("%s: %w", filepath.Join(dir, "package.json"), err)
+	}
+	for _, deps := range []map[string]string{pkg.Dependencies, pkg.DevDependencies, pkg.PeerDependencies, pkg.OptionalDependencies} {
+		for name := range deps {
+			requires = append(requires, name)
+		}
+	}
+	return pkg.Name, requires, nil
+}
+
+// packagesOf collects what the repo in dir provides and requires from its
+// go.mod files and, with withPackageJSON, its package.json files.
+func packagesOf(dir string, withPackageJSON bool) (*repoPackages, error) {
+	packages := &repoPackages{}
+	type manifest struct {
+		file  string
+		parse func(dir string) (string, []string, error)
+	}
+	manifests := []manifest{{"go.mod", goModPackages}}
+	if withPackageJSON {
+		manifests = append(manifests, manifest{"package.json", packageJSONPackages})
+	}
+	for _, manifest := range manifests {
+		dirs, err := dirsWith(dir, manifest.file)
+		if err != nil {
+			return nil, err
+		}
+		for _, dir := range dirs {
+			provides, requires, err := manifest.parse(dir)
+			if err != nil {
+				return nil, err
+			}
+			if provides != "" {
+				packages.provides = append(packages.provides, provides)
+			}
+			packages.requires = append(packages.requires, requires...)
+		}
+	}
+	return packages, nil
+}
+
+// Graph scans the go.mod files, and with opts.PackageJSON the package.json
+// files, of the selected repos and returns which of them depend on modules
+// or packages of the others.
+func (client *RepoManager) Graph(opts GraphOptions) (*DependencyGraph, error) {
+	client.logger.Info("building the dependency graph", "workspace", client.workspace)
+	graph := &DependencyGraph{Repos: []string{}, Dependencies: []Dependency{}}
+	providers := make(map[string]string)
+	packages := make(map[string]*repoPackages)
+	for _, repoConfig := range client.selectedRepos(opts.Select) {
+		dir := repoConfig.FullDir(client.workspace)
+		if _, err := os.Stat(dir); err != nil || client.mirrorFor(repoConfig) {
+			client.logger.Debug("skipping", "repo", repoConfig.Name)
+			continue
+		}
+		repoPackages, err := packagesOf(dir, opts.PackageJSON)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", repoConfig.Name, err)
+		}
+		graph.Repos = append(graph.Repos, repoConfig.Name)
+		packages[repoConfig.Name] = repoPackages
+		for _, provides := range repoPackages.provides {
+			providers[provides] = repoConfig.Name
+		}
+	}
+
+	seen := make(map[Dependency]bool)
+	for _, name := range graph.Repos {
+		for _, require := range packages[name].requires {
+			provider, ok := providers[require]
+			if !ok || provider == name {
+				continue
+			}
+			dependency := Dependency{Repo: name, DependsOn: provider, Via: require}
+			if !seen[dependency] {
+				seen[dependency] = true
+				graph.Dependencies = append(graph.Dependencies, dependency)
+			}
+		}
+	}
+	sort.Slice(graph.Dependencies, func(i, j int) bool {
+		a, b := graph.Dependencies[i], graph.Dependencies[j]
+		if a.Repo != b.Repo {
+			return a.Repo < b.Repo
+		}
+		if a.DependsOn != b.DependsOn {
+			return a.DependsOn < b.DependsOn
+		}
+		return a.Via < b.Via
+	})
+	return graph, nil
+}
+
+// DOT renders the graph in the Graphviz DOT language, with an edge from every
+// repo to each repo it depends on, labelled with what it depends on.
+func (graph *DependencyGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph repos {\n")
+	for _, repo := range graph.Repos {
+		fmt.Fprintf(&b, "  %q;\n", repo)
+	}
+	type edge struct{ from, to string }
+	var edges []edge
+	vias := make(map[edge][]string)
+	for _, dependency := range graph.Dependencies {
+		e := edge{dependency.Repo, dependency.DependsOn}
+		if _, ok := vias[e]; !ok {
+			edges = append(edges, e)
+		}
+		vias[e] = append(vias[e], dependency.Via)
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", e.from, e.to, strings.Join(vias[e], "\n"))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// SaveDependencies adds the dependencies of the graph to the after fields of
+// the repos, so commands run on the repos they depend on first, and saves
+// the config. Nothing is changed when that would make a cycle. It returns
+// the dependencies that were added.
+func (client *RepoManager) SaveDependencies(graph *DependencyGraph) ([]Dependency, error) {
+	previous := make(map[*RepoConfig][]string)
+	var added []Dependency
+	for _, dependency := range graph.Dependencies {
+		repoConfig, err := client.repoConfigOf(dependency.Repo)
+		if err != nil {
+			return nil, err
+		}
+		known := false
+		for _, dep := range repoConfig.After {
+			known = known || dep == dependency.DependsOn
+		}
+		if known {
+			continue
+		}
+		if _, ok := previous[repoConfig]; !ok {
+			previous[repoConfig] = repoConfig.After
+		}
+		repoConfig.After = append(append([]string{}, repoConfig.After...), dependency.DependsOn)
+		added = append(added, dependency)
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	undo := func() {
+		for repoConfig, after := range previous {
+			repoConfig.After = after
+		}
+	}
+	if err := client.checkDependencies(client.config.Repos); err != nil {
+		undo()
+		return nil, err
+	}
+	if err := client.config.Save(); err != nil {
+		undo()
+		return nil, err
+	}
+	return added, nil
+}
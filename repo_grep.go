@@ -0,0 +1,110 @@
+package repos
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+type GrepOptions struct {
+	Regex      bool
+	IgnoreCase bool
+	Globs      []string
+}
+
+func (opts GrepOptions) args(pattern string) []string {
+	args := []string{"grep", "--line-number", "--full-name", "-I"}
+	if opts.Regex {
+		args = append(args, "--extended-regexp")
+	} else {
+		args = append(args, "--fixed-strings")
+	}
+	if opts.IgnoreCase {
+		args = append(args, "--ignore-case")
+	}
+	args = append(args, "-e", pattern, "--")
+	for _, glob := range opts.Globs {
+		args = append(args, ":(glob)"+glob)
+	}
+	return args
+}
+
+// grepRepo runs git grep in dir. git grep exits with 1 when nothing matched,
+// which is not an error here.
+func grepRepo(dir string, args []string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && stderr.Len() == 0 {
+		return "", nil
+	}
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git grep: %s", msg)
+	}
+	return strings.TrimRight(stdout.String(), "\n"), nil
+}
+
+// ErrNoMatch is returned by Grep when nothing matched.
+var ErrNoMatch = errors.New("nothing matched")
+
+// Grep searches the tracked files of all repos in parallel, at most jobs at
+// once, and prints the matches prefixed with the repo name. It returns
+// ErrNoMatch when nothing matched.
+func (client *RepoManager) Grep(pattern string, opts GrepOptions) error {
+	client.logger.Info("searching", "pattern", pattern, "workspace", client.workspace)
+	repoConfigs := client.sortedRepos()
+	outputs := make([]string, len(repoConfigs))
+	errs := make(map[string]error)
+	args := opts.args(pattern)
+
+	var mu sync.Mutex
+	slots := make(chan struct{}, client.jobsLimit())
+	wg := sync.WaitGroup{}
+	for i, repoConfig := range repoConfigs {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int, repoConfig *RepoConfig) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			client.logger.Debug("searching", "repo", repoConfig.Name)
+			output, err := grepRepo(repoConfig.FullDir(client.workspace), args)
+			if err != nil {
+				mu.Lock()
+				errs[repoConfig.Name] = err
+				mu.Unlock()
+				return
+			}
+			outputs[i] = output
+		}(i, repoConfig)
+	}
+	wg.Wait()
+
+	matched := false
+	for i, repoConfig := range repoConfigs {
+		if outputs[i] == "" {
+			continue
+		}
+		matched = true
+		for _, line := range strings.Split(outputs[i], "\n") {
+			fmt.Printf("%s:%s\n", repoConfig.Name, line)
+		}
+	}
+	if len(errs) > 0 {
+		return &BatchError{Errors: errs}
+	}
+	if !matched {
+		return ErrNoMatch
+	}
+	return nil
+}
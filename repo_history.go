@@ -0,0 +1,207 @@
+package repos
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// historyFile is the audit log in the workspace directory, one JSON line per
+// repo of every operation that changes repos.
+const historyFile = ".repos-history.jsonl"
+
+// HistoryEntry is what a batch operation did to one repo.
+type HistoryEntry struct {
+	Time     time.Time `json:"time"`
+	Command  string    `json:"command"`
+	Schedule string    `json:"schedule,omitempty"`
+	Repo     string    `json:"repo"`
+	Outcome  outcome   `json:"outcome"`
+	Reason   string    `json:"reason,omitempty"`
+	Before   string    `json:"before,omitempty"`
+	After    string    `json:"after,omitempty"`
+}
+
+// Changed reports whether the operation moved the HEAD of the repo.
+func (entry *HistoryEntry) Changed() bool {
+	return entry.Before != entry.After
+}
+
+type HistoryOptions struct {
+	// Repo and Command only keep the entries of that repo or command.
+	Repo    string
+	Command string
+	// Since only keeps newer entries, e.g. 7d, 2w or 2023-01-01.
+	Since string
+	// Changed only keeps the entries that moved HEAD.
+	Changed bool
+	// Failed only keeps the entries that failed.
+	Failed bool
+	// Limit keeps the newest entries only, all of them when 0.
+	Limit int
+}
+
+// headOf returns the commit HEAD of the repo in dir points at, empty when
+// there is none.
+func headOf(dir string) string {
+	head, err := runGit(dir, "rev-parse", "--verify", "--quiet", "HEAD")
+	if err != nil {
+		return ""
+	}
+	return head
+}
+
+// recordHistory appends the outcomes of a batch operation to the audit log.
+// Failing to write it is only logged.
+func (client *RepoManager) recordHistory(command string, summary *runSummary) {
+	if len(summary.outcomes) == 0 {
+		return
+	}
+	now := time.Now()
+	var lines []byte
+	for _, o := range summary.outcomes {
+		data, err := json.Marshal(&HistoryEntry{
+			Time:     now,
+			Command:  command,
+			Schedule: client.schedule,
+			Repo:     o.name,
+			Outcome:  o.outcome,
+			Reason:   o.reason,
+			Before:   o.stats.Before,
+			After:    o.stats.After,
+		})
+		if err != nil {
+			client.logger.Warn("recording the history failed", "error", err)
+			return
+		}
+		lines = append(append(lines, data...), '\n')
+	}
+	path := filepath.Join(client.workspace, historyFile)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err == nil {
+		_, err = f.Write(lines)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		client.logger.Warn("recording the history failed", "path", path, "error", err)
+	}
+}
+
+// historyLog collects what an operation that doesn't run through runBatch
+// did to its repos, for the audit log.
+type historyLog struct {
+	client  *RepoManager
+	command string
+	summary runSummary
+}
+
+func (client *RepoManager) newHistory(command string) *historyLog {
+	return &historyLog{client: client, command: command}
+}
+
+// add records the outcome of a repo whose HEAD was before when the operation
+// started.
+func (h *historyLog) add(repoConfig *RepoConfig, before string, result outcome, reason string) {
+	h.summary.setStats(repoConfig.Name, repoStats{Before: before, After: headOf(repoConfig.FullDir(h.client.workspace))})
+	h.summary.add(repoConfig.Name, result, reason)
+}
+
+// fail records a repo the operation failed in.
+func (h *historyLog) fail(repoConfig *RepoConfig, before string, err error) {
+	h.summary.setStats(repoConfig.Name, repoStats{Before: before, After: headOf(repoConfig.FullDir(h.client.workspace))})
+	h.summary.addFailure(repoConfig.Name, err)
+}
+
+// record appends the collected outcomes to the audit log.
+func (h *historyLog) record() {
+	h.client.recordHistory(h.command, &h.summary)
+}
+
+// parseSince turns shorthands like 7d or 2w, dates and RFC 3339 timestamps
+// into the time they stand for.
+func parseSince(since string, now time.Time) (time.Time, error) {
+	if m := sinceShorthand.FindStringSubmatch(since); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "h":
+			return now.Add(-time.Duration(n) * time.Hour), nil
+		case "d":
+			return now.AddDate(0, 0, -n), nil
+		case "w":
+			return now.AddDate(0, 0, -7*n), nil
+		case "m":
+			return now.AddDate(0, -n, 0), nil
+		default:
+			return now.AddDate(-n, 0, 0), nil
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", since, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since %q, use e.g. 7d, 2w, 2023-01-01 or an RFC 3339 time", since)
+}
+
+// History reads the audit log of the workspace, newest entries first.
+func (client *RepoManager) History(opts HistoryOptions) ([]*HistoryEntry, error) {
+	var since time.Time
+	if opts.Since != "" {
+		var err error
+		if since, err = parseSince(opts.Since, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	entries := []*HistoryEntry{}
+	path := filepath.Join(client.workspace, historyFile)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		entry := &HistoryEntry{}
+		if err := json.Unmarshal([]byte(line), entry); err != nil {
+			client.logger.Warn("skipping a malformed history line", "path", path, "line", n, "error", err)
+			continue
+		}
+		switch {
+		case opts.Repo != "" && entry.Repo != opts.Repo,
+			opts.Command != "" && entry.Command != opts.Command,
+			!since.IsZero() && entry.Time.Before(since),
+			opts.Changed && !entry.Changed(),
+			opts.Failed && entry.Outcome != outcomeFailed:
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	if opts.Limit > 0 && len(entries) > opts.Limit {
+		entries = entries[:opts.Limit]
+	}
+	return entries, nil
+}
@@ -0,0 +1,325 @@
+package repos
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// HookMode is how hooks install puts the shared hooks in place.
+type HookMode string
+
+const (
+	// HookSymlink links the hooks from .git/hooks, so changes to the shared
+	// hooks apply right away.
+	HookSymlink HookMode = "symlink"
+	// HookCopy copies the hooks into .git/hooks.
+	HookCopy HookMode = "copy"
+	// HookHooksPath points core.hooksPath at the shared hooks directory.
+	HookHooksPath HookMode = "hooks-path"
+)
+
+// ParseHookMode parses the --mode of hooks install, defaulting to
+// HookSymlink.
+func ParseHookMode(value string) (HookMode, error) {
+	switch mode := HookMode(value); mode {
+	case "":
+		return HookSymlink, nil
+	case HookSymlink, HookCopy, HookHooksPath:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown hook mode %q, must be symlink, copy or hooks-path", value)
+}
+
+// hooksMarkerFile records in the git dir what hooks install did, for
+// uninstalling exactly that.
+const hooksMarkerFile = "repos-hooks.json"
+
+// gitHooks are the hook names git runs, other files in the shared hooks
+// directory such as a README are left alone.
+var gitHooks = map[string]bool{
+	"applypatch-msg": true, "pre-applypatch": true, "post-applypatch": true,
+	"pre-commit": true, "pre-merge-commit": true, "prepare-commit-msg": true,
+	"commit-msg": true, "post-commit": true, "pre-rebase": true,
+	"post-checkout": true, "post-merge": true, "pre-push": true,
+	"pre-receive": true, "update": true, "proc-receive": true,
+	"post-receive": true, "post-update": true, "reference-transaction": true,
+	"push-to-checkout": true, "pre-auto-gc": true, "post-rewrite": true,
+	"sendemail-validate": true, "fsmonitor-watchman": true,
+	"post-index-change": true,
+}
+
+type HooksOptions struct {
+	Select ListOptions
+	// From is the directory with the shared hooks.
+	From string
+	Mode HookMode
+	// Force replaces hooks the repos have of their own, keeping them with
+	// an .orig suffix, and overrides a core.hooksPath set otherwise.
+	Force bool
+}
+
+// installedHooks is what the marker file records.
+type installedHooks struct {
+	Mode HookMode `json:"mode"`
+	From string   `json:"from"`
+	// Hooks are the names of the hooks linked or copied.
+	Hooks []string `json:"hooks,omitempty"`
+	// Backups are the hooks the repo had of its own, renamed with .orig.
+	Backups []string `json:"backups,omitempty"`
+	// HooksPath is the core.hooksPath the repo had before.
+	HooksPath string `json:"hooks_path,omitempty"`
+}
+
+// sharedHooks returns the names of the hooks in dir.
+func sharedHooks(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var hooks []string
+	for _, entry := range entries {
+		if gitHooks[entry.Name()] && !entry.IsDir() {
+			hooks = append(hooks, entry.Name())
+		}
+	}
+	if len(hooks) == 0 {
+		return nil, fmt.Errorf("no git hooks in %s", dir)
+	}
+	return hooks, nil
+}
+
+// gitCommonDir returns the absolute git dir of the repo in dir, the main one
+// for worktrees.
+func gitCommonDir(dir string) (string, error) {
+	gitDir, err := runGit(dir, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(dir, gitDir)
+	}
+	return gitDir, nil
+}
+
+func readInstalledHooks(gitDir string) (*installedHooks, error) {
+	data, err := os.ReadFile(filepath.Join(gitDir, hooksMarkerFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	installed := &installedHooks{}
+	if err := json.Unmarshal(data, installed); err != nil {
+		return nil, fmt.Errorf("%s: %w", hooksMarkerFile, err)
+	}
+	return installed, nil
+}
+
+// upToDate reports whether the hooks installed as recorded are what
+// installing hooks from from in mode would do.
+func (installed *installedHooks) upToDate(dir, gitDir string, mode HookMode, from string, hooks []string) bool {
+	if installed.Mode != mode || installed.From != from {
+		return false
+	}
+	if mode == HookHooksPath {
+		hooksPath, _ := runGit(dir, "config", "core.hooksPath")
+		return hooksPath == from
+	}
+	if strings.Join(installed.Hooks, " ") != strings.Join(hooks, " ") {
+		return false
+	}
+	for _, hook := range hooks {
+		target := filepath.Join(gitDir, "hooks", hook)
+		if mode == HookSymlink {
+			if link, err := os.Readlink(target); err != nil || link != filepath.Join(from, hook) {
+				return false
+			}
+			continue
+		}
+		installedData, err := os.ReadFile(target)
+		sharedData, sharedErr := os.ReadFile(filepath.Join(from, hook))
+		if err != nil || sharedErr != nil || string(installedData) != string(sharedData) {
+			return false
+		}
+	}
+	return true
+}
+
+// uninstallHooks undoes what the marker in gitDir records.
+func uninstallHooks(dir, gitDir string, installed *installedHooks) error {
+	if installed.Mode == HookHooksPath {
+		if hooksPath, _ := runGit(dir, "config", "core.hooksPath"); hooksPath == installed.From {
+			if installed.HooksPath != "" {
+				if _, err := runGit(dir, "config", "core.hooksPath", installed.HooksPath); err != nil {
+					return err
+				}
+			} else if _, err := runGit(dir, "config", "--unset", "core.hooksPath"); err != nil {
+				return err
+			}
+		}
+	}
+	hooksDir := filepath.Join(gitDir, "hooks")
+	for _, hook := range installed.Hooks {
+		if err := os.Remove(filepath.Join(hooksDir, hook)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	for _, hook := range installed.Backups {
+		if err := os.Rename(filepath.Join(hooksDir, hook+".orig"), filepath.Join(hooksDir, hook)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Remove(filepath.Join(gitDir, hooksMarkerFile))
+}
+
+// copyHook copies the hook at src to dst, executable.
+func copyHook(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0755)
+}
+
+func (client *RepoManager) installRepoHooks(repoConfig *RepoConfig, opts HooksOptions, hooks []string) (outcome, string, error) {
+	if client.mirrorFor(repoConfig) {
+		return outcomeSkipped, "mirror", nil
+	}
+	dir := repoConfig.FullDir(client.workspace)
+	gitDir, err := gitCommonDir(dir)
+	if err != nil {
+		return outcomeFailed, "", err
+	}
+	installed, err := readInstalledHooks(gitDir)
+	if err != nil {
+		return outcomeFailed, "", err
+	}
+	// Hooks of the repo's own replaced before may be replaced again.
+	replaced := make(map[string]bool)
+	if installed != nil {
+		if installed.upToDate(dir, gitDir, opts.Mode, opts.From, hooks) {
+			return outcomeUpToDate, "", nil
+		}
+		if err := uninstallHooks(dir, gitDir, installed); err != nil {
+			return outcomeFailed, "", err
+		}
+		for _, hook := range installed.Backups {
+			replaced[hook] = true
+		}
+	}
+
+	hooksPath, _ := runGit(dir, "config", "core.hooksPath")
+	record := &installedHooks{Mode: opts.Mode, From: opts.From}
+	if opts.Mode == HookHooksPath {
+		if hooksPath != "" && hooksPath != opts.From {
+			if !opts.Force {
+				return outcomeFailed, "", fmt.Errorf("core.hooksPath is %s already, use --force to replace it", hooksPath)
+			}
+			record.HooksPath = hooksPath
+		}
+		if _, err := runGit(dir, "config", "core.hooksPath", opts.From); err != nil {
+			return outcomeFailed, "", err
+		}
+	} else {
+		if hooksPath != "" {
+			return outcomeFailed, "", fmt.Errorf("core.hooksPath is set to %s, so git doesn't run .git/hooks, use --mode hooks-path", hooksPath)
+		}
+		hooksDir := filepath.Join(gitDir, "hooks")
+		if err := os.MkdirAll(hooksDir, 0755); err != nil {
+			return outcomeFailed, "", err
+		}
+		// Conflicts are found before anything changes.
+		for _, hook := range hooks {
+			if _, err := os.Lstat(filepath.Join(hooksDir, hook)); err == nil {
+				if !opts.Force && !replaced[hook] {
+					return outcomeFailed, "", fmt.Errorf("has a %s hook of its own, use --force to replace it", hook)
+				}
+				record.Backups = append(record.Backups, hook)
+			}
+		}
+		for _, hook := range record.Backups {
+			target := filepath.Join(hooksDir, hook)
+			if err := os.Rename(target, target+".orig"); err != nil {
+				return outcomeFailed, "", err
+			}
+		}
+		for _, hook := range hooks {
+			src, target := filepath.Join(opts.From, hook), filepath.Join(hooksDir, hook)
+			if opts.Mode == HookCopy {
+				err = copyHook(src, target)
+			} else {
+				err = os.Symlink(src, target)
+			}
+			if err != nil {
+				uninstallHooks(dir, gitDir, record)
+				return outcomeFailed, "", err
+			}
+			record.Hooks = append(record.Hooks, hook)
+		}
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return outcomeFailed, "", err
+	}
+	if err := os.WriteFile(filepath.Join(gitDir, hooksMarkerFile), data, 0644); err != nil {
+		return outcomeFailed, "", err
+	}
+	if len(record.Backups) > 0 {
+		return outcomeSucceeded, "kept " + strings.Join(record.Backups, ", ") + " as .orig", nil
+	}
+	return outcomeSucceeded, "", nil
+}
+
+// InstallHooks puts the git hooks in opts.From in place in every selected
+// repo, as symlinks or copies in its hooks directory or by pointing
+// core.hooksPath at opts.From. Hooks installed before are replaced. What is
+// installed is recorded in the git dir, so UninstallHooks removes just that.
+func (client *RepoManager) InstallHooks(opts HooksOptions) error {
+	from, err := filepath.Abs(expandPath(opts.From))
+	if err != nil {
+		return err
+	}
+	opts.From = from
+	hooks, err := sharedHooks(from)
+	if err != nil {
+		return err
+	}
+	sort.Strings(hooks)
+	client.logger.Info("installing hooks", "workspace", client.workspace, "from", from, "mode", opts.Mode, "hooks", hooks)
+	return client.runBatch("hooks install", client.selectedRepos(opts.Select), func(repoConfig *RepoConfig) (outcome, string, error) {
+		return client.installRepoHooks(repoConfig, opts, hooks)
+	})
+}
+
+// UninstallHooks removes the hooks InstallHooks installed from the selected
+// repos, restoring the hooks they replaced.
+func (client *RepoManager) UninstallHooks(opts ListOptions) error {
+	client.logger.Info("uninstalling hooks", "workspace", client.workspace)
+	return client.runBatch("hooks uninstall", client.selectedRepos(opts), func(repoConfig *RepoConfig) (outcome, string, error) {
+		if client.mirrorFor(repoConfig) {
+			return outcomeSkipped, "mirror", nil
+		}
+		dir := repoConfig.FullDir(client.workspace)
+		gitDir, err := gitCommonDir(dir)
+		if err != nil {
+			return outcomeFailed, "", err
+		}
+		installed, err := readInstalledHooks(gitDir)
+		if err != nil {
+			return outcomeFailed, "", err
+		}
+		if installed == nil {
+			return outcomeUpToDate, "no hooks installed", nil
+		}
+		if err := uninstallHooks(dir, gitDir, installed); err != nil {
+			return outcomeFailed, "", err
+		}
+		return outcomeSucceeded, "", nil
+	})
+}
@@ -0,0 +1,96 @@
+package repos
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// sshControlOptions make ssh share one connection per host among the git
+// commands of a batch, and keep it open for a minute after the last one.
+const sshControlOptions = "-o ControlMaster=auto -o ControlPath=~/.ssh/repos-%C -o ControlPersist=60s"
+
+// hostLimiter limits how many repos on the same remote host are worked on
+// at once, so that forges don't rate limit the batch.
+type hostLimiter struct {
+	// limits maps host patterns to the number of repos on each matching
+	// host that may run at once.
+	limits map[string]int
+
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+func newHostLimiter(limits map[string]int) *hostLimiter {
+	return &hostLimiter{limits: limits, slots: make(map[string]chan struct{})}
+}
+
+// remoteHost returns the host of the origin of a repo, or an empty string
+// for local remotes.
+func remoteHost(repoConfig *RepoConfig) string {
+	endpoint, err := transport.NewEndpoint(repoConfig.RemoteURL())
+	if err != nil || endpoint.Protocol == "file" {
+		return ""
+	}
+	return endpoint.Host
+}
+
+// acquire waits until a repo on host may run and returns the function that
+// releases its slot.
+func (limiter *hostLimiter) acquire(host string) func() {
+	slots := limiter.slotsFor(host)
+	if slots == nil {
+		return func() {}
+	}
+	slots <- struct{}{}
+	return func() { <-slots }
+}
+
+func (limiter *hostLimiter) slotsFor(host string) chan struct{} {
+	if host == "" || len(limiter.limits) == 0 {
+		return nil
+	}
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	if slots, ok := limiter.slots[host]; ok {
+		return slots
+	}
+	patterns := make([]string, 0, len(limiter.limits))
+	for pattern := range limiter.limits {
+		patterns = append(patterns, pattern)
+	}
+	var slots chan struct{}
+	if pattern, ok := matchHost(patterns, host); ok && limiter.limits[pattern] > 0 {
+		slots = make(chan struct{}, limiter.limits[pattern])
+	}
+	limiter.slots[host] = slots
+	return slots
+}
+
+// withSSHMultiplexing adds the ssh connection sharing options to the git -c
+// options of an ssh remote, keeping a proxy command already in them. ssh on
+// Windows can't share connections.
+func withSSHMultiplexing(args []string, remoteURL string) []string {
+	if runtime.GOOS == "windows" {
+		return args
+	}
+	endpoint, err := transport.NewEndpoint(remoteURL)
+	if err != nil || endpoint.Protocol != "ssh" {
+		return args
+	}
+	return withSSHOptions(args, sshControlOptions)
+}
+
+// withSSHOptions adds options to the ssh command in the git -c options args,
+// setting core.sshCommand unless they have it already.
+func withSSHOptions(args []string, options string) []string {
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "core.sshCommand=ssh ") {
+			args[i] = "core.sshCommand=ssh " + options + strings.TrimPrefix(arg, "core.sshCommand=ssh")
+			return args
+		}
+	}
+	return append(args, "-c", "core.sshCommand=ssh "+options)
+}
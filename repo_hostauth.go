@@ -0,0 +1,167 @@
+package repos
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/kevinburke/ssh_config"
+)
+
+// HostAuth is how to authenticate to the remotes on the hosts matching its
+// pattern in the auth config, e.g. a work key and port for git.corp.example.
+type HostAuth struct {
+	// KeyFile is the ssh key for ssh remotes, used instead of key_file and
+	// the IdentityFile of ~/.ssh/config.
+	KeyFile string `yaml:"key_file,omitempty" mapstructure:"key_file"`
+	// User is the ssh user, or the user name sent with the token, for
+	// remotes whose url doesn't name one.
+	User string `yaml:"user,omitempty"`
+	// Port is the ssh port for remotes whose url doesn't give one.
+	Port int `yaml:"port,omitempty"`
+	// Token is the password for https remotes.
+	Token string `yaml:"token,omitempty"`
+	// TokenEnv names the environment variable holding the token.
+	TokenEnv string `yaml:"token_env,omitempty" mapstructure:"token_env"`
+}
+
+// hostAuthFor returns the auth configured for host in auths, whose keys are
+// host patterns, or nil if there is none.
+func hostAuthFor(auths map[string]*HostAuth, host string) *HostAuth {
+	patterns := make([]string, 0, len(auths))
+	for pattern := range auths {
+		patterns = append(patterns, pattern)
+	}
+	if pattern, ok := matchHost(patterns, host); ok {
+		return auths[pattern]
+	}
+	return nil
+}
+
+// keyPath returns KeyFile with environment variables and ~ expanded.
+func (auth *HostAuth) keyPath() string {
+	return expandPath(auth.KeyFile)
+}
+
+func (auth *HostAuth) token() string {
+	if auth.TokenEnv != "" {
+		if token := os.Getenv(auth.TokenEnv); token != "" {
+			return token
+		}
+	}
+	return auth.Token
+}
+
+// sshConfigWithPorts makes go-git connect to the configured ports, which it
+// only takes from ~/.ssh/config, falling back to that file for other hosts.
+type sshConfigWithPorts struct {
+	auths map[string]*HostAuth
+}
+
+func (config *sshConfigWithPorts) Get(alias, key string) string {
+	if auth := hostAuthFor(config.auths, alias); auth != nil && auth.Port != 0 {
+		switch strings.ToLower(key) {
+		case "hostname":
+			if hostname := ssh_config.Get(alias, "Hostname"); hostname != "" {
+				return hostname
+			}
+			return alias
+		case "port":
+			return strconv.Itoa(auth.Port)
+		}
+	}
+	return ssh_config.Get(alias, key)
+}
+
+var installSSHPortsOnce sync.Once
+
+// installSSHPorts makes go-git's ssh transport use the ports of auths.
+func installSSHPorts(auths map[string]*HostAuth) {
+	hasPort := false
+	for _, auth := range auths {
+		hasPort = hasPort || auth.Port != 0
+	}
+	if !hasPort {
+		return
+	}
+	installSSHPortsOnce.Do(func() {
+		ssh.DefaultSSHConfig = &sshConfigWithPorts{auths: auths}
+	})
+}
+
+// tokenHelper is a git credential helper answering with the token passed in
+// the environment, so it doesn't show up in the arguments of git.
+const tokenHelper = `!f() { test "$1" = get && echo "username=$REPOS_AUTH_USER" && echo "password=$REPOS_AUTH_TOKEN"; }; f`
+
+// gitHostAuthArgs returns the git -c options and environment that apply the
+// auth configured for the host of remoteURL to the git command line tool.
+func gitHostAuthArgs(auths map[string]*HostAuth, remoteURL string, args []string) ([]string, []string) {
+	endpoint, err := transport.NewEndpoint(remoteURL)
+	if err != nil {
+		return args, nil
+	}
+	auth := hostAuthFor(auths, endpoint.Host)
+	if auth == nil {
+		if endpoint.Protocol == "http" || endpoint.Protocol == "https" {
+			return gitNetrcArgs(endpoint, args)
+		}
+		return args, nil
+	}
+	switch endpoint.Protocol {
+	case "ssh":
+		var options []string
+		if keyPath := auth.keyPath(); keyPath != "" {
+			options = append(options, "-i "+shellQuote(keyPath), "-o IdentitiesOnly=yes")
+		}
+		if auth.Port != 0 && (endpoint.Port == 0 || endpoint.Port == 22) {
+			options = append(options, "-o Port="+strconv.Itoa(auth.Port))
+		}
+		if auth.User != "" && endpoint.User == "" {
+			options = append(options, "-o User="+shellQuote(auth.User))
+		}
+		if len(options) == 0 {
+			return args, nil
+		}
+		return withSSHOptions(args, strings.Join(options, " ")), nil
+	case "http", "https":
+		token := auth.token()
+		if token == "" || endpoint.Password != "" {
+			return gitNetrcArgs(endpoint, args)
+		}
+		user := endpoint.User
+		if user == "" {
+			user = auth.User
+		}
+		if user == "" {
+			user = "git"
+		}
+		return withTokenHelper(args, user, token)
+	}
+	return args, nil
+}
+
+// gitNetrcArgs passes the credentials of the .netrc file to git. curl only
+// reads ~/.netrc by itself, this makes NETRC work as well.
+func gitNetrcArgs(endpoint *transport.Endpoint, args []string) ([]string, []string) {
+	if endpoint.Password != "" || os.Getenv("NETRC") == "" {
+		return args, nil
+	}
+	user, password, ok := netrcCredential(endpoint.Host)
+	if !ok {
+		return args, nil
+	}
+	if user == "" {
+		user = endpoint.User
+	}
+	return withTokenHelper(args, user, password)
+}
+
+// withTokenHelper makes git use user and token for the remote instead of
+// asking its credential helpers.
+func withTokenHelper(args []string, user, token string) ([]string, []string) {
+	args = append(args, "-c", "credential.helper=", "-c", "credential.helper="+tokenHelper)
+	return args, []string{"REPOS_AUTH_USER=" + user, "REPOS_AUTH_TOKEN=" + token}
+}
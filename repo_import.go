@@ -0,0 +1,310 @@
+package repos
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ImportOptions tells how to import repos from the config of another tool.
+type ImportOptions struct {
+	// Base resolves relative remote urls, e.g. the url a repo-tool
+	// manifest was cloned from. It defaults to the location of the imported
+	// manifest when that is a url.
+	Base string
+}
+
+// readSource reads a local file or, for http and https urls, downloads it.
+func readSource(source string) ([]byte, error) {
+	if !isURL(source) {
+		return os.ReadFile(source)
+	}
+	resp, err := http.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", source, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// resolveSource resolves name relative to the source it is referenced from.
+func resolveSource(source, name string) string {
+	if isURL(name) || filepath.IsAbs(name) {
+		return name
+	}
+	if isURL(source) {
+		u, err := url.Parse(source)
+		if err != nil {
+			return name
+		}
+		u.Path = path.Join(path.Dir(u.Path), name)
+		return u.String()
+	}
+	return filepath.Join(filepath.Dir(source), name)
+}
+
+// importRepos adds repoConfigs to the config, leaving repos that are
+// configured already alone, and saves it. Directories inside the workspace
+// are made relative to it.
+func (client *RepoManager) importRepos(repoConfigs []*RepoConfig) error {
+	configured := make(map[string]bool)
+	for _, repoConfig := range client.config.Repos {
+		configured[repoConfig.FullDir(client.workspace)] = true
+	}
+	workspace, err := filepath.Abs(client.workspace)
+	if err != nil {
+		return err
+	}
+	max := client.nameWidth()
+	for _, repoConfig := range repoConfigs {
+		fullDir := repoConfig.FullDir(client.workspace)
+		if abs, err := filepath.Abs(fullDir); err == nil {
+			if rel, err := filepath.Rel(workspace, abs); err == nil && !strings.HasPrefix(rel, "..") {
+				repoConfig.Dir = rel
+			}
+		}
+		if configured[fullDir] {
+			client.logger.Debug("skipping configured repo", "dir", repoConfig.Dir)
+			continue
+		}
+		if _, ok := client.config.Repos[repoConfig.Name]; ok {
+			repoConfig.Name = strings.ReplaceAll(filepath.ToSlash(repoConfig.Dir), "/", "-")
+		}
+		if _, ok := client.config.Repos[repoConfig.Name]; ok {
+			client.printRepoLine(max, repoConfig.Name, "skipped, the name is taken")
+			continue
+		}
+		client.config.Repos[repoConfig.Name] = repoConfig
+		configured[fullDir] = true
+		client.printRepoLine(max, repoConfig.Name, "imported "+repoConfig.Dir)
+	}
+	if err := client.config.Save(); err != nil {
+		return err
+	}
+	client.syncGoWork()
+	return nil
+}
+
+// repoManifest is a manifest of Google's repo tool.
+type repoManifest struct {
+	Remotes  []manifestRemote  `xml:"remote"`
+	Default  *manifestDefault  `xml:"default"`
+	Projects []manifestProject `xml:"project"`
+	Removes  []manifestProject `xml:"remove-project"`
+	Extends  []manifestProject `xml:"extend-project"`
+	Includes []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"include"`
+}
+
+type manifestRemote struct {
+	Name     string `xml:"name,attr"`
+	Fetch    string `xml:"fetch,attr"`
+	Revision string `xml:"revision,attr"`
+}
+
+type manifestDefault struct {
+	Remote   string `xml:"remote,attr"`
+	Revision string `xml:"revision,attr"`
+}
+
+type manifestProject struct {
+	Name       string `xml:"name,attr"`
+	Path       string `xml:"path,attr"`
+	Remote     string `xml:"remote,attr"`
+	Revision   string `xml:"revision,attr"`
+	Upstream   string `xml:"upstream,attr"`
+	DestBranch string `xml:"dest-branch,attr"`
+	Groups     string `xml:"groups,attr"`
+	CloneDepth int    `xml:"clone-depth,attr"`
+}
+
+var commitHash = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// loadRepoManifest reads the manifest in source with the manifests it
+// includes, which are merged in, later ones overriding earlier ones.
+func loadRepoManifest(source string, merged *repoManifest, depth int) error {
+	if depth > 10 {
+		return fmt.Errorf("%s: includes nest too deep", source)
+	}
+	data, err := readSource(source)
+	if err != nil {
+		return err
+	}
+	manifest := &repoManifest{}
+	if err := xml.Unmarshal(data, manifest); err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	merged.Remotes = append(merged.Remotes, manifest.Remotes...)
+	if manifest.Default != nil {
+		merged.Default = manifest.Default
+	}
+	merged.Projects = append(merged.Projects, manifest.Projects...)
+	merged.Removes = append(merged.Removes, manifest.Removes...)
+	merged.Extends = append(merged.Extends, manifest.Extends...)
+	for _, include := range manifest.Includes {
+		if err := loadRepoManifest(resolveSource(source, include.Name), merged, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// manifestBranch picks the branch to track from the revision of a project,
+// which may also be a tag or a commit. Those fall back to the upstream or
+// dest-branch of the project.
+func manifestBranch(project manifestProject, revision string) string {
+	switch {
+	case strings.HasPrefix(revision, "refs/heads/"):
+		return strings.TrimPrefix(revision, "refs/heads/")
+	case revision != "" && !strings.HasPrefix(revision, "refs/") && !commitHash.MatchString(revision):
+		return revision
+	}
+	for _, branch := range []string{project.Upstream, project.DestBranch} {
+		if branch != "" && !commitHash.MatchString(branch) {
+			return strings.TrimPrefix(branch, "refs/heads/")
+		}
+	}
+	return ""
+}
+
+// manifestGroups turns the groups of a project into repos groups, dropping
+// the special ones of the repo tool.
+func manifestGroups(groups string) []string {
+	var kept []string
+	for _, group := range strings.FieldsFunc(groups, func(r rune) bool {
+		return r == ',' || r == ' '
+	}) {
+		switch {
+		case group == "all", group == "default", group == "notdefault",
+			strings.HasPrefix(group, "name:"), strings.HasPrefix(group, "path:"):
+			continue
+		}
+		kept = append(kept, group)
+	}
+	return kept
+}
+
+// RepoManifestConfigs converts the projects of the repo-tool manifest in
+// source, a file or url, into repo configs.
+func RepoManifestConfigs(source string, opts ImportOptions) ([]*RepoConfig, error) {
+	manifest := &repoManifest{}
+	if err := loadRepoManifest(source, manifest, 0); err != nil {
+		return nil, err
+	}
+	base := opts.Base
+	if base == "" && isURL(source) {
+		base = source
+	}
+	remotes := make(map[string]manifestRemote)
+	for _, remote := range manifest.Remotes {
+		remotes[remote.Name] = remote
+	}
+	removed := make(map[string]bool)
+	for _, project := range manifest.Removes {
+		removed[project.Name] = true
+	}
+	extends := make(map[string]manifestProject)
+	for _, project := range manifest.Extends {
+		extends[project.Name] = project
+	}
+	defaults := manifestDefault{}
+	if manifest.Default != nil {
+		defaults = *manifest.Default
+	}
+
+	var repoConfigs []*RepoConfig
+	for _, project := range manifest.Projects {
+		if removed[project.Name] {
+			continue
+		}
+		if extend, ok := extends[project.Name]; ok {
+			if extend.Revision != "" {
+				project.Revision = extend.Revision
+			}
+			if extend.Groups != "" {
+				project.Groups += "," + extend.Groups
+			}
+		}
+		remoteName := project.Remote
+		if remoteName == "" {
+			remoteName = defaults.Remote
+		}
+		remote, ok := remotes[remoteName]
+		if !ok {
+			return nil, fmt.Errorf("project %s: remote %q is not declared", project.Name, remoteName)
+		}
+		fetch, err := resolveFetch(remote.Fetch, base)
+		if err != nil {
+			return nil, fmt.Errorf("project %s: %w", project.Name, err)
+		}
+		revision := project.Revision
+		if revision == "" {
+			revision = remote.Revision
+		}
+		if revision == "" {
+			revision = defaults.Revision
+		}
+		dir := project.Path
+		if dir == "" {
+			dir = project.Name
+		}
+		repoConfig := &RepoConfig{
+			Name:   path.Base(dir),
+			Dir:    dir,
+			Url:    strings.TrimSuffix(fetch, "/") + "/" + project.Name,
+			Depth:  project.CloneDepth,
+			Groups: manifestGroups(project.Groups),
+		}
+		if branch := manifestBranch(project, revision); branch != "" {
+			repoConfig.Branch = Branches{branch}
+		}
+		repoConfigs = append(repoConfigs, repoConfig)
+	}
+	sort.SliceStable(repoConfigs, func(i, j int) bool {
+		return repoConfigs[i].Dir < repoConfigs[j].Dir
+	})
+	return repoConfigs, nil
+}
+
+// resolveFetch resolves the fetch url of a remote, which the repo tool allows
+// to be relative to the url the manifest was cloned from.
+func resolveFetch(fetch, base string) (string, error) {
+	if !strings.HasPrefix(fetch, ".") {
+		return fetch, nil
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil || baseURL.Scheme == "" {
+		return "", fmt.Errorf("the fetch url %q is relative, pass the url the manifest comes from", fetch)
+	}
+	ref, err := url.Parse(fetch)
+	if err != nil {
+		return "", err
+	}
+	return baseURL.ResolveReference(ref).String(), nil
+}
+
+// ImportRepoManifest adds the projects of a repo-tool manifest to the config.
+func (client *RepoManager) ImportRepoManifest(source string, opts ImportOptions) error {
+	client.logger.Info("importing the manifest", "source", source)
+	repoConfigs, err := RepoManifestConfigs(source, opts)
+	if err != nil {
+		return err
+	}
+	return client.importRepos(repoConfigs)
+}
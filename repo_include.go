@@ -0,0 +1,182 @@
+package repos
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// includedConfig is what is taken from an included config file, which
+// holds nothing else.
+type includedConfig struct {
+	Include []string               `yaml:"include,omitempty"`
+	Repos   map[string]*RepoConfig `yaml:"repos"`
+}
+
+// resolveIncludes expands the include patterns of a config file, globs
+// relative to the directory of cfgFile, into sorted file paths.
+func resolveIncludes(cfgFile string, patterns []string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		pattern = expandPath(pattern)
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(cfgFile), pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("include %s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("include %s matches no file", pattern)
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// readIncludedConfig reads an included or nested config file.
+func readIncludedConfig(file string) (*includedConfig, error) {
+	v := newViper()
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	included := &includedConfig{}
+	if err := v.Unmarshal(included); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return included, nil
+}
+
+// LoadIncludes merges the repos of the files named by include into the
+// config. Included files may include others in turn. A repo defined in more
+// than one file is an error. Save writes every repo back to the file it
+// came from.
+func (config *ReposConfig) LoadIncludes() error {
+	defined := make(map[string]string)
+	for name := range config.Repos {
+		defined[name] = config.CfgFile
+	}
+	seen := map[string]bool{filepath.Clean(config.CfgFile): true}
+	var load func(cfgFile string, patterns []string) error
+	load = func(cfgFile string, patterns []string) error {
+		files, err := resolveIncludes(cfgFile, patterns)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			if seen[file] {
+				continue
+			}
+			seen[file] = true
+			included, err := readIncludedConfig(file)
+			if err != nil {
+				return err
+			}
+			config.includes = append(config.includes, &includedFile{path: file, include: included.Include})
+			for name, repoConfig := range included.Repos {
+				if other, ok := defined[name]; ok {
+					return fmt.Errorf("repo %s is defined in both %s and %s", name, other, file)
+				}
+				defined[name] = file
+				repoConfig.source = file
+				if config.Repos == nil {
+					config.Repos = make(map[string]*RepoConfig)
+				}
+				config.Repos[name] = repoConfig
+			}
+			if err := load(file, included.Include); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return load(config.CfgFile, config.Include)
+}
+
+// IncludedFile returns the included config file that is file, for adding
+// repos to it.
+func (config *ReposConfig) IncludedFile(file string) (string, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	for _, included := range config.root().includes {
+		if includedAbs, err := filepath.Abs(included.path); err == nil && includedAbs == abs {
+			return included.path, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not an included config file", file)
+}
+
+// SetSource makes Save write repoConfig to file, an included config file
+// returned by IncludedFile, or to the main config file when file is empty.
+func (repoConfig *RepoConfig) SetSource(file string) {
+	repoConfig.source = file
+}
+
+// includedFile is a config file loaded through include, or a nested one.
+type includedFile struct {
+	path    string
+	include []string
+	// dir is the directory of a nested config file relative to the
+	// workspace, which the dirs of its repos are relative to.
+	dir string
+}
+
+// reposOf returns the repos defined in source, including the ones that
+// nested config files override.
+func (config *ReposConfig) reposOf(source string) map[string]*RepoConfig {
+	repos := make(map[string]*RepoConfig)
+	for _, repoConfig := range config.shadowed {
+		if repoConfig.source == source {
+			repos[repoConfig.Name] = repoConfig
+		}
+	}
+	for name, repoConfig := range config.Repos {
+		if repoConfig.source == source {
+			repos[name] = repoConfig
+		}
+	}
+	return repos
+}
+
+// saveIncludes writes the repos of every included and nested file back to
+// it.
+func (config *ReposConfig) saveIncludes() error {
+	for _, file := range config.includes {
+		repos := config.reposOf(file.path)
+		if file.dir != "" {
+			for name, repoConfig := range repos {
+				if dir, err := filepath.Rel(file.dir, repoConfig.Dir); err == nil && !filepath.IsAbs(expandPath(repoConfig.Dir)) {
+					relative := *repoConfig
+					relative.Dir = dir
+					repos[name] = &relative
+				}
+			}
+		}
+		data, err := config.marshalEncrypted(&includedConfig{Include: file.include, Repos: repos})
+		if err != nil {
+			return err
+		}
+		var settings map[string]interface{}
+		if err := yaml.Unmarshal(data, &settings); err != nil {
+			return err
+		}
+		if err := writeSettings(file.path, settings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mainRepos returns the repos defined in the main config file.
+func (config *ReposConfig) mainRepos() map[string]*RepoConfig {
+	if len(config.includes) == 0 {
+		return config.Repos
+	}
+	return config.reposOf("")
+}
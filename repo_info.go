@@ -0,0 +1,84 @@
+package repos
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RepoInfo is everything known about one managed repo.
+type RepoInfo struct {
+	Config   *RepoConfig
+	Dir      string
+	Branch   string
+	Head     string
+	Subject  string
+	Upstream string
+	Ahead    int
+	Behind   int
+	Remotes  map[string]string
+	// LastFetch is zero when the repo was never fetched.
+	LastFetch time.Time
+	Stashes   int
+	Clean     bool
+}
+
+// Info collects the state of the repo called name, cloning it first when it
+// is a lazy repo not cloned yet.
+func (client *RepoManager) Info(name string) (*RepoInfo, error) {
+	repoConfig, err := client.repoConfigOf(name)
+	if err != nil {
+		return nil, err
+	}
+	if client.notCloned(repoConfig) {
+		if _, err := client.Get(name); err != nil {
+			return nil, err
+		}
+	}
+	dir := repoConfig.FullDir(client.workspace)
+	status, err := client.backend.Status(dir)
+	if err != nil {
+		return nil, err
+	}
+	info := &RepoInfo{
+		Config:  repoConfig,
+		Dir:     dir,
+		Branch:  status.Head(),
+		Clean:   status.Clean(),
+		Remotes: map[string]string{},
+	}
+
+	head, err := runGit(dir, "log", "-1", "--format=%H%x00%s")
+	if err != nil {
+		return nil, err
+	}
+	info.Head, info.Subject, _ = strings.Cut(head, "\x00")
+
+	if info.Upstream = upstreamOf(dir); info.Upstream != "" {
+		if info.Ahead, info.Behind, err = aheadBehind(dir, info.Upstream); err != nil {
+			return nil, err
+		}
+	}
+
+	remotes, err := runGit(dir, "remote", "-v")
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(remotes, "\n") {
+		if fields := strings.Fields(line); len(fields) == 3 && fields[2] == "(fetch)" {
+			info.Remotes[fields[0]] = fields[1]
+		}
+	}
+
+	if gitDir, err := gitDirOf(dir); err == nil {
+		if stat, err := os.Stat(filepath.Join(gitDir, "FETCH_HEAD")); err == nil {
+			info.LastFetch = stat.ModTime()
+		}
+	}
+
+	if stashes, err := runGit(dir, "stash", "list"); err == nil && stashes != "" {
+		info.Stashes = len(strings.Split(stashes, "\n"))
+	}
+	return info, nil
+}
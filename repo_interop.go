@@ -0,0 +1,365 @@
+package repos
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of other multi-repo tools that repos imports and exports.
+const (
+	// FormatMr is the .mrconfig of myrepos.
+	FormatMr = "mr"
+	// FormatVcstool is the .repos file of vcstool.
+	FormatVcstool = "vcstool"
+	// FormatGita is the repos.csv of gita, with its groups.csv.
+	FormatGita = "gita"
+)
+
+var interopFormats = []string{FormatMr, FormatVcstool, FormatGita}
+
+func checkFormat(format string) error {
+	if !contains(interopFormats, format) {
+		return fmt.Errorf("invalid format %q, must be one of mr, vcstool or gita", format)
+	}
+	return nil
+}
+
+// DefaultGitaConfig returns where gita keeps its repos.csv.
+func DefaultGitaConfig() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = filepath.Join(homeDir(), ".config")
+	}
+	return filepath.Join(dir, "gita", "repos.csv")
+}
+
+// Import adds the repos listed in source, a config file of another tool in
+// format, to the config.
+func (client *RepoManager) Import(format, source string) error {
+	if err := checkFormat(format); err != nil {
+		return err
+	}
+	client.logger.Info("importing", "format", format, "source", source)
+	var repoConfigs []*RepoConfig
+	var err error
+	switch format {
+	case FormatMr:
+		repoConfigs, err = client.mrConfigs(source)
+	case FormatVcstool:
+		repoConfigs, err = client.vcstoolConfigs(source)
+	case FormatGita:
+		repoConfigs, err = client.gitaConfigs(source)
+	}
+	if err != nil {
+		return err
+	}
+	return client.importRepos(repoConfigs)
+}
+
+// splitShellWords splits a command line into words, honoring single and
+// double quotes and backslash escapes.
+func splitShellWords(line string) []string {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// parseGitClone returns the url and branch of a git clone command line.
+func parseGitClone(command string) (url, branch string, ok bool) {
+	words := splitShellWords(command)
+	if len(words) < 3 || words[0] != "git" || words[1] != "clone" {
+		return "", "", false
+	}
+	var args []string
+	for i := 2; i < len(words); i++ {
+		switch word := words[i]; {
+		case word == "-b" || word == "--branch":
+			if i+1 < len(words) {
+				branch = words[i+1]
+				i++
+			}
+		case strings.HasPrefix(word, "--branch="):
+			branch = strings.TrimPrefix(word, "--branch=")
+		case strings.HasPrefix(word, "-"):
+		default:
+			args = append(args, word)
+		}
+	}
+	if len(args) == 0 {
+		return "", "", false
+	}
+	return args[0], branch, true
+}
+
+// mrConfigs reads the git repos of a .mrconfig, whose sections are repo
+// directories relative to the file with the clone command in checkout.
+// Sections of a downloaded .mrconfig are relative to the workspace.
+func (client *RepoManager) mrConfigs(source string) ([]*RepoConfig, error) {
+	data, err := readSource(source)
+	if err != nil {
+		return nil, err
+	}
+	base := filepath.Dir(source)
+	var repoConfigs []*RepoConfig
+	var section string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = strings.TrimSpace(line[1 : len(line)-1])
+		case section != "" && section != "DEFAULT":
+			key, value, ok := strings.Cut(line, "=")
+			if !ok || strings.TrimSpace(key) != "checkout" {
+				continue
+			}
+			url, branch, ok := parseGitClone(strings.TrimSpace(value))
+			if !ok {
+				client.logger.Warn("not a git clone, skipping", "section", section)
+				continue
+			}
+			dir := expandPath(section)
+			if !filepath.IsAbs(dir) && !isURL(source) {
+				dir = filepath.Join(base, dir)
+			}
+			repoConfigs = append(repoConfigs, importedRepo(dir, url, branch))
+		}
+	}
+	return repoConfigs, scanner.Err()
+}
+
+// vcstoolFile is the .repos file of vcstool.
+type vcstoolFile struct {
+	Repositories map[string]*vcstoolRepo `yaml:"repositories"`
+}
+
+type vcstoolRepo struct {
+	Type    string `yaml:"type"`
+	URL     string `yaml:"url"`
+	Version string `yaml:"version,omitempty"`
+}
+
+// vcstoolConfigs reads the git repos of a vcstool .repos file, whose keys are
+// the repo directories. Versions that are commits aren't tracked.
+func (client *RepoManager) vcstoolConfigs(source string) ([]*RepoConfig, error) {
+	data, err := readSource(source)
+	if err != nil {
+		return nil, err
+	}
+	file := &vcstoolFile{}
+	if err := yaml.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	var repoConfigs []*RepoConfig
+	for dir, repo := range file.Repositories {
+		if repo.Type != "git" {
+			client.logger.Warn("not a git repo, skipping", "dir", dir, "type", repo.Type)
+			continue
+		}
+		branch := repo.Version
+		if commitHash.MatchString(branch) {
+			branch = ""
+		}
+		repoConfigs = append(repoConfigs, importedRepo(dir, repo.URL, branch))
+	}
+	sort.Slice(repoConfigs, func(i, j int) bool {
+		return repoConfigs[i].Dir < repoConfigs[j].Dir
+	})
+	return repoConfigs, nil
+}
+
+// gitaConfigs reads the repos of gita's repos.csv, rows of path, name, type
+// and flags, with the groups from the groups.csv next to it. gita doesn't
+// record urls, they are taken from the origin of the repos.
+func (client *RepoManager) gitaConfigs(source string) ([]*RepoConfig, error) {
+	f, err := os.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	groups, err := readGitaGroups(filepath.Join(filepath.Dir(source), "groups.csv"))
+	if err != nil {
+		return nil, err
+	}
+	var repoConfigs []*RepoConfig
+	for _, record := range records {
+		if len(record) < 2 || record[0] == "" {
+			continue
+		}
+		dir := record[0]
+		url := originOf(dir)
+		if url == "" {
+			client.logger.Warn("no origin url, skipping", "dir", dir)
+			continue
+		}
+		repoConfig := importedRepo(dir, url, defaultBranchOf(dir))
+		repoConfig.Name = record[1]
+		repoConfig.Groups = groups[record[1]]
+		repoConfigs = append(repoConfigs, repoConfig)
+	}
+	return repoConfigs, nil
+}
+
+// readGitaGroups maps repo names to their groups in gita's groups.csv, lines
+// of a group name, its space separated repos and optionally a path, split by
+// colons.
+func readGitaGroups(path string) (map[string][]string, error) {
+	groups := make(map[string][]string)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return groups, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Split(strings.TrimSpace(line), ":")
+		if len(fields) < 2 || fields[0] == "" {
+			continue
+		}
+		for _, name := range strings.Fields(fields[1]) {
+			groups[name] = append(groups[name], fields[0])
+		}
+	}
+	return groups, nil
+}
+
+func importedRepo(dir, url, branch string) *RepoConfig {
+	repoConfig := &RepoConfig{
+		Name: filepath.Base(dir),
+		Dir:  dir,
+		Url:  url,
+	}
+	if branch != "" {
+		repoConfig.Branch = Branches{branch}
+	}
+	return repoConfig
+}
+
+// Export writes the repos in format for another tool to w. Directories are
+// relative to the workspace, except for gita, which wants absolute paths.
+func (client *RepoManager) Export(format string, w io.Writer) error {
+	if err := checkFormat(format); err != nil {
+		return err
+	}
+	repoConfigs := client.sortedRepos()
+	switch format {
+	case FormatMr:
+		return client.exportMr(repoConfigs, w)
+	case FormatVcstool:
+		return client.exportVcstool(repoConfigs, w)
+	default:
+		return client.exportGita(repoConfigs, w)
+	}
+}
+
+// exportDir returns the directory of a repo as configured, relative to the
+// workspace unless it is absolute.
+func (client *RepoManager) exportDir(repoConfig *RepoConfig) string {
+	if dir := filepath.Clean(expandPath(repoConfig.Dir)); !filepath.IsAbs(dir) {
+		return filepath.ToSlash(dir)
+	}
+	return repoConfig.FullDir(client.workspace)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func (client *RepoManager) exportMr(repoConfigs []*RepoConfig, w io.Writer) error {
+	for i, repoConfig := range repoConfigs {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		dir := client.exportDir(repoConfig)
+		checkout := "git clone"
+		if branch := repoConfig.Branch.Main(); branch != "" {
+			checkout += " -b " + shellQuote(branch)
+		}
+		checkout += " " + shellQuote(repoConfig.RemoteURL()) + " " + shellQuote(filepath.Base(dir))
+		if _, err := fmt.Fprintf(w, "[%s]\ncheckout = %s\n", dir, checkout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (client *RepoManager) exportVcstool(repoConfigs []*RepoConfig, w io.Writer) error {
+	file := &vcstoolFile{Repositories: make(map[string]*vcstoolRepo)}
+	for _, repoConfig := range repoConfigs {
+		file.Repositories[client.exportDir(repoConfig)] = &vcstoolRepo{
+			Type:    "git",
+			URL:     repoConfig.RemoteURL(),
+			Version: repoConfig.Branch.Main(),
+		}
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(file); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func (client *RepoManager) exportGita(repoConfigs []*RepoConfig, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	for _, repoConfig := range repoConfigs {
+		dir, err := filepath.Abs(repoConfig.FullDir(client.workspace))
+		if err != nil {
+			return err
+		}
+		if err := cw.Write([]string{dir, repoConfig.Name, "", ""}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
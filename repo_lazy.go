@@ -0,0 +1,82 @@
+package repos
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// lazyFor reports whether repoConfig is cloned on first use only, the repo's
+// own lazy setting overriding the workspace one.
+func (client *RepoManager) lazyFor(repoConfig *RepoConfig) bool {
+	if repoConfig.Lazy != nil {
+		return *repoConfig.Lazy
+	}
+	return client.config.Lazy
+}
+
+// notCloned reports whether repoConfig is a lazy repo that hasn't been
+// cloned yet.
+func (client *RepoManager) notCloned(repoConfig *RepoConfig) bool {
+	if !client.lazyFor(repoConfig) {
+		return false
+	}
+	_, err := os.Stat(repoConfig.FullDir(client.workspace))
+	return os.IsNotExist(err)
+}
+
+// cloneRepo clones repoConfig into its directory.
+func (client *RepoManager) cloneRepo(repoConfig *RepoConfig, opts CloneOptions) error {
+	if repoConfig.Url == "" {
+		return fmt.Errorf("no url configured")
+	}
+	dir := repoConfig.FullDir(client.workspace)
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+	spec := client.cloneSpec(repoConfig, opts)
+	client.logger.Debug("cloning", "repo", repoConfig.Name, "url", spec.URL, "depth", spec.Depth, "filter", spec.Filter)
+	return client.backend.Clone(spec)
+}
+
+// cloneNamed leaves the lazy repos that haven't been cloned yet out of
+// repoConfigs, except those in named, which are cloned now. The ones that
+// fail to clone are kept, for the operation to report them.
+func (client *RepoManager) cloneNamed(repoConfigs []*RepoConfig, named []string) []*RepoConfig {
+	var kept []*RepoConfig
+	for _, repoConfig := range repoConfigs {
+		if client.notCloned(repoConfig) {
+			if !contains(named, repoConfig.Name) {
+				client.logger.Debug("skipping lazy repo not cloned yet", "repo", repoConfig.Name)
+				continue
+			}
+			client.logger.Info("cloning lazy repo", "repo", repoConfig.Name)
+			if err := client.cloneRepo(repoConfig, CloneOptions{}); err != nil {
+				client.logger.Error("cloning lazy repo failed", "repo", repoConfig.Name, "error", err)
+			} else {
+				client.syncGoWork()
+			}
+		}
+		kept = append(kept, repoConfig)
+	}
+	return kept
+}
+
+// Get returns the directory of the repo name, cloning it first when it
+// isn't there yet, as lazy repos are until first used.
+func (client *RepoManager) Get(name string) (string, error) {
+	repoConfig, err := client.repoConfigOf(name)
+	if err != nil {
+		return "", err
+	}
+	dir := repoConfig.FullDir(client.workspace)
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		return dir, nil
+	}
+	client.logger.Info("cloning", "repo", name, "dir", dir)
+	if err := client.cloneRepo(repoConfig, CloneOptions{}); err != nil {
+		return "", err
+	}
+	client.syncGoWork()
+	return dir, nil
+}
@@ -0,0 +1,94 @@
+package repos
+
+import "sort"
+
+type ListOptions struct {
+	// Groups only lists repos in at least one of these groups.
+	Groups []string
+	// Only lists just the repos with these names.
+	Only []string
+}
+
+// ListEntry is a configured repo as printed by list.
+type ListEntry struct {
+	Name   string `json:"name"`
+	Dir    string `json:"dir"`
+	Url    string `json:"url"`
+	Branch string `json:"branch"`
+	// Branches lists every tracked branch, Branch being the first.
+	Branches []string `json:"branches"`
+	Groups   []string `json:"groups"`
+	Enabled  bool     `json:"enabled"`
+	// NotCloned is set for lazy repos that haven't been cloned yet.
+	NotCloned bool `json:"not_cloned,omitempty"`
+}
+
+func (opts ListOptions) matches(repoConfig *RepoConfig) bool {
+	if len(opts.Only) > 0 && !contains(opts.Only, repoConfig.Name) {
+		return false
+	}
+	if len(opts.Groups) == 0 {
+		return true
+	}
+	for _, group := range repoConfig.Groups {
+		if contains(opts.Groups, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectedRepos returns the repos batch operations work on that match opts,
+// sorted by name. Lazy repos not cloned yet are left out unless opts names
+// them.
+func (client *RepoManager) selectedRepos(opts ListOptions) []*RepoConfig {
+	var selected []*RepoConfig
+	for _, repoConfig := range client.configuredRepos() {
+		if opts.matches(repoConfig) {
+			selected = append(selected, repoConfig)
+		}
+	}
+	return client.cloneNamed(selected, opts.Only)
+}
+
+// List returns the configured repos matching opts, sorted by name, lazy
+// ones not cloned yet included.
+func (client *RepoManager) List(opts ListOptions) []*ListEntry {
+	entries := []*ListEntry{}
+	for _, repoConfig := range client.configuredRepos() {
+		if !opts.matches(repoConfig) {
+			continue
+		}
+		groups := repoConfig.Groups
+		if groups == nil {
+			groups = []string{}
+		}
+		entries = append(entries, &ListEntry{
+			Name:      repoConfig.Name,
+			Dir:       repoConfig.FullDir(client.workspace),
+			Url:       repoConfig.RemoteURL(),
+			Branch:    repoConfig.Branch.Main(),
+			Branches:  append([]string{}, repoConfig.Branch...),
+			Groups:    groups,
+			Enabled:   repoConfig.IsEnabled(),
+			NotCloned: client.notCloned(repoConfig),
+		})
+	}
+	return entries
+}
+
+// Groups returns the groups the configured repos belong to, sorted.
+func (config *ReposConfig) Groups() []string {
+	seen := make(map[string]bool)
+	var groups []string
+	for _, repoConfig := range config.Repos {
+		for _, group := range repoConfig.Groups {
+			if !seen[group] {
+				seen[group] = true
+				groups = append(groups, group)
+			}
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
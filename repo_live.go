@@ -0,0 +1,228 @@
+package repos
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// liveCommands are the batch commands that show a live table of their repos
+// with WithLiveTable.
+var liveCommands = []string{"pull", "push", "sync"}
+
+// liveMaxRows is how many repos the live table lists at most, beyond that it
+// only lists those running or failed.
+const liveMaxRows = 20
+
+// liveRefresh is how often the live table is redrawn.
+const liveRefresh = 200 * time.Millisecond
+
+// WithLiveTable makes pull, push and sync redraw a table of the state,
+// transfer progress and elapsed time of every repo in place while they run,
+// instead of printing a line per repo. It is meant for terminals only.
+func WithLiveTable(live bool) NewRepoManagerClientOptions {
+	return func(client *RepoManager) {
+		client.live = live
+	}
+}
+
+type liveRow struct {
+	name string
+	dir  string
+	// state is queued, running or the outcome.
+	state    string
+	progress string
+	started  time.Time
+	took     time.Duration
+}
+
+// liveTable is the table of a batch operation redrawn in place.
+type liveTable struct {
+	client *RepoManager
+	out    io.Writer
+	width  int
+
+	mu    sync.Mutex
+	rows  []*liveRow
+	byDir map[string]*liveRow
+	// lines is how many lines the last draw printed, to move back over.
+	lines int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (client *RepoManager) newLiveTable(repoConfigs []*RepoConfig) *liveTable {
+	table := &liveTable{
+		client: client,
+		out:    os.Stdout,
+		width:  client.nameWidth(),
+		byDir:  make(map[string]*liveRow),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	for _, repoConfig := range repoConfigs {
+		row := &liveRow{name: repoConfig.Name, dir: repoConfig.FullDir(client.workspace), state: "queued"}
+		table.rows = append(table.rows, row)
+		table.byDir[row.dir] = row
+	}
+	return table
+}
+
+// run redraws the table until close is called.
+func (table *liveTable) run() {
+	defer close(table.done)
+	ticker := time.NewTicker(liveRefresh)
+	defer ticker.Stop()
+	for {
+		table.draw()
+		select {
+		case <-ticker.C:
+		case <-table.stop:
+			table.clear()
+			return
+		}
+	}
+}
+
+// close stops redrawing and removes the table from the terminal, for the
+// summary to take its place.
+func (table *liveTable) close() {
+	close(table.stop)
+	<-table.done
+}
+
+func (table *liveTable) start(dir string) {
+	table.mu.Lock()
+	defer table.mu.Unlock()
+	if row := table.byDir[dir]; row != nil {
+		row.state, row.started = "running", time.Now()
+	}
+}
+
+func (table *liveTable) finish(dir string, result outcome) {
+	table.mu.Lock()
+	defer table.mu.Unlock()
+	if row := table.byDir[dir]; row != nil {
+		row.state, row.progress = string(result), ""
+		if !row.started.IsZero() {
+			row.took = time.Since(row.started)
+		}
+	}
+}
+
+// progressOf returns a writer taking the transfer progress of git for the
+// repo in dir, or nil when the repo isn't in the table.
+func (table *liveTable) progressOf(dir string) io.Writer {
+	table.mu.Lock()
+	defer table.mu.Unlock()
+	if table.byDir[dir] == nil {
+		return nil
+	}
+	return &liveProgress{table: table, dir: dir}
+}
+
+// liveProgress keeps the last progress line git wrote for a repo.
+type liveProgress struct {
+	table *liveTable
+	dir   string
+}
+
+func (p *liveProgress) Write(data []byte) (int, error) {
+	lines := strings.FieldsFunc(string(data), func(r rune) bool {
+		return r == '\r' || r == '\n'
+	})
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			p.table.mu.Lock()
+			if row := p.table.byDir[p.dir]; row != nil && row.state == "running" {
+				row.progress = line
+			}
+			p.table.mu.Unlock()
+			break
+		}
+	}
+	return len(data), nil
+}
+
+func (table *liveTable) stateColor(state string) string {
+	switch outcome(state) {
+	case outcomeSucceeded, outcomeUpToDate:
+		return colorGreen
+	case outcomeSkipped:
+		return colorYellow
+	case outcomeFailed:
+		return colorRed
+	}
+	return ""
+}
+
+// draw prints the table over the one drawn before.
+func (table *liveTable) draw() {
+	table.mu.Lock()
+	defer table.mu.Unlock()
+	var b strings.Builder
+	if table.lines > 0 {
+		fmt.Fprintf(&b, "\033[%dA", table.lines)
+	}
+	lines := 0
+	counts := make(map[string]int)
+	for _, row := range table.rows {
+		counts[row.state]++
+		if len(table.rows) > liveMaxRows && row.state != "running" && row.state != string(outcomeFailed) {
+			continue
+		}
+		state := fmt.Sprintf("%-10s", row.state)
+		if color := table.stateColor(row.state); color != "" {
+			state = table.client.paint(color, state)
+		}
+		elapsed := ""
+		switch {
+		case row.took > 0:
+			elapsed = row.took.Round(100 * time.Millisecond).String()
+		case !row.started.IsZero():
+			elapsed = time.Since(row.started).Round(100 * time.Millisecond).String()
+		}
+		progress := row.progress
+		if len(progress) > 40 {
+			progress = progress[:40]
+		}
+		fmt.Fprintf(&b, "\033[2K%-*s %s %-40s %s\n", table.width, row.name, state, progress, elapsed)
+		lines++
+	}
+	finished := len(table.rows) - counts["queued"] - counts["running"]
+	fmt.Fprintf(&b, "\033[2K%d/%d done, %d running, %d failed\n", finished, len(table.rows), counts["running"], counts[string(outcomeFailed)])
+	lines++
+	// Rows dropped since the last draw leave lines below to clear.
+	for i := lines; i < table.lines; i++ {
+		b.WriteString("\033[2K\n")
+	}
+	if table.lines > lines {
+		fmt.Fprintf(&b, "\033[%dA", table.lines-lines)
+	}
+	table.lines = lines
+	io.WriteString(table.out, b.String())
+}
+
+// clear removes the table from the terminal.
+func (table *liveTable) clear() {
+	table.mu.Lock()
+	defer table.mu.Unlock()
+	if table.lines == 0 {
+		return
+	}
+	fmt.Fprintf(table.out, "\033[%dA\033[J", table.lines)
+	table.lines = 0
+}
+
+// liveProgressOf returns the writer for the transfer progress of the repo in
+// dir while a live table is shown, or nil.
+func (client *RepoManager) liveProgressOf(dir string) io.Writer {
+	if table := client.table; table != nil {
+		return table.progressOf(dir)
+	}
+	return nil
+}
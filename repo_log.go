@@ -0,0 +1,94 @@
+package repos
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type LogOptions struct {
+	Since  string
+	Author string
+}
+
+type LogEntry struct {
+	Repo    string    `json:"repo"`
+	Hash    string    `json:"hash"`
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+	Subject string    `json:"subject"`
+}
+
+var sinceShorthand = regexp.MustCompile(`^(\d+)([hdwmy])$`)
+
+// gitSince turns shorthands like 7d or 2w into something git log --since
+// understands. Anything else is handed to git untouched.
+func gitSince(since string) string {
+	m := sinceShorthand.FindStringSubmatch(since)
+	if m == nil {
+		return since
+	}
+	unit := map[string]string{"h": "hours", "d": "days", "w": "weeks", "m": "months", "y": "years"}[m[2]]
+	return m[1] + "." + unit + ".ago"
+}
+
+// logEntries runs git log with args in dir and parses the commits.
+func logEntries(repo string, dir string, args ...string) ([]*LogEntry, error) {
+	out, err := runGit(dir, append(args, "--format=%H%x00%at%x00%an%x00%s")...)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*LogEntry
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\x00", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		unix, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &LogEntry{
+			Repo:    repo,
+			Hash:    fields[0],
+			Date:    time.Unix(unix, 0),
+			Author:  fields[2],
+			Subject: fields[3],
+		})
+	}
+	return entries, nil
+}
+
+// Log collects the commits of all repos and merges them newest first. Repos
+// whose log can't be read are left out and returned in a BatchError along
+// with the commits of the others.
+func (client *RepoManager) Log(opts LogOptions) ([]*LogEntry, error) {
+	client.logger.Info("collecting logs", "workspace", client.workspace)
+	entries := []*LogEntry{}
+	errs := make(map[string]error)
+	for _, repoConfig := range client.sortedRepos() {
+		args := []string{"log"}
+		if opts.Since != "" {
+			args = append(args, "--since="+gitSince(opts.Since))
+		}
+		if opts.Author != "" {
+			args = append(args, "--author="+opts.Author)
+		}
+		repoEntries, err := logEntries(repoConfig.Name, repoConfig.FullDir(client.workspace), args...)
+		if err != nil {
+			client.logger.Error("failed to read the log", "repo", repoConfig.Name, "error", err)
+			errs[repoConfig.Name] = err
+			continue
+		}
+		entries = append(entries, repoEntries...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Date.After(entries[j].Date)
+	})
+	if len(errs) > 0 {
+		return entries, &BatchError{Errors: errs}
+	}
+	return entries, nil
+}
@@ -0,0 +1,142 @@
+package repos
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+type MaintainOptions struct {
+	// Auto only runs gc when git thinks it's needed (git gc --auto).
+	Auto bool
+	// ReflogExpire is passed to git reflog expire --expire, e.g. 90.days.
+	ReflogExpire string
+}
+
+// FormatBytes shows a size in bytes with binary units, e.g. 1.5 MiB.
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
+func gitDirOf(dir string) (string, error) {
+	return gitPathOf(dir, "--git-dir")
+}
+
+// gitCommonDirOf returns the git directory shared by all worktrees of the
+// repo in dir, where its objects and refs are.
+func gitCommonDirOf(dir string) (string, error) {
+	return gitPathOf(dir, "--git-common-dir")
+}
+
+// gitPathOf returns the absolute path git rev-parse prints with the flag.
+func gitPathOf(dir string, flag string) (string, error) {
+	gitDir, err := runGit(dir, "rev-parse", flag)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(dir, gitDir)
+	}
+	return gitDir, nil
+}
+
+func (client *RepoManager) maintainSingleRepo(repoConfig *RepoConfig, opts MaintainOptions) (int64, int64, error) {
+	dir := repoConfig.FullDir(client.workspace)
+	gitDir, err := gitCommonDirOf(dir)
+	if err != nil {
+		return 0, 0, err
+	}
+	before, err := dirSize(gitDir)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	remotes, err := runGit(dir, "remote")
+	if err != nil {
+		return before, before, err
+	}
+	steps := [][]string{{"reflog", "expire", "--expire=" + opts.ReflogExpire, "--all"}}
+	if remotes != "" {
+		steps = append(steps, append([]string{"remote", "prune"}, strings.Fields(remotes)...))
+	}
+	gc := []string{"gc", "--quiet"}
+	if opts.Auto {
+		gc = append(gc, "--auto")
+	}
+	steps = append(steps, gc)
+	for _, step := range steps {
+		client.logger.Debug("running git", "repo", repoConfig.Name, "args", strings.Join(step, " "))
+		if _, err := runGit(dir, step...); err != nil {
+			return before, before, err
+		}
+	}
+
+	after, err := dirSize(gitDir)
+	return before, after, err
+}
+
+// Maintain expires reflogs, prunes stale remote-tracking branches of every
+// remote and runs git gc in every repo, printing the size of each git
+// directory before and after.
+func (client *RepoManager) Maintain(opts MaintainOptions) error {
+	client.logger.Info("maintaining", "workspace", client.workspace)
+	if opts.ReflogExpire == "" {
+		opts.ReflogExpire = "90.days"
+	}
+	max := client.nameWidth()
+	var totalBefore, totalAfter int64
+	history := client.newHistory("maintain")
+	defer history.record()
+	var failed []string
+	for _, repoConfig := range client.sortedRepos() {
+		if client.failingFast(failed) {
+			break
+		}
+		head := headOf(repoConfig.FullDir(client.workspace))
+		before, after, err := client.maintainSingleRepo(repoConfig, opts)
+		if err != nil {
+			client.printRepoLine(max, repoConfig.Name, err)
+			history.fail(repoConfig, head, err)
+			failed = append(failed, repoConfig.Name)
+			continue
+		}
+		totalBefore += before
+		totalAfter += after
+		client.printRepoLine(max, repoConfig.Name, FormatBytes(before)+" -> "+FormatBytes(after))
+		history.add(repoConfig, head, outcomeSucceeded, FormatBytes(before)+" -> "+FormatBytes(after))
+	}
+	client.printRepoLine(max, "total", FormatBytes(totalBefore)+" -> "+FormatBytes(totalAfter))
+	if len(failed) > 0 {
+		return failedIn("maintenance", failed)
+	}
+	return nil
+}
@@ -2,102 +2,179 @@ package repos
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
-	"sync"
+	"strings"
+	"text/template"
 
 	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
-	"github.com/spf13/viper"
-	cssh "golang.org/x/crypto/ssh"
 )
 
-type CommandLogger struct {
-	verbose bool
-}
+type RepoManager struct {
+	verbosity      int
+	workspace      string
+	logger         Logger
+	logFile        string
+	branchPolicy   BranchPolicy
+	detachedPolicy BranchPolicy
+	syncStrategy   SyncStrategy
+	notify         NotifyPolicy
+	errorPolicy    ErrorPolicy
+	// schedule names the daemon schedule the manager runs for.
+	schedule string
+	// review approves what push and sync publish, per repo.
+	review func(repo, preview string) bool
+	// progress receives the outcome of every repo of a batch operation.
+	progress func(ProgressEvent)
+	// lastSummary holds the outcomes of the last batch operation.
+	lastSummary *runSummary
 
-func (l *CommandLogger) Info(msg string, args ...interface{}) {
-	if l.verbose {
-		fmt.Printf(msg+"\n", args...)
-	}
+	includeDisabled bool
+	slowest         int
+	reportPath      string
+	reportFormat    string
+	skipUnchanged   bool
+	// reportTemplate is reportFormat parsed, nil for JSON reports.
+	reportTemplate *template.Template
+	// selection limits the repos batch operations work on.
+	selection ListOptions
+	color     bool
+	jobs      int
+	// live shows a live table during pull, push and sync, table is the one
+	// shown right now. It is only set while no repo is worked on.
+	live  bool
+	table *liveTable
+
+	backend GitBackend
+	config  *ReposConfig
+	// ctx stops batch operations early when it's done.
+	ctx context.Context
 }
 
-var logger *CommandLogger = &CommandLogger{}
+type NewRepoManagerClientOptions func(*RepoManager)
 
-type RepoManager struct {
-	verbose   bool
-	workspace string
+// VerbosityQuiet only reports errors.
+const VerbosityQuiet = -1
 
-	auth   *ssh.PublicKeys
-	config *ReposConfig
+// WithVerbosity sets how much is printed: VerbosityQuiet only reports errors,
+// 0 the final summary of batch operations, 1 adds a line per repo and info
+// logs, 2 debug logs and 3 the transfer progress of git.
+func WithVerbosity(level int) NewRepoManagerClientOptions {
+	return func(client *RepoManager) {
+		client.verbosity = level
+	}
 }
 
-type NewRepoManagerClientOptions func(*RepoManager)
-
+// WithVerbose is WithVerbosity(1) when verbose is set.
+//
+// Deprecated: use WithVerbosity.
 func WithVerbose(verbose bool) NewRepoManagerClientOptions {
 	return func(client *RepoManager) {
-		logger.verbose = verbose
-		client.verbose = verbose
+		if verbose {
+			client.verbosity = 1
+		}
 	}
 }
 
 func WithConfig(config *ReposConfig) NewRepoManagerClientOptions {
 	return func(client *RepoManager) {
 		client.config = config
-		client.workspace = filepath.Dir(config.CfgFile)
+		client.workspace = config.WorkspaceDir()
 	}
 }
 
-func IfRepoIsClean(dir string) bool {
-	cmd := exec.Command("git", "status", "--porcelain")
-	cmd.Dir = dir
-	var stdout bytes.Buffer
-	cmd.Stdout = &stdout
-	err := cmd.Run()
-	if err != nil {
-		panic(err)
+// WithIncludeDisabled makes batch operations include repos disabled in the config.
+func WithIncludeDisabled(includeDisabled bool) NewRepoManagerClientOptions {
+	return func(client *RepoManager) {
+		client.includeDisabled = includeDisabled
 	}
-	return stdout.Len() == 0
 }
 
-func newAuth() (*ssh.PublicKeys, error) {
-	var publicKey *ssh.PublicKeys
-	sshPath := filepath.Join(os.Getenv("HOME"), ".ssh/id_rsa")
-	publicKey, keyError := ssh.NewPublicKeysFromFile(ssh.DefaultUsername, sshPath, "")
-	if keyError != nil {
-		return nil, keyError
-	}
-	publicKey.HostKeyCallbackHelper = ssh.HostKeyCallbackHelper{
-		HostKeyCallback: cssh.InsecureIgnoreHostKey(),
+// IfRepoIsClean reports whether the working tree in dir has no changes,
+// untracked files included. Directories that aren't git repos are an error.
+func IfRepoIsClean(dir string) (bool, error) {
+	out, err := runGit(dir, "status", "--porcelain")
+	if err != nil {
+		return false, err
 	}
-	return publicKey, nil
+	return out == "", nil
 }
 
-func NewRepoManager(options ...NewRepoManagerClientOptions) (*RepoManager, error) {
-	auth, err := newAuth()
-	if err != nil {
-		return nil, err
+func runGit(dir string, args ...string) (string, error) {
+	return runGitEnv(dir, nil, args...)
+}
+
+// runGitEnv runs git like runGit with env added to its environment.
+func runGitEnv(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0"), env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
 	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
 
-	client := &RepoManager{
-		auth: auth,
+// upstreamOf returns the upstream of the current branch, e.g. origin/main,
+// or an empty string when the branch doesn't track anything.
+func upstreamOf(dir string) string {
+	upstream, err := runGit(dir, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+	if err != nil {
+		return ""
 	}
+	return upstream
+}
 
+func NewRepoManager(options ...NewRepoManagerClientOptions) (*RepoManager, error) {
+	client := &RepoManager{}
 	for _, opt := range options {
 		opt(client)
 	}
+	if client.logger == nil {
+		logger, err := newDefaultLogger(client.verbosity, client.logFile)
+		if err != nil {
+			return nil, err
+		}
+		client.logger = logger
+	}
+	switch client.reportFormat {
+	case "", "json", OutputCSV, OutputTSV:
+	default:
+		tmpl, err := FormatTemplate(client.reportFormat)
+		if err != nil {
+			return nil, fmt.Errorf("report format: %w", err)
+		}
+		client.reportTemplate = tmpl
+	}
+
+	if client.backend == nil {
+		backend, err := client.newBackend()
+		if err != nil {
+			return nil, err
+		}
+		client.backend = backend
+	}
 	return client, nil
 }
 
 func (client *RepoManager) openRepo(repoConfig *RepoConfig) (*git.Repository, error) {
-	repoPath := filepath.Join(client.workspace, repoConfig.Dir)
-	repo, err := git.PlainOpen(repoPath)
-	logger.Info("Opening %s", repoPath)
+	repoPath := repoConfig.FullDir(client.workspace)
+	repo, err := plainOpen(repoPath)
+	client.logger.Debug("opening", "dir", repoPath)
 	if err != nil {
 		return nil, err
 	}
@@ -107,134 +184,190 @@ func (client *RepoManager) openRepo(repoConfig *RepoConfig) (*git.Repository, er
 	return repo, nil
 }
 
-func (client *RepoManager) progeess() io.Writer {
-	if client.verbose {
-		return os.Stdout
+// sortedRepos returns the repos batch operations work on, sorted by name.
+// Lazy repos that haven't been cloned yet are left out.
+func (client *RepoManager) sortedRepos() []*RepoConfig {
+	return client.cloneNamed(client.configuredRepos(), nil)
+}
+
+// configuredRepos returns the enabled and selected repos sorted by name,
+// cloned or not.
+func (client *RepoManager) configuredRepos() []*RepoConfig {
+	repoConfigs := make([]*RepoConfig, 0, len(client.config.Repos))
+	for _, repoConfig := range client.config.Repos {
+		if !repoConfig.IsEnabled() && !client.includeDisabled {
+			client.logger.Debug("skipping disabled repo", "repo", repoConfig.Name)
+			continue
+		}
+		if !client.selection.matches(repoConfig) {
+			continue
+		}
+		repoConfigs = append(repoConfigs, repoConfig)
 	}
-	return nil
+	sort.Slice(repoConfigs, func(i, j int) bool {
+		return repoConfigs[i].Name < repoConfigs[j].Name
+	})
+	return repoConfigs
 }
 
-func (client *RepoManager) pullSingleRepo(repo *git.Repository) error {
-	w, err := repo.Worktree()
-	if err != nil {
-		return err
+func (client *RepoManager) nameWidth() int {
+	max := 22
+	for repoName := range client.config.Repos {
+		if len(repoName) > max {
+			max = len(repoName) + 2
+		}
 	}
+	return max
+}
 
-	err = w.Pull(&git.PullOptions{RemoteName: "origin", Auth: client.auth, Progress: client.progeess()})
-	if errors.Is(err, git.NoErrAlreadyUpToDate) {
-		return nil
+func (client *RepoManager) printRepoLine(width int, name string, value interface{}) {
+	if client.verbosity <= VerbosityQuiet {
+		return
+	}
+	if err, ok := value.(error); ok {
+		value = client.paint(colorRed, err)
 	}
-	return err
+	fmt.Printf("%-"+strconv.Itoa(width)+"s %v\n", name, value)
 }
 
-func (client *RepoManager) Pull() error {
-	logger.Info("Pulling all in workspace %s", client.workspace)
-	fn := func(repoConfig *RepoConfig) error {
-		logger.Info("Pulling %s %s", repoConfig.Name, repoConfig.Dir)
-		repo, err := client.openRepo(repoConfig)
-		if err != nil {
-			return err
-		}
-		err = client.pullSingleRepo(repo)
-		if err != nil {
-			return err
-		}
-		return nil
+// printRepoDetail prints a per repo line of a batch operation, which only
+// shows up in verbose mode.
+func (client *RepoManager) printRepoDetail(width int, name string, value interface{}) {
+	if client.verbosity < 1 {
+		return
 	}
+	client.printRepoLine(width, name, client.paintResult(value))
+}
 
-	for _, repoConfig := range client.config.Repos {
-		err := fn(repoConfig)
-		if err != nil {
-			return err
-		}
+func (client *RepoManager) progeess() io.Writer {
+	if client.verbosity >= 3 {
+		return os.Stdout
 	}
 	return nil
 }
 
-func (client *RepoManager) pushSingleRepo(repo *git.Repository) error {
-	err := repo.Push(&git.PushOptions{RemoteName: "origin", Auth: client.auth, Progress: client.progeess()})
-	if errors.Is(err, git.NoErrAlreadyUpToDate) {
-		return nil
+// prepareRepo checks whether a repo can be pulled or pushed. A non empty
+// reason means the repo must be skipped.
+func (client *RepoManager) prepareRepo(repoConfig *RepoConfig, needClean bool) (string, error) {
+	dir := repoConfig.FullDir(client.workspace)
+	client.logger.Debug("opening", "dir", dir)
+	if err := client.backend.Open(dir); err != nil {
+		return "", err
+	}
+	status, err := client.backend.Status(dir)
+	if err != nil {
+		return "", err
+	}
+	// Untracked files don't get in the way of a pull.
+	if needClean && status.Changed {
+		return "dirty", nil
 	}
-	return err
+	return client.ensureBranch(repoConfig, status)
 }
 
-func (client *RepoManager) Push() error {
-	logger.Info("Pushing all in workspace %s", client.workspace)
-	wg := sync.WaitGroup{}
-	for _, repoConfig := range client.config.Repos {
-		wg.Add(1)
-		go func(repoConfig *RepoConfig) error {
-			logger.Info("Pushing %s", repoConfig.Name)
-			repo, err := client.openRepo(repoConfig)
-			if err != nil {
-				wg.Done()
-				return err
+func (client *RepoManager) Pull() error {
+	client.logger.Info("pulling", "workspace", client.workspace)
+	repoConfigs := client.sortedRepos()
+	shared := client.sharingRepos(repoConfigs)
+	fetches := &fetchOnce{}
+	skipUnchanged := client.skipUnchanged || client.config.SkipUnchanged
+	var cache *refCache
+	if skipUnchanged {
+		cache = loadRefCache(client.workspace)
+		defer func() {
+			if err := cache.save(); err != nil {
+				client.logger.Warn("saving the ref cache failed", "error", err)
 			}
-			err = client.pushSingleRepo(repo)
-			wg.Done()
-			return err
-		}(repoConfig)
+		}()
 	}
-	wg.Wait()
-	return nil
-}
-
-func (client *RepoManager) Sync() error {
-	logger.Info("Syncing all in workspace %s", client.workspace)
-	wg := sync.WaitGroup{}
-	for _, repoDir := range client.config.Repos {
-		if !IfRepoIsClean(repoDir.FullDir(client.workspace)) {
-			return fmt.Errorf("%s is not clean", repoDir.FullDir(client.workspace))
+	return client.runBatch("pull", repoConfigs, func(repoConfig *RepoConfig) (outcome, string, error) {
+		client.logger.Info("pulling", "repo", repoConfig.Name, "dir", repoConfig.Dir)
+		if client.mirrorFor(repoConfig) {
+			return client.updateMirror(repoConfig)
+		}
+		reason, err := client.prepareRepo(repoConfig, true)
+		if reason != "" || err != nil {
+			return outcomeSkipped, reason, err
 		}
-		wg.Add(1)
-		go func(repoConfig *RepoConfig) error {
-			logger.Info("Syncing %s", repoConfig.Name)
-			repo, err := client.openRepo(repoConfig)
+		dir := repoConfig.FullDir(client.workspace)
+		var state *refState
+		if skipUnchanged {
+			status, err := client.backend.Status(dir)
 			if err != nil {
-				wg.Done()
-				return err
+				return outcomeFailed, "", err
 			}
-			err = client.pullSingleRepo(repo)
-			if err != nil {
-				wg.Done()
-				return err
+			var unchanged bool
+			if unchanged, state = client.unchangedSince(repoConfig, status, cache); unchanged {
+				client.logger.Info("remote unchanged", "repo", repoConfig.Name)
+				return outcomeUpToDate, "", nil
 			}
-			err = client.pushSingleRepo(repo)
-			if err != nil {
-				wg.Done()
-				return err
-			}
-			wg.Done()
-			logger.Info("Synced %s", repoConfig.Name)
-			return nil
-		}(repoDir)
-	}
-	wg.Wait()
-	return nil
+		}
+		var upToDate bool
+		if gitDir, ok := shared[repoConfig.Name]; ok {
+			upToDate, err = client.pullSharedRepo(repoConfig, gitDir, fetches)
+		} else {
+			upToDate, err = client.backend.Pull(dir, client.singleBranchFor(repoConfig))
+		}
+		if err != nil {
+			return outcomeFailed, "", explainConflict(dir, err)
+		}
+		if state != nil {
+			recordPulled(dir, state, cache, repoConfig.Name)
+		}
+		if upToDate {
+			return outcomeUpToDate, "", err
+		}
+		return outcomeSucceeded, "", err
+	})
 }
 
-func (client *RepoManager) Status() error {
-	logger.Info("Statusing all in workspace %s", client.workspace)
-	max := 22
-	for repoName := range client.config.Repos {
-		if len(repoName) > max {
-			max = len(repoName) + 2
+func (client *RepoManager) Sync() error {
+	client.logger.Info("syncing", "workspace", client.workspace)
+	return client.runBatch("sync", client.sortedRepos(), func(repoConfig *RepoConfig) (outcome, string, error) {
+		client.logger.Info("syncing", "repo", repoConfig.Name)
+		if client.mirrorFor(repoConfig) {
+			return client.updateMirror(repoConfig)
 		}
-	}
-	for _, repoConfig := range client.config.Repos {
-		logger.Info("Statusing %s", repoConfig.Name)
-		clean := IfRepoIsClean(repoConfig.FullDir(client.workspace))
-		fmt.Printf("%-"+strconv.Itoa(max)+"s %-4v\n", repoConfig.Name, clean)
-	}
-	return nil
+		reason, err := client.prepareRepo(repoConfig, true)
+		if reason != "" || err != nil {
+			return outcomeSkipped, reason, err
+		}
+		if !repoConfig.IsReadOnly() {
+			if ok, err := client.reviewed(repoConfig); !ok || err != nil {
+				return outcomeSkipped, reasonDeclined, err
+			}
+		}
+		upToDate, err := client.syncSingleRepo(repoConfig)
+		if err != nil {
+			return outcomeFailed, "", err
+		}
+		client.logger.Info("synced", "repo", repoConfig.Name)
+		if repoConfig.IsReadOnly() {
+			reason = reasonNotPushed
+		}
+		if upToDate {
+			return outcomeUpToDate, reason, nil
+		}
+		return outcomeSucceeded, reason, nil
+	})
 }
 
-func (client *RepoManager) Add(repoPath string, dept int) error {
+type AddOptions struct {
+	// CreateRemote names the provider to create the remote repository on
+	// when a repo has no origin yet.
+	CreateRemote string
+	// Private makes created remote repositories private.
+	Private bool
+	// File is the included config file to write the repos to instead of
+	// the main one.
+	File string
+}
+
+func (client *RepoManager) Add(repoPath string, dept int, opts AddOptions) error {
 	if dept < 0 {
 		return nil
 	}
-	logger.Info("Adding %s to workspace %s", repoPath, client.workspace)
+	client.logger.Info("adding", "path", repoPath, "workspace", client.workspace)
 	dir, err := filepath.Rel(client.workspace, repoPath)
 	if err != nil {
 		return err
@@ -243,43 +376,67 @@ func (client *RepoManager) Add(repoPath string, dept int) error {
 		Name: filepath.Base(repoPath),
 		Dir:  dir,
 	}
+	if opts.File != "" {
+		file, err := client.config.IncludedFile(opts.File)
+		if err != nil {
+			return err
+		}
+		repoConfig.SetSource(file)
+	}
 	repo, err := client.openRepo(repoConfig)
 	if errors.Is(err, git.ErrRepositoryNotExists) {
+		if dept == 0 {
+			client.logger.Warn("skipping directory that isn't a git repository", "dir", repoPath)
+			return nil
+		}
 		files, err := os.ReadDir(repoPath)
 		if err != nil {
 			return err
 		}
 		for _, file := range files {
-			if file.IsDir() {
-				if err := client.Add(filepath.Join(repoPath, file.Name()), dept-1); err != nil {
+			if file.IsDir() && !client.ignoredDir(filepath.Join(repoPath, file.Name())) {
+				if err := client.Add(filepath.Join(repoPath, file.Name()), dept-1, opts); err != nil {
 					return err
 				}
 			}
 		}
 	} else if err == nil {
 		if _, err := repo.Branch("main"); err == nil {
-			repoConfig.Branch = "main"
+			repoConfig.Branch = Branches{"main"}
 		} else if _, err := repo.Branch("master"); err == nil {
-			repoConfig.Branch = "master"
+			repoConfig.Branch = Branches{"master"}
 		}
 		origin, err := repo.Remote("origin")
-		if err != nil {
+		if errors.Is(err, git.ErrRemoteNotFound) && opts.CreateRemote != "" {
+			if repoConfig.Url, err = client.publish(repoConfig, repoPath, opts); err != nil {
+				return err
+			}
+		} else if errors.Is(err, git.ErrRemoteNotFound) {
+			return fmt.Errorf("%s has no origin, create one with --create-remote", repoPath)
+		} else if err != nil {
 			return err
+		} else {
+			repoConfig.Url = origin.Config().URLs[0]
 		}
-		repoConfig.Url = origin.Config().URLs[0]
 		client.config.Repos[repoConfig.Name] = repoConfig
-		viper.Set("repos", client.config.Repos)
-		logger.Info("Added %s to workspace %s", repoPath, client.workspace)
+		client.logger.Info("added", "path", repoPath, "workspace", client.workspace)
 	} else {
-		panic(err)
+		return fmt.Errorf("%s: %w", repoPath, err)
+	}
+	if err := client.config.Save(); err != nil {
+		return err
 	}
-	return viper.WriteConfig()
+	client.syncGoWork()
+	return nil
 }
 
 func (client *RepoManager) Remove(repoPath string) error {
-	logger.Info("Removing %s from workspace %s", repoPath, client.workspace)
+	client.logger.Info("removing", "path", repoPath, "workspace", client.workspace)
 	repoName := filepath.Base(repoPath)
 	delete(client.config.Repos, repoName)
-	viper.Set("repos", client.config.Repos)
-	return viper.WriteConfig()
+	if err := client.config.Save(); err != nil {
+		return err
+	}
+	client.syncGoWork()
+	return nil
 }
@@ -0,0 +1,52 @@
+package repos
+
+import (
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// MessageData are the variables of the commit message templates of the
+// commands committing in batch, next to those of the command. The Message
+// option of such a command is a text/template executed once per repo, so
+// every commit can name its repo, branch or ticket.
+type MessageData struct {
+	RepoName string
+	// Branch is the checked out branch, empty with a detached HEAD.
+	Branch string
+	// Date is today's date, e.g. 2023-06-01.
+	Date string
+	// TicketFromBranch is the issue key in the name of the branch, such as
+	// ABC-123 of feature/ABC-123-login, or empty when there is none.
+	TicketFromBranch string
+}
+
+// ticketPattern matches issue keys in branch names, as Jira and similar
+// trackers write them.
+var ticketPattern = regexp.MustCompile(`[A-Z][A-Z0-9]+-[0-9]+`)
+
+// parseMessage parses a commit message template, failing on variables that
+// don't exist when executed rather than writing <no value>.
+func parseMessage(message string) (*template.Template, error) {
+	return template.New("message").Option("missingkey=error").Parse(message)
+}
+
+// executeMessage renders the commit message of a repo.
+func executeMessage(tmpl *template.Template, data any) (string, error) {
+	var message strings.Builder
+	if err := tmpl.Execute(&message, data); err != nil {
+		return "", err
+	}
+	return message.String(), nil
+}
+
+func (client *RepoManager) messageData(repoConfig *RepoConfig) MessageData {
+	branch, _ := runGit(repoConfig.FullDir(client.workspace), "symbolic-ref", "--short", "--quiet", "HEAD")
+	return MessageData{
+		RepoName:         repoConfig.Name,
+		Branch:           branch,
+		Date:             time.Now().Format("2006-01-02"),
+		TicketFromBranch: ticketPattern.FindString(branch),
+	}
+}
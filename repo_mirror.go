@@ -0,0 +1,36 @@
+package repos
+
+// mirrorFor tells whether a repo is kept as a bare mirror of origin, which is
+// only ever fetched and has no working tree to check.
+func (client *RepoManager) mirrorFor(repoConfig *RepoConfig) bool {
+	if repoConfig.Mirror != nil {
+		return *repoConfig.Mirror
+	}
+	return client.config.Mirror
+}
+
+// updateMirror fetches every ref of origin into a mirror, pruning the ones
+// deleted there, and reports whether nothing changed.
+func (client *RepoManager) updateMirror(repoConfig *RepoConfig) (outcome, string, error) {
+	dir := repoConfig.FullDir(client.workspace)
+	client.logger.Debug("fetching mirror", "repo", repoConfig.Name, "dir", dir)
+	if err := client.backend.Open(dir); err != nil {
+		return outcomeSkipped, "", err
+	}
+	before, err := runGit(dir, "for-each-ref", "--format=%(objectname) %(refname)")
+	if err != nil {
+		return outcomeFailed, "", err
+	}
+	cli := client.gitCLI()
+	if _, err := cli.originGit(dir, "fetch", "--prune", "origin"); err != nil {
+		return outcomeFailed, "", err
+	}
+	after, err := runGit(dir, "for-each-ref", "--format=%(objectname) %(refname)")
+	if err != nil {
+		return outcomeFailed, "", err
+	}
+	if before == after {
+		return outcomeUpToDate, "", nil
+	}
+	return outcomeSucceeded, "", nil
+}
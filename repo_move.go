@@ -0,0 +1,126 @@
+package repos
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type RenameOptions struct {
+	// MoveDir also renames the directory to the new name.
+	MoveDir bool
+}
+
+type MoveOptions struct {
+	// ConfigOnly updates the config without moving the directory, e.g. when
+	// it has been moved already.
+	ConfigOnly bool
+}
+
+func (client *RepoManager) repoConfigOf(name string) (*RepoConfig, error) {
+	repoConfig, ok := client.config.Repos[name]
+	if !ok {
+		return nil, fmt.Errorf("repo %s is not configured", name)
+	}
+	return repoConfig, nil
+}
+
+// configDir returns how dir is written to the config: relative to the
+// workspace when it is inside of it.
+func (client *RepoManager) configDir(dir string) string {
+	rel, err := filepath.Rel(client.workspace, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return dir
+	}
+	return rel
+}
+
+// relocate points repoConfig to newDir, moving the directory first unless
+// configOnly is set. The move is undone when the config can't be saved.
+func (client *RepoManager) relocate(repoConfig *RepoConfig, newDir string, configOnly bool, save func() error) error {
+	oldDir := repoConfig.FullDir(client.workspace)
+	if !configOnly && oldDir != newDir {
+		if _, err := os.Stat(newDir); err == nil {
+			return fmt.Errorf("%s already exists", newDir)
+		}
+		if err := os.MkdirAll(filepath.Dir(newDir), 0755); err != nil {
+			return err
+		}
+		client.logger.Info("moving", "repo", repoConfig.Name, "from", oldDir, "to", newDir)
+		if err := os.Rename(oldDir, newDir); err != nil {
+			return err
+		}
+	}
+	oldConfigDir := repoConfig.Dir
+	repoConfig.Dir = client.configDir(newDir)
+	if err := save(); err != nil {
+		repoConfig.Dir = oldConfigDir
+		if !configOnly && oldDir != newDir {
+			if undoErr := os.Rename(newDir, oldDir); undoErr != nil {
+				return fmt.Errorf("%w, and moving %s back failed: %v", err, newDir, undoErr)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// Rename renames a repo in the config, keeping the rest of its entry and
+// updating the after lists that refer to it.
+func (client *RepoManager) Rename(oldName string, newName string, opts RenameOptions) error {
+	client.logger.Info("renaming", "repo", oldName, "to", newName)
+	repoConfig, err := client.repoConfigOf(oldName)
+	if err != nil {
+		return err
+	}
+	if _, ok := client.config.Repos[newName]; ok {
+		return fmt.Errorf("repo %s is configured already", newName)
+	}
+
+	save := func() error {
+		delete(client.config.Repos, oldName)
+		client.config.Repos[newName] = repoConfig
+		repoConfig.Name = newName
+		renamed := make(map[*RepoConfig][]string)
+		for _, other := range client.config.Repos {
+			for i, dep := range other.After {
+				if dep == oldName {
+					renamed[other] = other.After
+					other.After = append([]string{}, other.After...)
+					other.After[i] = newName
+				}
+			}
+		}
+		err := client.config.Save()
+		if err != nil {
+			delete(client.config.Repos, newName)
+			client.config.Repos[oldName] = repoConfig
+			repoConfig.Name = oldName
+			for other, after := range renamed {
+				other.After = after
+			}
+		}
+		return err
+	}
+
+	newDir := repoConfig.FullDir(client.workspace)
+	if opts.MoveDir {
+		newDir = filepath.Join(filepath.Dir(newDir), newName)
+	}
+	return client.relocate(repoConfig, newDir, !opts.MoveDir, save)
+}
+
+// Move points a repo to another directory and moves the directory there.
+func (client *RepoManager) Move(name string, newDir string, opts MoveOptions) error {
+	client.logger.Info("moving", "repo", name, "to", newDir)
+	repoConfig, err := client.repoConfigOf(name)
+	if err != nil {
+		return err
+	}
+	newDir = expandPath(newDir)
+	if !filepath.IsAbs(newDir) {
+		newDir = filepath.Join(client.workspace, newDir)
+	}
+	return client.relocate(repoConfig, filepath.Clean(newDir), opts.ConfigOnly, client.config.Save)
+}
@@ -0,0 +1,117 @@
+package repos
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// nestedConfigNames are the names of config files in workspace
+// subdirectories that add or override repos for that subtree.
+var nestedConfigNames = []string{".repos.yaml", ".repos.yml"}
+
+// nestedConfigDepth is how many directory levels below the workspace are
+// searched for nested config files.
+const nestedConfigDepth = 4
+
+// findNestedConfigs returns the nested config files below root, skipping
+// hidden directories and not descending into repos.
+func findNestedConfigs(root string, depth int) ([]string, error) {
+	var found []string
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		if isGitRepo(dir) {
+			continue
+		}
+		for _, name := range nestedConfigNames {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				found = append(found, filepath.Join(dir, name))
+				break
+			}
+		}
+		if depth > 1 {
+			nested, err := findNestedConfigs(dir, depth-1)
+			if err != nil {
+				return nil, err
+			}
+			found = append(found, nested...)
+		}
+	}
+	return found, nil
+}
+
+// isAncestorDir reports whether dir is above sub.
+func isAncestorDir(dir, sub string) bool {
+	rel, err := filepath.Rel(dir, sub)
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..")
+}
+
+// LoadNested merges the repos of .repos.yaml files in subdirectories of the
+// workspace into the config. Their dirs are relative to the directory of
+// the file. A nested file overrides the repos of the same name defined by
+// the main config or a nested file further up its path, other nested files
+// defining one is an error. Save writes the repos back to the file they came
+// from.
+func (config *ReposConfig) LoadNested() error {
+	workspace := config.WorkspaceDir()
+	files, err := findNestedConfigs(workspace, nestedConfigDepth)
+	if err != nil {
+		return err
+	}
+	depthOf := func(file string) int {
+		return strings.Count(filepath.ToSlash(file), "/")
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return depthOf(files[i]) < depthOf(files[j])
+	})
+
+	loaded := make(map[string]bool)
+	for _, included := range config.includes {
+		if abs, err := filepath.Abs(included.path); err == nil {
+			loaded[abs] = true
+		}
+	}
+	definedAt := make(map[string]string)
+	for _, file := range files {
+		if abs, err := filepath.Abs(file); err == nil && loaded[abs] {
+			continue
+		}
+		nested, err := readIncludedConfig(file)
+		if err != nil {
+			return err
+		}
+		dir, err := filepath.Rel(workspace, filepath.Dir(file))
+		if err != nil {
+			return err
+		}
+		config.includes = append(config.includes, &includedFile{path: file, dir: dir})
+		for name, repoConfig := range nested.Repos {
+			if other, ok := definedAt[name]; ok && !isAncestorDir(filepath.Dir(other), filepath.Dir(file)) {
+				return fmt.Errorf("repo %s is defined in both %s and %s", name, other, file)
+			}
+			definedAt[name] = file
+			if repoDir := expandPath(repoConfig.Dir); !filepath.IsAbs(repoDir) {
+				repoConfig.Dir = filepath.Join(dir, repoConfig.Dir)
+			}
+			repoConfig.source = file
+			if shadowed, ok := config.Repos[name]; ok {
+				shadowed.Name = name
+				config.shadowed = append(config.shadowed, shadowed)
+			}
+			if config.Repos == nil {
+				config.Repos = make(map[string]*RepoConfig)
+			}
+			config.Repos[name] = repoConfig
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package repos
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// netrcEntry is a machine, or the default, of a .netrc file.
+type netrcEntry struct {
+	machine  string
+	login    string
+	password string
+}
+
+// netrcPath returns the .netrc file curl and git read: NETRC when set,
+// otherwise ~/.netrc, or ~/_netrc on Windows when only that exists.
+func netrcPath() string {
+	if path := os.Getenv("NETRC"); path != "" {
+		return expandPath(path)
+	}
+	home := homeDir()
+	if home == "" {
+		return ""
+	}
+	path := filepath.Join(home, ".netrc")
+	if runtime.GOOS == "windows" {
+		if _, err := os.Stat(path); err != nil {
+			return filepath.Join(home, "_netrc")
+		}
+	}
+	return path
+}
+
+// parseNetrc reads the machine and default entries of a .netrc file. Macro
+// definitions are skipped.
+func parseNetrc(data string) []*netrcEntry {
+	var entries []*netrcEntry
+	var entry *netrcEntry
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		for j := 0; j < len(fields); j++ {
+			value := func() string {
+				if j+1 < len(fields) {
+					j++
+					return fields[j]
+				}
+				return ""
+			}
+			switch fields[j] {
+			case "machine":
+				entry = &netrcEntry{machine: value()}
+				entries = append(entries, entry)
+			case "default":
+				entry = &netrcEntry{}
+				entries = append(entries, entry)
+			case "login":
+				if entry != nil {
+					entry.login = value()
+				}
+			case "password":
+				if entry != nil {
+					entry.password = value()
+				}
+			case "account":
+				value()
+			case "macdef":
+				// The macro runs until the next empty line.
+				for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+					i++
+				}
+				j = len(fields)
+			}
+		}
+	}
+	return entries
+}
+
+// netrcCredential returns the login and password in the .netrc file for
+// host, falling back to its default entry.
+func netrcCredential(host string) (string, string, bool) {
+	path := netrcPath()
+	if path == "" {
+		return "", "", false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", false
+	}
+	var fallback *netrcEntry
+	for _, entry := range parseNetrc(string(data)) {
+		switch {
+		case entry.machine == host && entry.password != "":
+			return entry.login, entry.password, true
+		case entry.machine == "" && fallback == nil:
+			fallback = entry
+		}
+	}
+	if fallback != nil && fallback.password != "" {
+		return fallback.login, fallback.password, true
+	}
+	return "", "", false
+}
@@ -0,0 +1,115 @@
+package repos
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// NotifyPolicy decides when a batch operation ends with a desktop
+// notification.
+type NotifyPolicy string
+
+const (
+	// NotifyNever doesn't send notifications.
+	NotifyNever NotifyPolicy = "never"
+	// NotifyAlways sends a notification after every batch operation.
+	NotifyAlways NotifyPolicy = "always"
+	// NotifyFailure only sends a notification when some repos failed.
+	NotifyFailure NotifyPolicy = "failure"
+)
+
+func ParseNotifyPolicy(s string) (NotifyPolicy, error) {
+	switch policy := NotifyPolicy(s); policy {
+	case "", NotifyNever, NotifyAlways, NotifyFailure:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid notify policy %q, must be one of %s, %s or %s", s, NotifyNever, NotifyAlways, NotifyFailure)
+}
+
+// WithNotify sets when to send desktop notifications, overriding the notify
+// config setting.
+func WithNotify(policy NotifyPolicy) NewRepoManagerClientOptions {
+	return func(client *RepoManager) {
+		client.notify = policy
+	}
+}
+
+func (client *RepoManager) notifyPolicy() NotifyPolicy {
+	if client.notify != "" {
+		return client.notify
+	}
+	if client.config.Notify != "" {
+		return client.config.Notify
+	}
+	return NotifyNever
+}
+
+// notifyResult sends a desktop notification summarizing a batch operation
+// when the notify policy asks for it. Failing to notify is only logged.
+func (client *RepoManager) notifyResult(name string, summary *runSummary) {
+	failed := summary.failed()
+	switch client.notifyPolicy() {
+	case NotifyAlways:
+	case NotifyFailure:
+		if len(failed) == 0 {
+			return
+		}
+	default:
+		return
+	}
+	title := "repos " + name
+	message := fmt.Sprintf("%d succeeded, %d up-to-date, %d skipped, %d failed",
+		summary.count(outcomeSucceeded), summary.count(outcomeUpToDate), summary.count(outcomeSkipped), len(failed))
+	if len(failed) > 0 {
+		title += " failed"
+		message += ": " + strings.Join(failed, ", ")
+	}
+	if err := desktopNotify(title, message); err != nil {
+		client.logger.Warn("sending the desktop notification failed", "error", err)
+	}
+}
+
+// desktopNotify shows a notification with osascript on macOS, notify-send on
+// Linux and a PowerShell balloon tip on Windows, which is not waited for.
+func desktopNotify(title, message string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(message), appleScriptString(title))
+		cmd = exec.Command("osascript", "-e", script)
+	case "windows":
+		script := fmt.Sprintf(`Add-Type -AssemblyName System.Windows.Forms
+$icon = New-Object System.Windows.Forms.NotifyIcon
+$icon.Icon = [System.Drawing.SystemIcons]::Information
+$icon.Visible = $true
+$icon.ShowBalloonTip(10000, %s, %s, [System.Windows.Forms.ToolTipIcon]::None)
+Start-Sleep -Seconds 10
+$icon.Dispose()`, powerShellString(title), powerShellString(message))
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+		// The balloon tip only shows while PowerShell runs, so it is left
+		// running on its own rather than holding up the command.
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("%s: %w", cmd.Path, err)
+		}
+		return cmd.Process.Release()
+	default:
+		cmd = exec.Command("notify-send", "--app-name=repos", title, message)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s: %s", cmd.Path, msg)
+		}
+		return fmt.Errorf("%s: %w", cmd.Path, err)
+	}
+	return nil
+}
+
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func powerShellString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,76 @@
+package repos
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// fuzzyScore matches query against s as a subsequence, ignoring case. The
+// score is higher for matches at the start, after separators and in runs of
+// consecutive characters, and lower for gaps.
+func fuzzyScore(query, s string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+	q := []rune(strings.ToLower(query))
+	runes := []rune(s)
+	score, qi, last := 0, 0, -1
+	for i, r := range runes {
+		if qi == len(q) {
+			break
+		}
+		if unicode.ToLower(r) != q[qi] {
+			continue
+		}
+		switch {
+		case i == 0:
+			score += 8
+		case strings.ContainsRune("/-_. ", runes[i-1]):
+			score += 6
+		case unicode.IsUpper(r) && unicode.IsLower(runes[i-1]):
+			score += 4
+		}
+		if last >= 0 && i == last+1 {
+			score += 5
+		} else if last >= 0 {
+			score -= i - last - 1
+		}
+		score++
+		last = i
+		qi++
+	}
+	return score, qi == len(q)
+}
+
+// FuzzyFilter returns the entries whose name or directory fuzzily matches
+// query, best matches first. An empty query keeps every entry in order.
+func FuzzyFilter(query string, entries []*ListEntry) []*ListEntry {
+	if query == "" {
+		return entries
+	}
+	type scored struct {
+		entry *ListEntry
+		score int
+	}
+	var matches []scored
+	for _, entry := range entries {
+		nameScore, nameOK := fuzzyScore(query, entry.Name)
+		dirScore, dirOK := fuzzyScore(query, entry.Dir)
+		switch {
+		case nameOK && (!dirOK || nameScore >= dirScore):
+			// Matching the name counts more than matching the directory.
+			matches = append(matches, scored{entry, nameScore + 2})
+		case dirOK:
+			matches = append(matches, scored{entry, dirScore})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	filtered := make([]*ListEntry, len(matches))
+	for i, match := range matches {
+		filtered[i] = match.entry
+	}
+	return filtered
+}
@@ -0,0 +1,276 @@
+package repos
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type PullRequestOptions struct {
+	Select ListOptions
+	// Branch is the branch to open pull requests for, the checked out
+	// branch of each repo when empty.
+	Branch string
+	// Base is the branch to merge into, the configured or default branch of
+	// each repo when empty.
+	Base  string
+	Title string
+	Body  string
+	Draft bool
+}
+
+// PullRequest is a pull request, or merge request on GitLab, of a repo.
+type PullRequest struct {
+	Repo   string `json:"repo"`
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Branch string `json:"branch"`
+	Base   string `json:"base"`
+	Author string `json:"author"`
+	Draft  bool   `json:"draft"`
+	// Review and Checks are the review and CI states, set when listing.
+	Review string `json:"review,omitempty"`
+	Checks string `json:"checks,omitempty"`
+}
+
+// githubPull is a pull request as the GitHub and Gitea APIs return it.
+type githubPull struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	HTMLURL string `json:"html_url"`
+	Draft   bool   `json:"draft"`
+	Head    struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+}
+
+func (pull *githubPull) pullRequest() *PullRequest {
+	return &PullRequest{Number: pull.Number, Title: pull.Title, URL: pull.HTMLURL, Branch: pull.Head.Ref,
+		Base: pull.Base.Ref, Author: pull.User.Login, Draft: pull.Draft || strings.HasPrefix(pull.Title, "WIP:")}
+}
+
+// gitlabMergeRequest is a merge request as the GitLab API returns it.
+type gitlabMergeRequest struct {
+	IID          int    `json:"iid"`
+	Title        string `json:"title"`
+	WebURL       string `json:"web_url"`
+	Draft        bool   `json:"draft"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+	Author       struct {
+		Username string `json:"username"`
+	} `json:"author"`
+}
+
+func (mr *gitlabMergeRequest) pullRequest() *PullRequest {
+	return &PullRequest{Number: mr.IID, Title: mr.Title, URL: mr.WebURL, Branch: mr.SourceBranch,
+		Base: mr.TargetBranch, Author: mr.Author.Username, Draft: mr.Draft}
+}
+
+// findPullRequest returns the open pull request from branch into base of
+// project, nil when there is none.
+func (provider *ProviderConfig) findPullRequest(project, branch, base string) (*PullRequest, error) {
+	switch provider.Type {
+	case "github", "gitea":
+		prefix, query := "", url.Values{"state": {"open"}, "base": {base}}
+		if provider.Type == "gitea" {
+			prefix = "/api/v1"
+		} else {
+			owner, _, _ := strings.Cut(project, "/")
+			query.Set("head", owner+":"+branch)
+		}
+		var pulls []githubPull
+		if err := provider.call(http.MethodGet, prefix+"/repos/"+project+"/pulls?"+query.Encode(), nil, &pulls); err != nil {
+			return nil, err
+		}
+		for _, pull := range pulls {
+			if pull.Head.Ref == branch && pull.Base.Ref == base {
+				return pull.pullRequest(), nil
+			}
+		}
+		return nil, nil
+	case "gitlab":
+		query := url.Values{"state": {"opened"}, "source_branch": {branch}, "target_branch": {base}}
+		var mrs []gitlabMergeRequest
+		if err := provider.call(http.MethodGet, "/api/v4/projects/"+url.PathEscape(project)+"/merge_requests?"+query.Encode(), nil, &mrs); err != nil {
+			return nil, err
+		}
+		if len(mrs) > 0 {
+			return mrs[0].pullRequest(), nil
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown provider type %q, must be github, gitlab or gitea", provider.Type)
+}
+
+// createPullRequest opens a pull request from branch into base of project.
+func (provider *ProviderConfig) createPullRequest(project, branch, base string, opts PullRequestOptions) (*PullRequest, error) {
+	switch provider.Type {
+	case "github", "gitea":
+		prefix, title := "", opts.Title
+		in := map[string]interface{}{"head": branch, "base": base, "body": opts.Body}
+		if provider.Type == "gitea" {
+			// Gitea marks drafts by the title.
+			prefix = "/api/v1"
+			if opts.Draft {
+				title = "WIP: " + title
+			}
+		} else {
+			in["draft"] = opts.Draft
+		}
+		in["title"] = title
+		var pull githubPull
+		if err := provider.call(http.MethodPost, prefix+"/repos/"+project+"/pulls", in, &pull); err != nil {
+			return nil, err
+		}
+		return pull.pullRequest(), nil
+	case "gitlab":
+		title := opts.Title
+		if opts.Draft {
+			title = "Draft: " + title
+		}
+		in := map[string]interface{}{"source_branch": branch, "target_branch": base, "title": title, "description": opts.Body}
+		var mr gitlabMergeRequest
+		if err := provider.call(http.MethodPost, "/api/v4/projects/"+url.PathEscape(project)+"/merge_requests", in, &mr); err != nil {
+			return nil, err
+		}
+		return mr.pullRequest(), nil
+	}
+	return nil, fmt.Errorf("unknown provider type %q, must be github, gitlab or gitea", provider.Type)
+}
+
+// baseOf is the branch pull requests of the repo merge into unless told
+// otherwise: the configured branch, or else the default branch of origin.
+// Unlike defaultBranchOf it never falls back to the checked out branch,
+// which is usually the one to open the pull request for.
+func (client *RepoManager) baseOf(repoConfig *RepoConfig, dir string) string {
+	if branch := repoConfig.Branch.Main(); branch != "" {
+		return branch
+	}
+	if ref, err := runGit(dir, "symbolic-ref", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		return strings.TrimPrefix(ref, "origin/")
+	}
+	out, err := client.gitCLI().originGit(dir, "ls-remote", "--symref", "origin", "HEAD")
+	if err != nil {
+		return ""
+	}
+	// ref: refs/heads/main	HEAD
+	for _, line := range strings.Split(out, "\n") {
+		if ref, ok := strings.CutPrefix(line, "ref: refs/heads/"); ok {
+			branch, _, _ := strings.Cut(ref, "\t")
+			return branch
+		}
+	}
+	return ""
+}
+
+// openPullRequest pushes the branch of the repo and opens a pull request
+// for it, or finds the one that is open already.
+func (client *RepoManager) openPullRequest(repoConfig *RepoConfig, opts PullRequestOptions) (outcome, string, *PullRequest, error) {
+	dir := repoConfig.FullDir(client.workspace)
+	if client.mirrorFor(repoConfig) {
+		return outcomeSkipped, "mirror", nil, nil
+	}
+	if repoConfig.IsReadOnly() {
+		return outcomeSkipped, reasonReadOnly, nil, nil
+	}
+	if err := client.backend.Open(dir); err != nil {
+		return outcomeFailed, "", nil, err
+	}
+	branch := opts.Branch
+	if branch == "" {
+		branch, _ = runGit(dir, "symbolic-ref", "--short", "--quiet", "HEAD")
+		if branch == "" {
+			return outcomeSkipped, "HEAD is detached", nil, nil
+		}
+	} else if _, err := runGit(dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err != nil {
+		return outcomeSkipped, "no branch " + branch, nil, nil
+	}
+	base := opts.Base
+	if base == "" {
+		base = client.baseOf(repoConfig, dir)
+	}
+	if base == "" {
+		return outcomeFailed, "", nil, fmt.Errorf("can't determine the base branch, pass --base")
+	}
+	if base == branch {
+		return outcomeSkipped, "on the base branch", nil, nil
+	}
+	baseRef := "origin/" + base
+	if _, err := runGit(dir, "rev-parse", "--verify", "--quiet", "refs/remotes/"+baseRef); err != nil {
+		baseRef = base
+	}
+	if commits, err := runGit(dir, "rev-list", "--count", baseRef+".."+branch); err != nil {
+		return outcomeFailed, "", nil, err
+	} else if commits == "0" {
+		return outcomeSkipped, "no changes against " + base, nil, nil
+	}
+
+	provider, err := client.providerFor(repoConfig)
+	if err != nil {
+		return outcomeFailed, "", nil, err
+	}
+	project, err := projectPath(repoConfig.RemoteURL())
+	if err != nil {
+		return outcomeFailed, "", nil, err
+	}
+	client.logger.Debug("pushing", "repo", repoConfig.Name, "branch", branch)
+	if _, err := client.gitCLI().originGit(dir, "push", "--set-upstream", "origin", branch); err != nil {
+		return outcomeFailed, "", nil, err
+	}
+	pull, err := provider.findPullRequest(project, branch, base)
+	if err != nil {
+		return outcomeFailed, "", nil, err
+	}
+	if pull != nil {
+		pull.Repo = repoConfig.Name
+		return outcomeUpToDate, "open already, " + pull.URL, pull, nil
+	}
+	pull, err = provider.createPullRequest(project, branch, base, opts)
+	if err != nil {
+		return outcomeFailed, "", nil, err
+	}
+	pull.Repo = repoConfig.Name
+	return outcomeSucceeded, pull.URL, pull, nil
+}
+
+// CreatePullRequests pushes the branch of every selected repo that has
+// commits its base branch doesn't and opens a pull request for it on the
+// provider of the repo. Repos with an open pull request for the branch keep
+// it. It returns the pull requests, created or found.
+func (client *RepoManager) CreatePullRequests(opts PullRequestOptions) ([]*PullRequest, error) {
+	client.logger.Info("creating pull requests", "workspace", client.workspace, "branch", opts.Branch)
+	if opts.Title == "" {
+		return nil, fmt.Errorf("pull requests need a title")
+	}
+	var mu sync.Mutex
+	var pulls []*PullRequest
+	err := client.runBatch("pr create", client.selectedRepos(opts.Select), func(repoConfig *RepoConfig) (outcome, string, error) {
+		result, reason, pull, err := client.openPullRequest(repoConfig, opts)
+		if pull != nil {
+			mu.Lock()
+			pulls = append(pulls, pull)
+			mu.Unlock()
+		}
+		return result, reason, err
+	})
+	sortPullRequests(pulls)
+	return pulls, err
+}
+
+func sortPullRequests(pulls []*PullRequest) {
+	sort.SliceStable(pulls, func(i, j int) bool {
+		return pulls[i].Repo < pulls[j].Repo
+	})
+}
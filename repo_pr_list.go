@@ -0,0 +1,359 @@
+package repos
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+// Review and check states of pull requests and commits, the same for every
+// provider.
+const (
+	ReviewApproved         = "approved"
+	ReviewChangesRequested = "changes requested"
+	ReviewRequested        = "review requested"
+	ChecksSuccess          = "success"
+	ChecksFailure          = "failure"
+	ChecksPending          = "pending"
+	// StateNone is the review or checks state of pull requests and commits
+	// without any.
+	StateNone = "none"
+)
+
+type PullRequestListOptions struct {
+	Select ListOptions
+	// All lists every open pull request into the base branch of each repo,
+	// instead of those of the user whatever their base.
+	All bool
+}
+
+// providerUser returns the login of the user of the token of the provider.
+func (provider *ProviderConfig) providerUser() (string, error) {
+	switch provider.Type {
+	case "github", "gitea":
+		prefix := ""
+		if provider.Type == "gitea" {
+			prefix = "/api/v1"
+		}
+		var user struct {
+			Login string `json:"login"`
+		}
+		err := provider.call(http.MethodGet, prefix+"/user", nil, &user)
+		return user.Login, err
+	case "gitlab":
+		var user struct {
+			Username string `json:"username"`
+		}
+		err := provider.call(http.MethodGet, "/api/v4/user", nil, &user)
+		return user.Username, err
+	}
+	return "", fmt.Errorf("unknown provider type %q, must be github, gitlab or gitea", provider.Type)
+}
+
+// combineChecks folds the states of several checks into one: any failure
+// fails, else anything unfinished is pending.
+func combineChecks(states []string) string {
+	combined := StateNone
+	for _, state := range states {
+		switch state {
+		case ChecksFailure:
+			return ChecksFailure
+		case ChecksPending:
+			combined = ChecksPending
+		case ChecksSuccess:
+			if combined == StateNone {
+				combined = ChecksSuccess
+			}
+		}
+	}
+	return combined
+}
+
+// checksState maps the states and conclusions of commit statuses, check runs
+// and pipelines to ChecksSuccess, ChecksFailure or ChecksPending, or "" for
+// those that don't count, such as skipped checks.
+func checksState(state string) string {
+	switch state {
+	case "success", "passed", "neutral":
+		return ChecksSuccess
+	case "failure", "failed", "error", "timed_out", "cancelled", "canceled", "action_required", "startup_failure":
+		return ChecksFailure
+	case "pending", "queued", "in_progress", "running", "created", "waiting_for_resource", "preparing", "scheduled", "manual", "requested", "waiting":
+		return ChecksPending
+	}
+	return ""
+}
+
+// commitChecks returns the combined state of the CI checks of commit sha of
+// project: commit statuses and check runs on GitHub, commit statuses on
+// Gitea and the latest pipeline on GitLab.
+func (provider *ProviderConfig) commitChecks(project, sha string) (string, error) {
+	switch provider.Type {
+	case "github", "gitea":
+		prefix := ""
+		if provider.Type == "gitea" {
+			prefix = "/api/v1"
+		}
+		var status struct {
+			Statuses []struct {
+				State string `json:"state"`
+			} `json:"statuses"`
+		}
+		if err := provider.call(http.MethodGet, prefix+"/repos/"+project+"/commits/"+sha+"/status", nil, &status); err != nil {
+			return "", err
+		}
+		var states []string
+		for _, s := range status.Statuses {
+			states = append(states, checksState(s.State))
+		}
+		if provider.Type == "github" {
+			var runs struct {
+				CheckRuns []struct {
+					Status     string `json:"status"`
+					Conclusion string `json:"conclusion"`
+				} `json:"check_runs"`
+			}
+			if err := provider.call(http.MethodGet, "/repos/"+project+"/commits/"+sha+"/check-runs?per_page=100", nil, &runs); err != nil {
+				return "", err
+			}
+			for _, run := range runs.CheckRuns {
+				if run.Status != "completed" {
+					states = append(states, ChecksPending)
+				} else {
+					states = append(states, checksState(run.Conclusion))
+				}
+			}
+		}
+		return combineChecks(states), nil
+	case "gitlab":
+		var pipelines []struct {
+			Status string `json:"status"`
+		}
+		query := url.Values{"sha": {sha}, "per_page": {"1"}}
+		if err := provider.call(http.MethodGet, "/api/v4/projects/"+url.PathEscape(project)+"/pipelines?"+query.Encode(), nil, &pipelines); err != nil {
+			return "", err
+		}
+		if len(pipelines) == 0 {
+			return StateNone, nil
+		}
+		return combineChecks([]string{checksState(pipelines[0].Status)}), nil
+	}
+	return "", fmt.Errorf("unknown provider type %q, must be github, gitlab or gitea", provider.Type)
+}
+
+// reviewState returns the review state of pull request number of project.
+// The latest review of every reviewer counts, requested changes over
+// approvals.
+func (provider *ProviderConfig) reviewState(project string, number int, requested bool) (string, error) {
+	switch provider.Type {
+	case "github", "gitea":
+		prefix := ""
+		if provider.Type == "gitea" {
+			prefix = "/api/v1"
+		}
+		var reviews []struct {
+			State string `json:"state"`
+			User  struct {
+				Login string `json:"login"`
+			} `json:"user"`
+		}
+		if err := provider.call(http.MethodGet, prefix+"/repos/"+project+"/pulls/"+strconv.Itoa(number)+"/reviews", nil, &reviews); err != nil {
+			return "", err
+		}
+		latest := make(map[string]string)
+		for _, review := range reviews {
+			switch review.State {
+			case "APPROVED", "CHANGES_REQUESTED", "REQUEST_CHANGES", "DISMISSED":
+				latest[review.User.Login] = review.State
+			}
+		}
+		state := StateNone
+		if requested {
+			state = ReviewRequested
+		}
+		for _, review := range latest {
+			switch review {
+			case "CHANGES_REQUESTED", "REQUEST_CHANGES":
+				return ReviewChangesRequested, nil
+			case "APPROVED":
+				state = ReviewApproved
+			}
+		}
+		return state, nil
+	case "gitlab":
+		var approvals struct {
+			Approved   bool       `json:"approved"`
+			ApprovedBy []struct{} `json:"approved_by"`
+		}
+		path := "/api/v4/projects/" + url.PathEscape(project) + "/merge_requests/" + strconv.Itoa(number) + "/approvals"
+		if err := provider.call(http.MethodGet, path, nil, &approvals); err != nil {
+			return "", err
+		}
+		switch {
+		case approvals.Approved && len(approvals.ApprovedBy) > 0:
+			return ReviewApproved, nil
+		case requested:
+			return ReviewRequested, nil
+		}
+		return StateNone, nil
+	}
+	return "", fmt.Errorf("unknown provider type %q, must be github, gitlab or gitea", provider.Type)
+}
+
+// listedPull is an open pull request with what its review and checks states
+// are looked up by.
+type listedPull struct {
+	*PullRequest
+	sha       string
+	requested bool
+}
+
+// openPullRequests lists the open pull requests of project by author, or
+// into base when author is empty.
+func (provider *ProviderConfig) openPullRequests(project, author, base string) ([]listedPull, error) {
+	var listed []listedPull
+	switch provider.Type {
+	case "github", "gitea":
+		prefix, query := "", url.Values{"state": {"open"}}
+		if provider.Type == "gitea" {
+			prefix = "/api/v1"
+			query.Set("limit", "50")
+		} else {
+			query.Set("per_page", "100")
+		}
+		if author == "" {
+			query.Set("base", base)
+		}
+		var pulls []struct {
+			githubPull
+			RequestedReviewers []struct{} `json:"requested_reviewers"`
+		}
+		if err := provider.call(http.MethodGet, prefix+"/repos/"+project+"/pulls?"+query.Encode(), nil, &pulls); err != nil {
+			return nil, err
+		}
+		for _, pull := range pulls {
+			pr := pull.githubPull.pullRequest()
+			if (author != "" && pr.Author != author) || (author == "" && pr.Base != base) {
+				continue
+			}
+			listed = append(listed, listedPull{PullRequest: pr, sha: pull.Head.SHA, requested: len(pull.RequestedReviewers) > 0})
+		}
+		return listed, nil
+	case "gitlab":
+		query := url.Values{"state": {"opened"}, "per_page": {"100"}}
+		if author != "" {
+			query.Set("author_username", author)
+		} else {
+			query.Set("target_branch", base)
+		}
+		var mrs []struct {
+			gitlabMergeRequest
+			SHA       string     `json:"sha"`
+			Reviewers []struct{} `json:"reviewers"`
+		}
+		if err := provider.call(http.MethodGet, "/api/v4/projects/"+url.PathEscape(project)+"/merge_requests?"+query.Encode(), nil, &mrs); err != nil {
+			return nil, err
+		}
+		for _, mr := range mrs {
+			listed = append(listed, listedPull{PullRequest: mr.gitlabMergeRequest.pullRequest(), sha: mr.SHA, requested: len(mr.Reviewers) > 0})
+		}
+		return listed, nil
+	}
+	return nil, fmt.Errorf("unknown provider type %q, must be github, gitlab or gitea", provider.Type)
+}
+
+// ListPullRequests returns the open pull requests of the user of each
+// provider token in the selected repos, or with opts.All every open pull
+// request into the base branch of each repo, with their review and checks
+// states. Repos on hosts without a configured provider are left out. Repos
+// that couldn't be listed are reported in a *BatchError next to the pull
+// requests of the others.
+func (client *RepoManager) ListPullRequests(opts PullRequestListOptions) ([]*PullRequest, error) {
+	client.logger.Info("listing pull requests", "workspace", client.workspace)
+	targets, err := client.providerRepos(opts.Select)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	users := make(map[*ProviderConfig]string)
+	userOf := func(provider *ProviderConfig) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if user, ok := users[provider]; ok {
+			return user, nil
+		}
+		user, err := provider.providerUser()
+		if err == nil {
+			users[provider] = user
+		}
+		return user, err
+	}
+
+	pulls := []*PullRequest{}
+	errs := make(map[string]error)
+	slots := make(chan struct{}, client.jobsLimit())
+	wg := sync.WaitGroup{}
+	for _, t := range targets {
+		wg.Add(1)
+		go func(t providerRepo) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			client.logger.Debug("listing pull requests", "repo", t.repoConfig.Name)
+			listed, err := client.listRepoPullRequests(t.repoConfig, t.provider, t.project, opts, userOf)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[t.repoConfig.Name] = err
+				return
+			}
+			pulls = append(pulls, listed...)
+		}(t)
+	}
+	wg.Wait()
+
+	sortPullRequests(pulls)
+	if len(errs) > 0 {
+		return pulls, &BatchError{Errors: errs}
+	}
+	return pulls, nil
+}
+
+func (client *RepoManager) listRepoPullRequests(repoConfig *RepoConfig, provider *ProviderConfig, project string,
+	opts PullRequestListOptions, userOf func(*ProviderConfig) (string, error)) ([]*PullRequest, error) {
+	var author, base string
+	if opts.All {
+		base = client.baseOf(repoConfig, repoConfig.FullDir(client.workspace))
+		if base == "" {
+			return nil, fmt.Errorf("can't determine the base branch")
+		}
+	} else {
+		user, err := userOf(provider)
+		if err != nil {
+			return nil, err
+		}
+		author = user
+	}
+	listed, err := provider.openPullRequests(project, author, base)
+	if err != nil {
+		return nil, err
+	}
+	var pulls []*PullRequest
+	for _, pull := range listed {
+		pull.Repo = repoConfig.Name
+		if pull.Review, err = provider.reviewState(project, pull.Number, pull.requested); err != nil {
+			return nil, err
+		}
+		pull.Checks = StateNone
+		if pull.sha != "" {
+			if pull.Checks, err = provider.commitChecks(project, pull.sha); err != nil {
+				return nil, err
+			}
+		}
+		pulls = append(pulls, pull.PullRequest)
+	}
+	return pulls, nil
+}
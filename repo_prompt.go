@@ -0,0 +1,153 @@
+package repos
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// promptRefreshTimeout is how long a started refresh of the prompt cache is
+// waited for before another one may start.
+const promptRefreshTimeout = time.Minute
+
+// PromptSummary counts the repos of the workspace that need attention, as
+// cached for shell prompts.
+type PromptSummary struct {
+	Time   time.Time `json:"time"`
+	Repos  int       `json:"repos"`
+	Dirty  int       `json:"dirty"`
+	Ahead  int       `json:"ahead"`
+	Behind int       `json:"behind"`
+	Failed int       `json:"failed"`
+}
+
+// String is the terse summary printed in prompts, e.g. "3 dirty, 1 behind",
+// empty when every repo is clean and in sync.
+func (summary *PromptSummary) String() string {
+	var parts []string
+	for _, count := range []struct {
+		n    int
+		what string
+	}{{summary.Dirty, "dirty"}, {summary.Ahead, "ahead"}, {summary.Behind, "behind"}, {summary.Failed, "failed"}} {
+		if count.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", count.n, count.what))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// promptCachePath returns the file the prompt summary of the workspace is
+// cached in, in the user's cache directory.
+func (client *RepoManager) promptCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum([]byte(client.workspace))
+	return filepath.Join(dir, "repos", "prompt-"+hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// CachedPrompt returns the cached prompt summary of the workspace, nil when
+// there is none.
+func (client *RepoManager) CachedPrompt() (*PromptSummary, error) {
+	path, err := client.promptCachePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	summary := &PromptSummary{}
+	if err := json.Unmarshal(data, summary); err != nil {
+		return nil, nil
+	}
+	return summary, nil
+}
+
+// ClaimPromptRefresh reports whether the caller may refresh the prompt cache
+// in the background, false while another refresh is running.
+func (client *RepoManager) ClaimPromptRefresh() bool {
+	path, err := client.promptCachePath()
+	if err != nil {
+		return false
+	}
+	lock := path + ".lock"
+	if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) < promptRefreshTimeout {
+		return false
+	}
+	os.Remove(lock)
+	if err := os.MkdirAll(filepath.Dir(lock), 0755); err != nil {
+		return false
+	}
+	f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return false
+	}
+	return f.Close() == nil
+}
+
+// RefreshPrompt counts the repos that are dirty, ahead of or behind their
+// upstream as of the last fetch or whose status failed, without touching
+// the network, and caches the summary.
+func (client *RepoManager) RefreshPrompt() (*PromptSummary, error) {
+	path, err := client.promptCachePath()
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(path + ".lock")
+
+	summary := &PromptSummary{}
+	var mu sync.Mutex
+	slots := make(chan struct{}, client.jobsLimit())
+	wg := sync.WaitGroup{}
+	for _, repoConfig := range client.sortedRepos() {
+		wg.Add(1)
+		go func(repoConfig *RepoConfig) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			entry := client.statusEntry(repoConfig)
+			mu.Lock()
+			defer mu.Unlock()
+			summary.Repos++
+			switch {
+			case entry.Error != "":
+				summary.Failed++
+				return
+			case !entry.Clean:
+				summary.Dirty++
+			}
+			if entry.Ahead > 0 {
+				summary.Ahead++
+			}
+			if entry.Behind > 0 {
+				summary.Behind++
+			}
+		}(repoConfig)
+	}
+	wg.Wait()
+	summary.Time = time.Now()
+
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	// Written aside and renamed, so prompts never read half a file.
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return nil, err
+	}
+	return summary, os.Rename(path+".tmp", path)
+}
@@ -0,0 +1,283 @@
+package repos
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ProviderConfig is a forge whose API creates remote repositories, such as
+// GitHub, GitLab or Gitea.
+type ProviderConfig struct {
+	// Type is github, gitlab or gitea.
+	Type string `yaml:"type"`
+	// URL is the base url of the API, required for gitea and defaulting to
+	// the public instance for github and gitlab.
+	URL string `yaml:"url,omitempty"`
+	// Owner is the user or organization new repositories belong to,
+	// defaulting to the user of the token.
+	Owner string `yaml:"owner,omitempty"`
+	// TokenEnv names the environment variable holding the API token,
+	// GITHUB_TOKEN, GITLAB_TOKEN or GITEA_TOKEN by default.
+	TokenEnv string `yaml:"token_env,omitempty" mapstructure:"token_env"`
+	// Token is the API token, best encrypted with repos config encrypt.
+	Token string `yaml:"token,omitempty"`
+	// Protocol of the origin url of created repositories, ssh or https.
+	Protocol string `yaml:"protocol,omitempty"`
+}
+
+// remoteRepo is a repository created on a provider.
+type remoteRepo struct {
+	SSHURL   string
+	CloneURL string
+}
+
+func (provider *ProviderConfig) apiURL() string {
+	if provider.URL != "" {
+		return strings.TrimRight(provider.URL, "/")
+	}
+	switch provider.Type {
+	case "github":
+		return "https://api.github.com"
+	case "gitlab":
+		return "https://gitlab.com"
+	}
+	return ""
+}
+
+// host is the host tokens of the provider are stored for by repos auth
+// login.
+func (provider *ProviderConfig) host() string {
+	if provider.URL != "" {
+		if u, err := url.Parse(provider.URL); err == nil && u.Host != "" {
+			return u.Host
+		}
+	}
+	switch provider.Type {
+	case "github":
+		return "github.com"
+	case "gitlab":
+		return "gitlab.com"
+	}
+	return ""
+}
+
+// token returns the API token from the environment, the config or else from
+// the OS keyring.
+func (provider *ProviderConfig) token() (string, error) {
+	env := provider.TokenEnv
+	if env == "" {
+		env = strings.ToUpper(provider.Type) + "_TOKEN"
+	}
+	if token := os.Getenv(env); token != "" {
+		return token, nil
+	}
+	if provider.Token != "" {
+		return provider.Token, nil
+	}
+	host := provider.host()
+	if credential, ok := storedCredential(host); ok {
+		return credential.Token, nil
+	}
+	return "", fmt.Errorf("no %s token, set %s or run repos auth login %s", provider.Type, env, host)
+}
+
+// call sends a JSON request to the API and decodes the JSON response into out.
+func (provider *ProviderConfig) call(method, path string, in, out interface{}) error {
+	token, err := provider.token()
+	if err != nil {
+		return err
+	}
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, provider.apiURL()+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	switch provider.Type {
+	case "gitlab":
+		req.Header.Set("PRIVATE-TOKEN", token)
+	case "gitea":
+		req.Header.Set("Authorization", "token "+token)
+	default:
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		var apiErr struct {
+			Message interface{} `json:"message"`
+		}
+		msg := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != nil {
+			msg = fmt.Sprint(apiErr.Message)
+		}
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, msg)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// createRepo creates an empty repository called name.
+func (provider *ProviderConfig) createRepo(name string, private bool) (*remoteRepo, error) {
+	switch provider.Type {
+	case "github", "gitea":
+		prefix := ""
+		if provider.Type == "gitea" {
+			if provider.URL == "" {
+				return nil, fmt.Errorf("the url of the gitea provider is not configured")
+			}
+			prefix = "/api/v1"
+		}
+		path := prefix + "/user/repos"
+		if provider.Owner != "" {
+			var user struct {
+				Login string `json:"login"`
+			}
+			if err := provider.call(http.MethodGet, prefix+"/user", nil, &user); err != nil {
+				return nil, err
+			}
+			if !strings.EqualFold(user.Login, provider.Owner) {
+				path = prefix + "/orgs/" + url.PathEscape(provider.Owner) + "/repos"
+			}
+		}
+		var created struct {
+			SSHURL   string `json:"ssh_url"`
+			CloneURL string `json:"clone_url"`
+		}
+		in := map[string]interface{}{"name": name, "private": private}
+		if err := provider.call(http.MethodPost, path, in, &created); err != nil {
+			return nil, err
+		}
+		return &remoteRepo{SSHURL: created.SSHURL, CloneURL: created.CloneURL}, nil
+	case "gitlab":
+		visibility := "public"
+		if private {
+			visibility = "private"
+		}
+		in := map[string]interface{}{"name": name, "path": name, "visibility": visibility}
+		if provider.Owner != "" {
+			var namespace struct {
+				ID int `json:"id"`
+			}
+			if err := provider.call(http.MethodGet, "/api/v4/namespaces/"+url.PathEscape(provider.Owner), nil, &namespace); err != nil {
+				return nil, err
+			}
+			in["namespace_id"] = namespace.ID
+		}
+		var created struct {
+			SSHURL   string `json:"ssh_url_to_repo"`
+			CloneURL string `json:"http_url_to_repo"`
+		}
+		if err := provider.call(http.MethodPost, "/api/v4/projects", in, &created); err != nil {
+			return nil, err
+		}
+		return &remoteRepo{SSHURL: created.SSHURL, CloneURL: created.CloneURL}, nil
+	}
+	return nil, fmt.Errorf("unknown provider type %q, must be github, gitlab or gitea", provider.Type)
+}
+
+// originURL picks the url of the created repo in the configured protocol.
+func (provider *ProviderConfig) originURL(repo *remoteRepo) string {
+	if provider.Protocol == "https" {
+		return repo.CloneURL
+	}
+	return repo.SSHURL
+}
+
+func (client *RepoManager) provider(name string) (*ProviderConfig, error) {
+	provider, ok := client.config.Providers[name]
+	if !ok {
+		return nil, fmt.Errorf("provider %s is not configured", name)
+	}
+	return provider, nil
+}
+
+// publish creates the remote repository of a local repo on the provider named
+// in opts, makes it origin and pushes the checked out branch to it.
+func (client *RepoManager) publish(repoConfig *RepoConfig, dir string, opts AddOptions) (string, error) {
+	provider, err := client.provider(opts.CreateRemote)
+	if err != nil {
+		return "", err
+	}
+	client.logger.Info("creating remote", "repo", repoConfig.Name, "provider", opts.CreateRemote)
+	created, err := provider.createRepo(repoConfig.Name, opts.Private)
+	if err != nil {
+		return "", fmt.Errorf("creating %s on %s: %w", repoConfig.Name, opts.CreateRemote, err)
+	}
+	remoteURL := provider.originURL(created)
+	if _, err := runGit(dir, "remote", "add", "origin", remoteURL); err != nil {
+		return "", err
+	}
+	client.printRepoLine(client.nameWidth(), repoConfig.Name, "created "+remoteURL)
+
+	branch, err := runGit(dir, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return remoteURL, nil
+	}
+	if _, err := runGit(dir, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		client.logger.Warn("nothing to push yet", "repo", repoConfig.Name)
+		return remoteURL, nil
+	}
+	cli := client.gitCLI()
+	if _, err := cli.originGit(dir, "push", "--set-upstream", "origin", branch); err != nil {
+		return remoteURL, err
+	}
+	if len(repoConfig.Branch) == 0 {
+		repoConfig.Branch = Branches{branch}
+	}
+	return remoteURL, nil
+}
+
+// providerRepo is a repo with the provider of the host of its origin and
+// its project path there.
+type providerRepo struct {
+	repoConfig *RepoConfig
+	provider   *ProviderConfig
+	project    string
+}
+
+// providerRepos returns the selected repos on hosts with a provider
+// configured, leaving the others out.
+func (client *RepoManager) providerRepos(opts ListOptions) ([]providerRepo, error) {
+	var targets []providerRepo
+	for _, repoConfig := range client.selectedRepos(opts) {
+		provider, err := client.providerFor(repoConfig)
+		if err != nil {
+			client.logger.Debug("skipping", "repo", repoConfig.Name, "error", err)
+			continue
+		}
+		project, err := projectPath(repoConfig.RemoteURL())
+		if err != nil {
+			client.logger.Debug("skipping", "repo", repoConfig.Name, "error", err)
+			continue
+		}
+		targets = append(targets, providerRepo{repoConfig, provider, project})
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no provider configured for the hosts of the repos")
+	}
+	return targets, nil
+}
@@ -0,0 +1,134 @@
+package repos
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/kevinburke/ssh_config"
+)
+
+// matchHost returns the pattern of patterns, host patterns such as
+// *.corp.example.com, that applies to host: an exact match, otherwise the
+// longest matching pattern. It returns false when none matches.
+func matchHost(patterns []string, host string) (string, bool) {
+	patterns = append([]string{}, patterns...)
+	sort.Slice(patterns, func(i, j int) bool {
+		if (patterns[i] == host) != (patterns[j] == host) {
+			return patterns[i] == host
+		}
+		return len(patterns[i]) > len(patterns[j])
+	})
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, host); matched {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// proxyFor returns the proxy configured for host in proxies, whose keys are
+// host patterns, or nil if there is none.
+func proxyFor(proxies map[string]string, host string) (*url.URL, error) {
+	patterns := make([]string, 0, len(proxies))
+	for pattern := range proxies {
+		patterns = append(patterns, pattern)
+	}
+	pattern, ok := matchHost(patterns, host)
+	if !ok {
+		return nil, nil
+	}
+	proxyURL, err := url.Parse(proxies[pattern])
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy for %s: %w", pattern, err)
+	}
+	return proxyURL, nil
+}
+
+// envProxy returns the proxy of the environment for req: HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY as usual, with ALL_PROXY as a last resort.
+func envProxy(req *http.Request) (*url.URL, error) {
+	proxyURL, err := http.ProxyFromEnvironment(req)
+	if proxyURL != nil || err != nil {
+		return proxyURL, err
+	}
+	for _, name := range []string{"ALL_PROXY", "all_proxy"} {
+		if all := os.Getenv(name); all != "" {
+			return url.Parse(all)
+		}
+	}
+	return nil, nil
+}
+
+// installHTTPProxies makes go-git's HTTP(S) transports use the configured
+// proxies, falling back to the proxy environment variables.
+func installHTTPProxies(proxies map[string]string) {
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			Proxy: func(req *http.Request) (*url.URL, error) {
+				if proxyURL, err := proxyFor(proxies, req.URL.Hostname()); proxyURL != nil || err != nil {
+					return proxyURL, err
+				}
+				return envProxy(req)
+			},
+			ForceAttemptHTTP2: true,
+		},
+	}
+	client.InstallProtocol("http", githttp.NewClient(httpClient))
+	client.InstallProtocol("https", githttp.NewClient(httpClient))
+}
+
+// sshNeedsCLI reports whether an ssh remote must go through a proxy, which
+// only the ssh command can do: a proxy is configured for its host or
+// ~/.ssh/config has a ProxyCommand or ProxyJump for it.
+func sshNeedsCLI(proxies map[string]string, remoteURL string) bool {
+	endpoint, err := transport.NewEndpoint(remoteURL)
+	if err != nil || endpoint.Protocol != "ssh" {
+		return false
+	}
+	if proxyURL, _ := proxyFor(proxies, endpoint.Host); proxyURL != nil {
+		return true
+	}
+	return ssh_config.Get(endpoint.Host, "ProxyCommand") != "" || ssh_config.Get(endpoint.Host, "ProxyJump") != ""
+}
+
+// gitProxyArgs returns the git -c options that send remoteURL through its
+// configured proxy. ssh connects through OpenBSD netcat, which speaks SOCKS5
+// and HTTP CONNECT.
+func gitProxyArgs(proxies map[string]string, remoteURL string) ([]string, error) {
+	endpoint, err := transport.NewEndpoint(remoteURL)
+	if err != nil {
+		return nil, nil
+	}
+	proxyURL, err := proxyFor(proxies, endpoint.Host)
+	if proxyURL == nil || err != nil {
+		return nil, err
+	}
+	switch endpoint.Protocol {
+	case "http", "https":
+		return []string{"-c", "http.proxy=" + proxyURL.String()}, nil
+	case "ssh":
+		var kind string
+		switch proxyURL.Scheme {
+		case "socks5", "socks5h":
+			kind = "5"
+		case "http":
+			kind = "connect"
+		default:
+			return nil, fmt.Errorf("unsupported proxy %s for ssh, use socks5:// or http://", proxyURL)
+		}
+		hostPort := proxyURL.Host
+		if proxyURL.Port() == "" {
+			hostPort = net.JoinHostPort(proxyURL.Hostname(), "1080")
+		}
+		return []string{"-c", fmt.Sprintf("core.sshCommand=ssh -o ProxyCommand='nc -X %s -x %s %%h %%p'", kind, hostPort)}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,71 @@
+package repos
+
+// PushOptions adds to what the push config says. A forced push needs Confirm
+// to approve every repo, repos it declines are skipped.
+type PushOptions struct {
+	Tags        bool
+	AllBranches bool
+	// ForceWithLease overwrites the remote branches as long as they are where
+	// the last fetch saw them. There is no plain force on purpose.
+	ForceWithLease bool
+	Confirm        func(repo string) bool
+	// AllowLarge pushes files over max_file_size too.
+	AllowLarge bool
+}
+
+func (client *RepoManager) pushTagsFor(repoConfig *RepoConfig) bool {
+	if repoConfig.PushTags != nil {
+		return *repoConfig.PushTags
+	}
+	return client.config.PushTags
+}
+
+func (client *RepoManager) pushAllBranchesFor(repoConfig *RepoConfig) bool {
+	if repoConfig.PushAllBranches != nil {
+		return *repoConfig.PushAllBranches
+	}
+	return client.config.PushAllBranches
+}
+
+// Reasons reported for read-only repos, which push skips and the other
+// commands that push only update locally.
+const (
+	reasonReadOnly  = "readonly"
+	reasonNotPushed = "readonly, not pushed"
+)
+
+func (client *RepoManager) pushSpec(repoConfig *RepoConfig, opts PushOptions) PushSpec {
+	return PushSpec{
+		Tags:           opts.Tags || client.pushTagsFor(repoConfig),
+		AllBranches:    opts.AllBranches || client.pushAllBranchesFor(repoConfig),
+		ForceWithLease: opts.ForceWithLease,
+	}
+}
+
+func (client *RepoManager) Push(opts PushOptions) error {
+	client.logger.Info("pushing", "workspace", client.workspace)
+	return client.runBatch("push", client.sortedRepos(), func(repoConfig *RepoConfig) (outcome, string, error) {
+		client.logger.Info("pushing", "repo", repoConfig.Name)
+		if client.mirrorFor(repoConfig) {
+			return outcomeSkipped, "mirror", nil
+		}
+		if repoConfig.IsReadOnly() {
+			return outcomeSkipped, reasonReadOnly, nil
+		}
+		reason, err := client.prepareRepo(repoConfig, false)
+		if reason != "" || err != nil {
+			return outcomeSkipped, reason, err
+		}
+		if ok, err := client.reviewed(repoConfig); !ok || err != nil {
+			return outcomeSkipped, reasonDeclined, err
+		}
+		if opts.ForceWithLease && (opts.Confirm == nil || !opts.Confirm(repoConfig.Name)) {
+			return outcomeSkipped, "force push not confirmed", nil
+		}
+		upToDate, err := client.push(repoConfig, client.pushSpec(repoConfig, opts), opts.AllowLarge)
+		if upToDate {
+			return outcomeUpToDate, "", err
+		}
+		return outcomeSucceeded, "", err
+	})
+}
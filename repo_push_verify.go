@@ -0,0 +1,112 @@
+package repos
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// pushedBranches returns the local branches a push as spec says updates on
+// origin: the checked out one, or every one with AllBranches.
+func pushedBranches(dir string, spec PushSpec) ([]string, error) {
+	if spec.AllBranches {
+		out, err := runGit(dir, "for-each-ref", "--format=%(refname:short)", "refs/heads")
+		if err != nil {
+			return nil, err
+		}
+		return strings.Fields(out), nil
+	}
+	branch, err := runGit(dir, "symbolic-ref", "--short", "--quiet", "HEAD")
+	if err != nil || branch == "" {
+		return nil, fmt.Errorf("detached HEAD, nothing to push")
+	}
+	return []string{branch}, nil
+}
+
+// protectedBranch returns the first of branches matching a pattern of the
+// protected_branches config setting, or an empty string.
+func (client *RepoManager) protectedBranch(branches []string) string {
+	for _, branch := range branches {
+		for _, pattern := range client.config.Protected {
+			if ok, _ := path.Match(pattern, branch); ok {
+				return branch
+			}
+		}
+	}
+	return ""
+}
+
+// checkProtected fails when a push as spec says would update a branch of
+// protected_branches on origin. Branches with nothing to push don't count.
+func (client *RepoManager) checkProtected(dir string, spec PushSpec) error {
+	if len(client.config.Protected) == 0 {
+		return nil
+	}
+	branches, err := pushedBranches(dir, spec)
+	if err != nil {
+		return err
+	}
+	if branch := client.protectedBranch(aheadOfOrigin(dir, branches)); branch != "" {
+		return fmt.Errorf("%s is protected by protected_branches, not pushing", branch)
+	}
+	return nil
+}
+
+// aheadOfOrigin returns those of branches that differ from their
+// remote-tracking branch of origin, that is have anything to push as of the
+// last fetch.
+func aheadOfOrigin(dir string, branches []string) []string {
+	var ahead []string
+	for _, branch := range branches {
+		local, _ := runGit(dir, "rev-parse", "refs/heads/"+branch)
+		tracking, _ := runGit(dir, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+branch)
+		if local != tracking {
+			ahead = append(ahead, branch)
+		}
+	}
+	return ahead
+}
+
+// verifyPush makes sure before sync pushes that origin would take the push:
+// that no branch is protected and that every pushed branch fast-forwards the
+// one on origin, as of now rather than of the last fetch. Branches with
+// nothing to push are not looked up on origin. This fails the repo with a
+// clear message before pushing, instead of a rejection halfway through.
+func (client *RepoManager) verifyPush(dir string, spec PushSpec, cli *cliBackend) error {
+	if err := client.checkProtected(dir, spec); err != nil {
+		return err
+	}
+	branches, err := pushedBranches(dir, spec)
+	if err != nil {
+		return err
+	}
+	branches = aheadOfOrigin(dir, branches)
+	if len(branches) == 0 {
+		return nil
+	}
+	out, err := cli.originGit(dir, "ls-remote", "--heads", "origin")
+	if err != nil {
+		return err
+	}
+	remote := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		sha, ref, ok := strings.Cut(line, "\t")
+		if ok {
+			remote[strings.TrimPrefix(ref, "refs/heads/")] = sha
+		}
+	}
+	for _, branch := range branches {
+		sha, ok := remote[branch]
+		if !ok {
+			// The push creates the branch.
+			continue
+		}
+		if _, err := runGit(dir, "cat-file", "-e", sha+"^{commit}"); err != nil {
+			return fmt.Errorf("origin/%s moved while syncing, fetch first and sync again", branch)
+		}
+		if _, err := runGit(dir, "merge-base", "--is-ancestor", sha, "refs/heads/"+branch); err != nil {
+			return fmt.Errorf("push of %s would be rejected as non-fast-forward, origin/%s has commits it doesn't", branch, branch)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package repos
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ReconcileAction is what reconcile does with an orphan or missing repo.
+type ReconcileAction string
+
+const (
+	// ReconcileSkip leaves the repo as it is.
+	ReconcileSkip ReconcileAction = ""
+	// ReconcileAdopt adds an orphan to the config.
+	ReconcileAdopt ReconcileAction = "adopt"
+	// ReconcileClone clones a missing repo.
+	ReconcileClone ReconcileAction = "clone"
+	// ReconcileRemove deletes the directory of an orphan, or removes a
+	// missing repo from the config.
+	ReconcileRemove ReconcileAction = "remove"
+)
+
+// ReconcileOptions choose what happens to the differences between the config
+// and the workspace. Without a choice they are only listed.
+type ReconcileOptions struct {
+	// Depth is how many directory levels are searched for orphans.
+	Depth int
+	// Orphan chooses between adopt, remove and skip for a git repo in dir,
+	// relative to the workspace, that isn't in the config.
+	Orphan func(dir string) ReconcileAction
+	// Missing chooses between clone, remove and skip for a configured repo
+	// whose directory doesn't exist.
+	Missing func(repo string) ReconcileAction
+}
+
+// Reconcile diffs the config against the workspace: it lists the git repos
+// in the workspace that aren't configured, orphans, and the configured repos
+// that aren't on disk, missing, and acts on each as the options choose.
+func (client *RepoManager) Reconcile(opts ReconcileOptions) error {
+	client.logger.Info("reconciling", "workspace", client.workspace)
+	dirs, skipped, err := client.discover(opts.Depth)
+	if err != nil {
+		return err
+	}
+	configured := make(map[string]bool)
+	for _, repoConfig := range client.config.Repos {
+		configured[repoConfig.FullDir(client.workspace)] = true
+	}
+	var orphans []string
+	for _, fullDir := range dirs {
+		if !configured[fullDir] {
+			orphans = append(orphans, fullDir)
+		}
+	}
+	sort.Strings(orphans)
+
+	max := client.nameWidth()
+	changed := false
+	var failed []string
+	for _, fullDir := range orphans {
+		dir, err := filepath.Rel(client.workspace, fullDir)
+		if err != nil {
+			return err
+		}
+		client.printRepoLine(max, dir, client.paint(colorYellow, "orphan, not in the config"))
+		action := ReconcileSkip
+		if opts.Orphan != nil {
+			action = opts.Orphan(dir)
+		}
+		switch action {
+		case ReconcileAdopt:
+			repoConfig := client.adoptedRepo(fullDir, dir)
+			client.config.Repos[repoConfig.Name] = repoConfig
+			changed = true
+			client.printRepoLine(max, dir, "adopted as "+repoConfig.Name)
+		case ReconcileRemove:
+			if err := os.RemoveAll(fullDir); err != nil {
+				client.printRepoLine(max, dir, err)
+				failed = append(failed, dir)
+				continue
+			}
+			client.printRepoLine(max, dir, "deleted")
+		}
+	}
+	client.printSkippedDirs(skipped)
+
+	for _, repoConfig := range client.sortedRepos() {
+		fullDir := repoConfig.FullDir(client.workspace)
+		if _, err := os.Stat(fullDir); !os.IsNotExist(err) {
+			continue
+		}
+		client.printRepoLine(max, repoConfig.Name, client.paint(colorYellow, "missing, "+fullDir+" doesn't exist"))
+		action := ReconcileSkip
+		if opts.Missing != nil {
+			action = opts.Missing(repoConfig.Name)
+		}
+		switch action {
+		case ReconcileClone:
+			if repoConfig.Url == "" {
+				client.printRepoLine(max, repoConfig.Name, "no url configured")
+				failed = append(failed, repoConfig.Name)
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(fullDir), 0755); err != nil {
+				return err
+			}
+			if err := client.backend.Clone(client.cloneSpec(repoConfig, CloneOptions{})); err != nil {
+				client.printRepoLine(max, repoConfig.Name, err)
+				failed = append(failed, repoConfig.Name)
+				continue
+			}
+			client.printRepoLine(max, repoConfig.Name, "cloned")
+		case ReconcileRemove:
+			delete(client.config.Repos, repoConfig.Name)
+			changed = true
+			client.printRepoLine(max, repoConfig.Name, "removed from the config")
+		}
+	}
+
+	if changed {
+		if err := client.config.Save(); err != nil {
+			return err
+		}
+	}
+	client.syncGoWork()
+	if len(failed) > 0 {
+		return failedIn("reconcile", failed)
+	}
+	return nil
+}
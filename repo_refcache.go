@@ -0,0 +1,127 @@
+package repos
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// refCacheFile is the file in the workspace directory where pull remembers
+// the remote refs it last brought in.
+const refCacheFile = ".repos-refs.json"
+
+// WithSkipUnchanged makes pull skip repos whose remote branches haven't moved
+// since their last pull, overriding the skip_unchanged config setting.
+func WithSkipUnchanged(skip bool) NewRepoManagerClientOptions {
+	return func(client *RepoManager) {
+		client.skipUnchanged = skip
+	}
+}
+
+// refState is what a repo looked like after its last pull: the remote
+// branches as ls-remote listed them and the local HEAD.
+type refState struct {
+	URL  string            `json:"url"`
+	Refs map[string]string `json:"refs"`
+	Head string            `json:"head"`
+}
+
+// refCache holds the refState of every repo pulled in the workspace.
+type refCache struct {
+	path  string
+	mu    sync.Mutex
+	repos map[string]*refState
+	dirty bool
+}
+
+func loadRefCache(workspace string) *refCache {
+	cache := &refCache{path: filepath.Join(workspace, refCacheFile), repos: make(map[string]*refState)}
+	if data, err := os.ReadFile(cache.path); err == nil {
+		json.Unmarshal(data, &cache.repos)
+	}
+	return cache
+}
+
+func (cache *refCache) get(name string) *refState {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.repos[name]
+}
+
+func (cache *refCache) set(name string, state *refState) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.repos[name] = state
+	cache.dirty = true
+}
+
+func (cache *refCache) save() error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if !cache.dirty {
+		return nil
+	}
+	data, err := json.MarshalIndent(cache.repos, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(cache.path, data, 0644)
+}
+
+// remoteRefs lists the remote branches a pull of the repo in dir would bring
+// in: the tracked branches and the checked out one.
+func (client *RepoManager) remoteRefs(repoConfig *RepoConfig, dir string, status *RepoStatus) (map[string]string, error) {
+	var refs []string
+	for _, branch := range append(append(Branches{}, repoConfig.Branch...), status.Branch) {
+		if branch != "" && !contains(refs, "refs/heads/"+branch) {
+			refs = append(refs, "refs/heads/"+branch)
+		}
+	}
+	sort.Strings(refs)
+	cli := client.gitCLI()
+	out, err := cli.originGit(dir, append([]string{"ls-remote", "origin"}, refs...)...)
+	if err != nil {
+		return nil, err
+	}
+	remote := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		if hash, ref, ok := strings.Cut(line, "\t"); ok {
+			remote[ref] = hash
+		}
+	}
+	return remote, nil
+}
+
+// unchangedSince checks the remote and local state of a repo against the
+// state after its last pull. It returns the current state for recording
+// after the pull, or nil when it can't be determined.
+func (client *RepoManager) unchangedSince(repoConfig *RepoConfig, status *RepoStatus, cache *refCache) (bool, *refState) {
+	dir := repoConfig.FullDir(client.workspace)
+	refs, err := client.remoteRefs(repoConfig, dir, status)
+	if err != nil {
+		client.logger.Debug("listing the remote refs failed", "repo", repoConfig.Name, "error", err)
+		return false, nil
+	}
+	head, err := runGit(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return false, nil
+	}
+	state := &refState{URL: repoConfig.RemoteURL(), Refs: refs}
+	last := cache.get(repoConfig.Name)
+	unchanged := last != nil && last.URL == state.URL && last.Head == head && reflect.DeepEqual(last.Refs, refs)
+	return unchanged, state
+}
+
+// recordPulled stores the state of a repo after a successful pull.
+func recordPulled(dir string, state *refState, cache *refCache, name string) {
+	head, err := runGit(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return
+	}
+	state.Head = head
+	cache.set(name, state)
+}
@@ -0,0 +1,256 @@
+package repos
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+type ReleaseOptions struct {
+	Select ListOptions
+	// Message of the annotated tags, defaulting to the tag name.
+	Message string
+	Sign    bool
+	// CreateRelease also creates a release on the provider whose host the
+	// origin of each repo is on.
+	CreateRelease bool
+	// Notes is the description of the provider releases.
+	Notes      string
+	Draft      bool
+	Prerelease bool
+}
+
+// projectPath returns the owner and name path of a remote url, e.g.
+// jerloo/repos for git@github.com:jerloo/repos.git.
+func projectPath(remoteURL string) (string, error) {
+	endpoint, err := transport.NewEndpoint(remoteURL)
+	if err != nil {
+		return "", err
+	}
+	path := strings.TrimSuffix(strings.Trim(endpoint.Path, "/"), ".git")
+	if !strings.Contains(path, "/") {
+		return "", fmt.Errorf("can't tell the project of %s", remoteURL)
+	}
+	return path, nil
+}
+
+// providerRelease is a release created on a provider, to delete it again on
+// rollback.
+type providerRelease struct {
+	provider *ProviderConfig
+	project  string
+	id       int
+	tag      string
+}
+
+// createRelease creates a release for the pushed tag in project.
+func (provider *ProviderConfig) createRelease(project, tag string, opts ReleaseOptions) (*providerRelease, error) {
+	release := &providerRelease{provider: provider, project: project, tag: tag}
+	switch provider.Type {
+	case "github", "gitea":
+		prefix := ""
+		if provider.Type == "gitea" {
+			prefix = "/api/v1"
+		}
+		in := map[string]interface{}{
+			"tag_name":   tag,
+			"name":       tag,
+			"body":       opts.Notes,
+			"draft":      opts.Draft,
+			"prerelease": opts.Prerelease,
+		}
+		var created struct {
+			ID int `json:"id"`
+		}
+		if err := provider.call(http.MethodPost, prefix+"/repos/"+project+"/releases", in, &created); err != nil {
+			return nil, err
+		}
+		release.id = created.ID
+		return release, nil
+	case "gitlab":
+		if opts.Draft || opts.Prerelease {
+			return nil, fmt.Errorf("gitlab releases can't be drafts or prereleases")
+		}
+		in := map[string]interface{}{"tag_name": tag, "name": tag, "description": opts.Notes}
+		if err := provider.call(http.MethodPost, "/api/v4/projects/"+url.PathEscape(project)+"/releases", in, nil); err != nil {
+			return nil, err
+		}
+		return release, nil
+	}
+	return nil, fmt.Errorf("unknown provider type %q, must be github, gitlab or gitea", provider.Type)
+}
+
+func (release *providerRelease) delete() error {
+	provider := release.provider
+	switch provider.Type {
+	case "gitlab":
+		return provider.call(http.MethodDelete, "/api/v4/projects/"+url.PathEscape(release.project)+"/releases/"+url.PathEscape(release.tag), nil, nil)
+	case "gitea":
+		return provider.call(http.MethodDelete, "/api/v1/repos/"+release.project+"/releases/"+strconv.Itoa(release.id), nil, nil)
+	default:
+		return provider.call(http.MethodDelete, "/repos/"+release.project+"/releases/"+strconv.Itoa(release.id), nil, nil)
+	}
+}
+
+// providerFor returns the configured provider on the host of the origin of
+// repoConfig.
+func (client *RepoManager) providerFor(repoConfig *RepoConfig) (*ProviderConfig, error) {
+	host := remoteHost(repoConfig)
+	names := make([]string, 0, len(client.config.Providers))
+	for name := range client.config.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if provider := client.config.Providers[name]; host != "" && provider.host() == host {
+			return provider, nil
+		}
+	}
+	return nil, fmt.Errorf("no provider configured for %s", host)
+}
+
+// checkReleasable makes sure the repo is clean, on a branch in sync with its
+// upstream as of a fresh fetch and doesn't have the tag yet.
+func (client *RepoManager) checkReleasable(repoConfig *RepoConfig, tag string, opts ReleaseOptions) error {
+	dir := repoConfig.FullDir(client.workspace)
+	if client.mirrorFor(repoConfig) || repoConfig.IsReadOnly() {
+		return fmt.Errorf("mirrors and readonly repos can't be released")
+	}
+	if err := client.backend.Open(dir); err != nil {
+		return err
+	}
+	status, err := client.backend.Status(dir)
+	if err != nil {
+		return err
+	}
+	if status.Changed {
+		return fmt.Errorf("has uncommitted changes")
+	}
+	if status.Branch == "" {
+		return fmt.Errorf("HEAD is detached")
+	}
+	upstream := upstreamOf(dir)
+	if upstream == "" {
+		return fmt.Errorf("%s has no upstream branch", status.Branch)
+	}
+	cli := client.gitCLI()
+	if _, err := cli.originGit(dir, "fetch", "origin"); err != nil {
+		return err
+	}
+	ahead, behind, err := aheadBehind(dir, upstream)
+	if err != nil {
+		return err
+	}
+	if ahead > 0 || behind > 0 {
+		return fmt.Errorf("%d ahead and %d behind %s, sync first", ahead, behind, upstream)
+	}
+	if _, err := runGit(dir, "rev-parse", "--verify", "--quiet", "refs/tags/"+tag); err == nil {
+		return fmt.Errorf("tag %s exists already", tag)
+	}
+	if out, err := cli.originGit(dir, "ls-remote", "--tags", "origin", "refs/tags/"+tag); err != nil {
+		return err
+	} else if out != "" {
+		return fmt.Errorf("tag %s exists on origin already", tag)
+	}
+	if opts.CreateRelease {
+		if _, err := client.providerFor(repoConfig); err != nil {
+			return err
+		}
+		if _, err := projectPath(repoConfig.RemoteURL()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Release tags HEAD of the selected repos as tag, pushes the tag and, with
+// opts.CreateRelease, creates a release on the provider of each repo. Every
+// repo is checked to be clean and in sync with its upstream first, nothing is
+// done unless all of them are. When a repo fails the releases, pushed tags
+// and tags already created are deleted again.
+func (client *RepoManager) Release(tag string, opts ReleaseOptions) error {
+	client.logger.Info("releasing", "workspace", client.workspace, "tag", tag)
+	max := client.nameWidth()
+	repoConfigs := client.selectedRepos(opts.Select)
+	if len(repoConfigs) == 0 {
+		return fmt.Errorf("no repos selected")
+	}
+
+	errs := make(map[string]error)
+	for _, repoConfig := range repoConfigs {
+		client.logger.Debug("checking", "repo", repoConfig.Name)
+		if err := client.checkReleasable(repoConfig, tag, opts); err != nil {
+			client.printRepoLine(max, repoConfig.Name, err)
+			errs[repoConfig.Name] = err
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("not releasing %s: %w", tag, &BatchError{Errors: errs})
+	}
+
+	history := client.newHistory("release")
+	defer history.record()
+	var tagged, pushed []*RepoConfig
+	var releases []*providerRelease
+	rollback := func(repoConfig *RepoConfig, cause error) error {
+		client.printRepoLine(max, repoConfig.Name, cause)
+		history.fail(repoConfig, headOf(repoConfig.FullDir(client.workspace)), cause)
+		for _, release := range releases {
+			client.logger.Warn("deleting release", "project", release.project, "tag", tag)
+			if err := release.delete(); err != nil {
+				client.printRepoLine(max, release.project, err)
+			}
+		}
+		cli := client.gitCLI()
+		for _, repoConfig := range pushed {
+			client.logger.Warn("deleting remote tag", "repo", repoConfig.Name, "tag", tag)
+			if _, err := cli.originGit(repoConfig.FullDir(client.workspace), "push", "origin", ":refs/tags/"+tag); err != nil {
+				client.printRepoLine(max, repoConfig.Name, err)
+			}
+		}
+		for _, other := range tagged {
+			client.logger.Warn("deleting tag", "repo", other.Name, "tag", tag)
+			if _, err := runGit(other.FullDir(client.workspace), "tag", "--delete", tag); err != nil {
+				client.printRepoLine(max, other.Name, err)
+			}
+			if other != repoConfig {
+				history.add(other, headOf(other.FullDir(client.workspace)), outcomeSkipped, "rolled back")
+			}
+		}
+		return fmt.Errorf("release %s rolled back: %w", tag, &BatchError{Errors: map[string]error{repoConfig.Name: cause}})
+	}
+
+	tagOpts := TagOptions{Message: opts.Message, Annotate: true, Sign: opts.Sign}
+	cli := client.gitCLI()
+	for _, repoConfig := range repoConfigs {
+		dir := repoConfig.FullDir(client.workspace)
+		if client.interrupted() {
+			return rollback(repoConfig, ErrInterrupted)
+		}
+		if _, err := runGit(dir, tagOpts.args(tag)...); err != nil {
+			return rollback(repoConfig, err)
+		}
+		tagged = append(tagged, repoConfig)
+		if _, err := cli.originGit(dir, "push", "origin", "refs/tags/"+tag); err != nil {
+			return rollback(repoConfig, err)
+		}
+		pushed = append(pushed, repoConfig)
+		if opts.CreateRelease {
+			provider, _ := client.providerFor(repoConfig)
+			project, _ := projectPath(repoConfig.RemoteURL())
+			release, err := provider.createRelease(project, tag, opts)
+			if err != nil {
+				return rollback(repoConfig, fmt.Errorf("creating the release: %w", err))
+			}
+			releases = append(releases, release)
+		}
+		client.printRepoLine(max, repoConfig.Name, "released "+tag)
+	}
+	client.recordTagged(history, tagged, "released "+tag)
+	return nil
+}
@@ -0,0 +1,82 @@
+package repos
+
+import (
+	"os"
+)
+
+type FixRemoteOptions struct {
+	// FromDisk updates the config from the origin on disk instead of the
+	// other way around.
+	FromDisk bool
+}
+
+// remoteDiffers reports whether the origin of an existing repo differs from
+// its configured url.
+func remoteDiffers(repoConfig *RepoConfig, dir string) bool {
+	if repoConfig.Url == "" {
+		return false
+	}
+	origin := originOf(dir)
+	return origin != "" && origin != repoConfig.RemoteURL()
+}
+
+// FixRemote makes the origin of every repo match its configured url, or with
+// FromDisk updates the configured urls to the origins on disk.
+func (client *RepoManager) FixRemote(opts FixRemoteOptions) error {
+	client.logger.Info("fixing remotes", "workspace", client.workspace)
+	max := client.nameWidth()
+	changed := false
+	history := client.newHistory("fix-remote")
+	defer history.record()
+	var failed []string
+	for _, repoConfig := range client.sortedRepos() {
+		if client.failingFast(failed) {
+			break
+		}
+		dir := repoConfig.FullDir(client.workspace)
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		origin := originOf(dir)
+		head := headOf(dir)
+		switch {
+		case opts.FromDisk:
+			if origin == "" || origin == repoConfig.RemoteURL() {
+				continue
+			}
+			client.printRepoLine(max, repoConfig.Name, "url "+repoConfig.Url+" -> "+origin)
+			history.add(repoConfig, head, outcomeSucceeded, "url "+repoConfig.Url+" -> "+origin)
+			repoConfig.Url = origin
+			changed = true
+		case repoConfig.Url == "" || origin == repoConfig.RemoteURL():
+			continue
+		case origin == "":
+			if _, err := runGit(dir, "remote", "add", "origin", repoConfig.RemoteURL()); err != nil {
+				client.printRepoLine(max, repoConfig.Name, err)
+				history.fail(repoConfig, head, err)
+				failed = append(failed, repoConfig.Name)
+				continue
+			}
+			client.printRepoLine(max, repoConfig.Name, "origin added "+repoConfig.RemoteURL())
+			history.add(repoConfig, head, outcomeSucceeded, "origin added "+repoConfig.RemoteURL())
+		default:
+			if _, err := runGit(dir, "remote", "set-url", "origin", repoConfig.RemoteURL()); err != nil {
+				client.printRepoLine(max, repoConfig.Name, err)
+				history.fail(repoConfig, head, err)
+				failed = append(failed, repoConfig.Name)
+				continue
+			}
+			client.printRepoLine(max, repoConfig.Name, "origin "+origin+" -> "+repoConfig.RemoteURL())
+			history.add(repoConfig, head, outcomeSucceeded, "origin "+origin+" -> "+repoConfig.RemoteURL())
+		}
+	}
+	if changed {
+		if err := client.config.Save(); err != nil {
+			return err
+		}
+	}
+	if len(failed) > 0 {
+		return failedIn("fix-remote", failed)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package repos
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+type ReplaceOptions struct {
+	// Select limits the repos to replace in.
+	Select ListOptions
+	// Globs limits the tracked files to replace in, e.g. **/*.go.
+	Globs []string
+	// DryRun prints the diff and leaves the files as they were.
+	DryRun bool
+	// Commit commits the changes of every repo with Message.
+	Commit bool
+	// Message templates the commit message like CommitOptions.Message. It
+	// also gets .Repo, .Pattern, .With and .Files, the number of changed
+	// files.
+	Message string
+}
+
+const defaultReplaceMessage = "Replace {{.Pattern}} with {{.With}}"
+
+type replaceMessageData struct {
+	MessageData
+	Repo    string
+	Pattern string
+	With    string
+	Files   int
+}
+
+// replaceResult is what a replacement changed in one repo, with the
+// original content of every changed file to undo a dry run.
+type replaceResult struct {
+	files     []string
+	originals map[string][]byte
+	diff      string
+	err       error
+}
+
+// replaceInRepo replaces every match of re in the tracked files of dir
+// matching globs and returns the diff of the changes.
+func replaceInRepo(dir string, re *regexp.Regexp, with string, globs []string, name string) *replaceResult {
+	result := &replaceResult{originals: make(map[string][]byte)}
+	args := []string{"ls-files", "-z", "--"}
+	for _, glob := range globs {
+		args = append(args, ":(glob)"+glob)
+	}
+	out, err := runGit(dir, args...)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	for _, file := range strings.Split(out, "\x00") {
+		if file == "" {
+			continue
+		}
+		path := filepath.Join(dir, file)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			// Deleted in the working tree or not a regular file.
+			continue
+		}
+		// Leave binary files alone, as git grep does.
+		if bytes.IndexByte(data, 0) >= 0 || !re.Match(data) {
+			continue
+		}
+		replaced := re.ReplaceAll(data, []byte(with))
+		if bytes.Equal(replaced, data) {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			result.err = err
+			return result
+		}
+		if err := os.WriteFile(path, replaced, info.Mode().Perm()); err != nil {
+			result.err = err
+			return result
+		}
+		result.files = append(result.files, file)
+		result.originals[file] = data
+	}
+	if len(result.files) == 0 {
+		return result
+	}
+	diffArgs := []string{"diff", "--no-color", "--src-prefix=a/" + name + "/", "--dst-prefix=b/" + name + "/", "--"}
+	result.diff, result.err = runGit(dir, append(diffArgs, result.files...)...)
+	return result
+}
+
+// undo writes the original content back to the changed files.
+func (result *replaceResult) undo(dir string) error {
+	for _, file := range result.files {
+		path := filepath.Join(dir, file)
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, result.originals[file], info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replace replaces every match of the regular expression pattern in the
+// tracked files of the selected repos with with, which can refer to groups
+// as $1, and prints the combined diff. Repos with uncommitted changes are
+// skipped so that a commit only holds the replacement.
+func (client *RepoManager) Replace(pattern, with string, opts ReplaceOptions) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	message := opts.Message
+	if message == "" {
+		message = defaultReplaceMessage
+	}
+	tmpl, err := parseMessage(message)
+	if err != nil {
+		return err
+	}
+
+	client.logger.Info("replacing", "pattern", pattern, "with", with, "workspace", client.workspace)
+	max := client.nameWidth()
+	repoConfigs := client.selectedRepos(opts.Select)
+	results := make([]*replaceResult, len(repoConfigs))
+	wg := sync.WaitGroup{}
+	for i, repoConfig := range repoConfigs {
+		if client.mirrorFor(repoConfig) {
+			continue
+		}
+		dir := repoConfig.FullDir(client.workspace)
+		status, err := client.backend.Status(dir)
+		if err != nil {
+			results[i] = &replaceResult{err: err}
+			continue
+		}
+		if status.Changed {
+			client.printRepoLine(max, repoConfig.Name, client.paint(colorYellow, "skipped, dirty"))
+			continue
+		}
+		wg.Add(1)
+		go func(i int, repoConfig *RepoConfig, dir string) {
+			defer wg.Done()
+			client.logger.Debug("replacing", "repo", repoConfig.Name)
+			results[i] = replaceInRepo(dir, re, with, opts.Globs, repoConfig.Name)
+		}(i, repoConfig, dir)
+	}
+	wg.Wait()
+
+	var changed []*RepoConfig
+	files := make(map[string][]string)
+	errs := make(map[string]error)
+	for i, repoConfig := range repoConfigs {
+		result := results[i]
+		if result == nil {
+			continue
+		}
+		if result.err != nil {
+			errs[repoConfig.Name] = result.err
+			continue
+		}
+		if len(result.files) == 0 {
+			continue
+		}
+		fmt.Println(result.diff)
+		changed = append(changed, repoConfig)
+		files[repoConfig.Name] = result.files
+		if opts.DryRun {
+			if err := result.undo(repoConfig.FullDir(client.workspace)); err != nil {
+				errs[repoConfig.Name] = err
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return &BatchError{Errors: errs}
+	}
+	if opts.DryRun || !opts.Commit {
+		return nil
+	}
+
+	return client.runBatch("replace", changed, func(repoConfig *RepoConfig) (outcome, string, error) {
+		data := replaceMessageData{MessageData: client.messageData(repoConfig), Repo: repoConfig.Name, Pattern: pattern, With: with, Files: len(files[repoConfig.Name])}
+		message, err := executeMessage(tmpl, data)
+		if err != nil {
+			return outcomeFailed, "", err
+		}
+		dir := repoConfig.FullDir(client.workspace)
+		args := append([]string{"commit", "-m", message, "--"}, files[repoConfig.Name]...)
+		if _, err := runGit(dir, args...); err != nil {
+			return outcomeFailed, "", err
+		}
+		return outcomeSucceeded, "", nil
+	})
+}
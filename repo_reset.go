@@ -0,0 +1,111 @@
+package repos
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ResetOptions selects the repos ResetToRemote resets. Every repo needs
+// Confirm to approve it, repos it declines are skipped.
+type ResetOptions struct {
+	Select ListOptions
+	// Confirm is asked with what would be discarded, such as "2 local
+	// commits and uncommitted changes".
+	Confirm func(repo, discarded string) bool
+}
+
+// backupBranchPrefix is what the branches reset-to-remote keeps the
+// discarded commits on start with.
+const backupBranchPrefix = "repos-backup/"
+
+// discardedBy describes what resetting a branch that is ahead of its
+// upstream and has dirty changes or not throws away.
+func discardedBy(ahead int, dirty bool) string {
+	var parts []string
+	switch ahead {
+	case 0:
+	case 1:
+		parts = append(parts, "1 local commit")
+	default:
+		parts = append(parts, fmt.Sprintf("%d local commits", ahead))
+	}
+	if dirty {
+		parts = append(parts, "uncommitted changes")
+	}
+	return strings.Join(parts, " and ")
+}
+
+func (client *RepoManager) resetRepoToRemote(repoConfig *RepoConfig, opts ResetOptions) (outcome, string, error) {
+	if client.mirrorFor(repoConfig) {
+		return outcomeSkipped, "mirror", nil
+	}
+	dir := repoConfig.FullDir(client.workspace)
+	branch, err := runGit(dir, "symbolic-ref", "--short", "--quiet", "HEAD")
+	if err != nil || branch == "" {
+		return outcomeSkipped, "detached HEAD", nil
+	}
+	upstream := upstreamOf(dir)
+	if upstream == "" {
+		return outcomeSkipped, "no upstream", nil
+	}
+	if _, err := client.gitCLI().originGit(dir, "fetch", "origin"); err != nil {
+		return outcomeFailed, "", err
+	}
+	ahead, behind, err := aheadBehind(dir, upstream)
+	if err != nil {
+		return outcomeFailed, "", err
+	}
+	clean, err := IfRepoIsClean(dir)
+	if err != nil {
+		return outcomeFailed, "", err
+	}
+	dirty := !clean
+	if ahead == 0 && !dirty {
+		if behind > 0 {
+			return outcomeUpToDate, fmt.Sprintf("not diverged, %d behind %s", behind, upstream), nil
+		}
+		return outcomeUpToDate, "", nil
+	}
+	if opts.Confirm == nil || !opts.Confirm(repoConfig.Name, discardedBy(ahead, dirty)) {
+		return outcomeSkipped, "reset not confirmed", nil
+	}
+
+	var kept []string
+	if ahead > 0 {
+		backup := backupBranchPrefix + branch + "-" + time.Now().Format("20060102-150405")
+		if _, err := runGit(dir, "branch", backup, "HEAD"); err != nil {
+			return outcomeFailed, "", err
+		}
+		kept = append(kept, "commits on "+backup)
+	}
+	if dirty {
+		message := stashMessage("before reset-to-remote " + upstream)
+		if _, err := runGit(dir, "stash", "push", "--include-untracked", "-m", message); err != nil {
+			return outcomeFailed, "", err
+		}
+		kept = append(kept, "changes stashed")
+	}
+	client.logger.Info("resetting to upstream", "repo", repoConfig.Name, "upstream", upstream, "ahead", ahead, "dirty", dirty)
+	if _, err := runGit(dir, "reset", "--hard", upstream); err != nil {
+		return outcomeFailed, "", err
+	}
+	return outcomeSucceeded, "reset to " + upstream + ", " + strings.Join(kept, ", "), nil
+}
+
+// ResetToRemote hard-resets the checked out branch of every selected repo
+// with local commits or changes to its upstream as of a fresh fetch, after
+// opts.Confirm approves it. The local commits are kept on a backup branch
+// and uncommitted changes, untracked files included, in a stash first, so
+// nothing is lost for good.
+func (client *RepoManager) ResetToRemote(opts ResetOptions) error {
+	for _, name := range opts.Select.Only {
+		if _, err := client.repoConfigOf(name); err != nil {
+			return err
+		}
+	}
+	client.logger.Info("resetting to remote", "workspace", client.workspace)
+	return client.runBatch("reset-to-remote", client.selectedRepos(opts.Select), func(repoConfig *RepoConfig) (outcome, string, error) {
+		return client.resetRepoToRemote(repoConfig, opts)
+	})
+}
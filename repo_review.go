@@ -0,0 +1,99 @@
+package repos
+
+import (
+	"fmt"
+	"strings"
+)
+
+// reasonDeclined is the skip reason of repos declined in review.
+const reasonDeclined = "declined in review"
+
+// WithReview makes push and sync show what is about to leave the machine, and
+// commit what is about to be committed, for every repo and only go ahead with
+// the repos review approves. Repos with nothing to push or commit aren't
+// reviewed.
+func WithReview(review func(repo, preview string) bool) NewRepoManagerClientOptions {
+	return func(client *RepoManager) {
+		client.review = review
+	}
+}
+
+// outgoing describes what pushing the checked out branch of the repo in dir
+// would publish: the commits its upstream doesn't have with their diffstat.
+// It is empty when there is nothing to push.
+func outgoing(dir string) (string, error) {
+	branch, _ := runGit(dir, "symbolic-ref", "--short", "--quiet", "HEAD")
+	upstream := upstreamOf(dir)
+	if upstream == "" {
+		commits, err := runGit(dir, "log", "--oneline", "HEAD", "--not", "--remotes")
+		if err != nil {
+			return "", err
+		}
+		preview := fmt.Sprintf("%s has no upstream branch, pushing creates it on origin", branch)
+		if commits != "" {
+			preview += "\n" + commits
+		}
+		return preview, nil
+	}
+	commits, err := runGit(dir, "log", "--oneline", upstream+"..HEAD")
+	if err != nil || commits == "" {
+		return "", err
+	}
+	stat, err := runGit(dir, "diff", "--stat", upstream+"...HEAD")
+	if err != nil {
+		return "", err
+	}
+	var preview strings.Builder
+	fmt.Fprintf(&preview, "%d commit(s) on %s to push to %s\n", len(strings.Split(commits, "\n")), branch, upstream)
+	preview.WriteString(commits + "\n" + stat)
+	return preview.String(), nil
+}
+
+// reviewed asks the review for the repo and reports whether to go ahead,
+// which it always does without a review or anything to push.
+func (client *RepoManager) reviewed(repoConfig *RepoConfig) (bool, error) {
+	if client.review == nil {
+		return true, nil
+	}
+	preview, err := outgoing(repoConfig.FullDir(client.workspace))
+	if err != nil || preview == "" {
+		return err == nil, err
+	}
+	return client.review(repoConfig.Name, preview), nil
+}
+
+// uncommitted describes what committing every change of the repo in dir
+// with message would record: the message, the changed files, untracked ones
+// included, and the diffstat of the tracked ones.
+func uncommitted(dir, message string) (string, error) {
+	files, err := runGit(dir, "status", "--short", "--untracked-files=all")
+	if err != nil {
+		return "", err
+	}
+	var preview strings.Builder
+	fmt.Fprintf(&preview, "commit message:\n  %s\n%s", strings.ReplaceAll(message, "\n", "\n  "), files)
+	// A repo without commits has nothing to diff against.
+	if headOf(dir) != "" {
+		stat, err := runGit(dir, "diff", "--stat", "HEAD")
+		if err != nil {
+			return "", err
+		}
+		if stat != "" {
+			preview.WriteString("\n" + stat)
+		}
+	}
+	return preview.String(), nil
+}
+
+// reviewedCommit asks the review whether to commit the changes of the repo
+// with message, which it always does without a review.
+func (client *RepoManager) reviewedCommit(repoConfig *RepoConfig, message string) (bool, error) {
+	if client.review == nil {
+		return true, nil
+	}
+	preview, err := uncommitted(repoConfig.FullDir(client.workspace), message)
+	if err != nil {
+		return false, err
+	}
+	return client.review(repoConfig.Name, preview), nil
+}
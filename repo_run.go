@@ -0,0 +1,208 @@
+package repos
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrReposFailed matches, with errors.Is, the errors of operations that failed
+// in some of the repos, as opposed to configuration or authentication problems.
+var ErrReposFailed = errors.New("operation failed in some repos")
+
+// BatchError collects the errors of the repos that failed in a batch operation.
+type BatchError struct {
+	Errors map[string]error
+}
+
+func (e *BatchError) Is(target error) bool {
+	return target == ErrReposFailed
+}
+
+func (e *BatchError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("%s: %v", name, e.Errors[name]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+type failedInError struct {
+	op    string
+	repos []string
+}
+
+func (e *failedInError) Error() string {
+	return fmt.Sprintf("%s failed in %s", e.op, strings.Join(e.repos, ", "))
+}
+
+func (e *failedInError) Is(target error) bool {
+	return target == ErrReposFailed
+}
+
+// failedIn reports that op failed in the given repos.
+func failedIn(op string, repos []string) error {
+	return &failedInError{op: op, repos: repos}
+}
+
+// checkDependencies makes sure every after entry names a configured repo and
+// that the dependencies among the selected repos don't form a cycle.
+func (client *RepoManager) checkDependencies(selected map[string]*RepoConfig) error {
+	for _, repoConfig := range selected {
+		for _, dep := range repoConfig.After {
+			if _, ok := client.config.Repos[dep]; !ok {
+				return fmt.Errorf("%s runs after %s which is not configured", repoConfig.Name, dep)
+			}
+		}
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(path, name), " -> "))
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range selected[name].After {
+			if _, ok := selected[dep]; !ok {
+				continue
+			}
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for name := range selected {
+		if err := visit(name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defaultJobs is how many repos are worked on at once unless configured.
+const defaultJobs = 8
+
+// WithJobs limits how many repos batch operations work on at once, overriding
+// the jobs config setting when positive.
+func WithJobs(jobs int) NewRepoManagerClientOptions {
+	return func(client *RepoManager) {
+		client.jobs = jobs
+	}
+}
+
+func (client *RepoManager) jobsLimit() int {
+	if client.jobs > 0 {
+		return client.jobs
+	}
+	if client.config.Jobs > 0 {
+		return client.config.Jobs
+	}
+	return defaultJobs
+}
+
+// skippedError is returned by runOrdered for repos it didn't run because a
+// repo they come after failed or was skipped itself.
+type skippedError struct {
+	reason string
+}
+
+func (e *skippedError) Error() string {
+	return "skipped, " + e.reason
+}
+
+// runOrdered calls fn for every repo in parallel, at most jobsLimit at once,
+// at most as many per remote host as host_jobs allows and one at a time per
+// serial group, except that a repo only starts once the repos listed in its
+// after field have finished. Repos whose dependencies failed are not run and
+// end with a skippedError.
+// Dependencies outside repoConfigs, such as disabled repos, are ignored.
+func (client *RepoManager) runOrdered(repoConfigs []*RepoConfig, fn func(*RepoConfig) error) error {
+	selected := make(map[string]*RepoConfig, len(repoConfigs))
+	done := make(map[string]chan struct{}, len(repoConfigs))
+	for _, repoConfig := range repoConfigs {
+		selected[repoConfig.Name] = repoConfig
+		done[repoConfig.Name] = make(chan struct{})
+	}
+	if err := client.checkDependencies(selected); err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	errs := make(map[string]error)
+	failed := func(name string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return errs[name] != nil
+	}
+
+	// Slots are only taken once the dependencies are done, so waiting repos
+	// can't starve the ones they wait for. The serial group and host slots
+	// come first, so repos waiting for them don't hold up the others.
+	slots := make(chan struct{}, client.jobsLimit())
+	hosts := newHostLimiter(client.config.HostJobs)
+	serial := make(map[string]chan struct{})
+	for _, repoConfig := range repoConfigs {
+		if group := repoConfig.SerialGroup; group != "" && serial[group] == nil {
+			serial[group] = make(chan struct{}, 1)
+		}
+	}
+	wg := sync.WaitGroup{}
+	for _, repoConfig := range repoConfigs {
+		wg.Add(1)
+		go func(repoConfig *RepoConfig) {
+			defer wg.Done()
+			defer close(done[repoConfig.Name])
+
+			var err error
+			for _, dep := range repoConfig.After {
+				if _, ok := selected[dep]; !ok {
+					continue
+				}
+				<-done[dep]
+				if failed(dep) {
+					err = &skippedError{reason: dep + " failed"}
+					break
+				}
+			}
+			if err == nil {
+				if lock := serial[repoConfig.SerialGroup]; lock != nil {
+					lock <- struct{}{}
+					defer func() { <-lock }()
+				}
+				release := hosts.acquire(remoteHost(repoConfig))
+				slots <- struct{}{}
+				err = fn(repoConfig)
+				<-slots
+				release()
+			}
+			if err != nil {
+				mu.Lock()
+				errs[repoConfig.Name] = err
+				mu.Unlock()
+			}
+		}(repoConfig)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return &BatchError{Errors: errs}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package repos
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunBatchSkipsDependentsOfFailedRepos(t *testing.T) {
+	workspace := t.TempDir()
+	config := &ReposConfig{
+		CfgFile: filepath.Join(workspace, "repos.yaml"),
+		Repos: map[string]*RepoConfig{
+			"lib":   {Name: "lib", Dir: "lib"},
+			"app":   {Name: "app", Dir: "app", After: []string{"lib"}},
+			"cli":   {Name: "cli", Dir: "cli", After: []string{"app"}},
+			"other": {Name: "other", Dir: "other"},
+		},
+	}
+	client, err := NewRepoManager(WithConfig(config), WithVerbosity(VerbosityQuiet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var repoConfigs []*RepoConfig
+	for _, name := range []string{"app", "cli", "lib", "other"} {
+		repoConfigs = append(repoConfigs, config.Repos[name])
+	}
+
+	err = client.runBatch("sync", repoConfigs, func(repoConfig *RepoConfig) (outcome, string, error) {
+		if repoConfig.Name == "lib" {
+			return outcomeFailed, "", errors.New("broken")
+		}
+		return outcomeSucceeded, "", nil
+	})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || batchErr.Errors["lib"] == nil {
+		t.Fatalf("runBatch() = %v, want only lib failed", err)
+	}
+
+	want := map[string]repoOutcome{
+		"lib":   {outcome: outcomeFailed, reason: "broken"},
+		"app":   {outcome: outcomeSkipped, reason: "lib failed"},
+		"cli":   {outcome: outcomeSkipped, reason: "app failed"},
+		"other": {outcome: outcomeSucceeded},
+	}
+	got := make(map[string]repoOutcome)
+	for _, o := range client.lastSummary.outcomes {
+		got[o.name] = o
+	}
+	for name, w := range want {
+		if o := got[name]; o.outcome != w.outcome || o.reason != w.reason {
+			t.Errorf("%s is %s %q, want %s %q", name, o.outcome, o.reason, w.outcome, w.reason)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package repos
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"gopkg.in/yaml.v3"
+)
+
+// Encrypted config values look like ENC[<base64 of nonce and ciphertext>],
+// sealed with XChaCha20-Poly1305 under the master key.
+const (
+	encryptedPrefix = "ENC["
+	encryptedSuffix = "]"
+	// masterKeyEnv holds the base64 master key, taking precedence over the
+	// one in the OS keyring.
+	masterKeyEnv = "REPOS_MASTER_KEY"
+	// masterKeyName is the name of the master key in the OS keyring.
+	masterKeyName = "master-key"
+)
+
+func isEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix) && strings.HasSuffix(value, encryptedSuffix)
+}
+
+// masterKey returns the master key from REPOS_MASTER_KEY or else the OS
+// keyring.
+func masterKey() ([]byte, error) {
+	encoded := os.Getenv(masterKeyEnv)
+	if encoded == "" {
+		var err error
+		if encoded, err = credentials.Get(masterKeyName); err != nil {
+			return nil, fmt.Errorf("no master key, set %s or run repos config keygen: %w", masterKeyEnv, err)
+		}
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != chacha20poly1305.KeySize {
+		return nil, fmt.Errorf("the master key must be %d base64 encoded bytes", chacha20poly1305.KeySize)
+	}
+	return key, nil
+}
+
+// GenerateMasterKey creates a random master key and stores it in the OS
+// keyring unless one is there already. It returns the base64 key, for
+// setting REPOS_MASTER_KEY on machines without a keyring.
+func GenerateMasterKey() (string, error) {
+	if _, err := credentials.Get(masterKeyName); err == nil {
+		return "", fmt.Errorf("the keyring already has a master key")
+	} else if !errors.Is(err, ErrCredentialNotFound) {
+		return "", err
+	}
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	encoded := base64.StdEncoding.EncodeToString(key)
+	return encoded, credentials.Set(masterKeyName, encoded)
+}
+
+// EncryptValue encrypts a config value with the master key.
+func EncryptValue(plaintext string) (string, error) {
+	key, err := masterKey()
+	if err != nil {
+		return "", err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed) + encryptedSuffix, nil
+}
+
+func decryptValue(key []byte, value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(strings.TrimPrefix(value, encryptedPrefix), encryptedSuffix))
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %w", err)
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("invalid encrypted value")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("can't decrypt a value, wrong master key?")
+	}
+	return string(plaintext), nil
+}
+
+// secretPath joins the YAML keys and list indexes leading to a value, as
+// the secrets of a config are keyed.
+func secretPath(path []string) string {
+	return strings.Join(path, "\x00")
+}
+
+// secret is an encrypted value of the config file with its plaintext.
+type secret struct {
+	plaintext string
+	encrypted string
+}
+
+// DecryptSecrets replaces the encrypted values of the config with their
+// plaintext. The master key is only needed when there are any. Save and
+// Marshal put the encrypted values back where they were.
+func (config *ReposConfig) DecryptSecrets() error {
+	var key []byte
+	var err error
+	walkStrings(reflect.ValueOf(config), nil, func(path []string, value string) string {
+		if err != nil || !isEncrypted(value) {
+			return value
+		}
+		if key == nil {
+			if key, err = masterKey(); err != nil {
+				return value
+			}
+		}
+		var plaintext string
+		if plaintext, err = decryptValue(key, value); err != nil {
+			return value
+		}
+		if config.secrets == nil {
+			config.secrets = make(map[string]secret)
+		}
+		config.secrets[secretPath(path)] = secret{plaintext: plaintext, encrypted: value}
+		return plaintext
+	})
+	return err
+}
+
+// yamlName returns the key of a struct field in YAML, empty for fields YAML
+// leaves out.
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// walkStrings replaces every string reachable from v, in struct fields, maps,
+// slices and pointers, with what fn returns for it. fn is given the YAML
+// path of the string, starting at path.
+func walkStrings(v reflect.Value, path []string, fn func(path []string, value string) string) {
+	at := func(key string) []string {
+		return append(append([]string{}, path...), key)
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			walkStrings(v.Elem(), path, fn)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if name := yamlName(field); field.IsExported() && name != "" {
+				walkStrings(v.Field(i), at(name), fn)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			walkStrings(v.Index(i), at(strconv.Itoa(i)), fn)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			value := iter.Value()
+			key := at(fmt.Sprint(iter.Key().Interface()))
+			if value.Kind() == reflect.String {
+				v.SetMapIndex(iter.Key(), reflect.ValueOf(fn(key, value.String())).Convert(value.Type()))
+				continue
+			}
+			walkStrings(value, key, fn)
+		}
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(fn(path, v.String()))
+		}
+	}
+}
+
+// Marshal returns the config as YAML with the decrypted values encrypted
+// again, as they were in the file.
+func (config *ReposConfig) Marshal() ([]byte, error) {
+	return config.marshalEncrypted(config)
+}
+
+// marshalEncrypted returns value as YAML with the secrets of the config
+// encrypted.
+func (config *ReposConfig) marshalEncrypted(value interface{}) ([]byte, error) {
+	data, err := yaml.Marshal(value)
+	if err != nil || len(config.root().secrets) == 0 {
+		return data, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	secrets := config.root().secrets
+	var encrypt func(node *yaml.Node, path []string)
+	encrypt = func(node *yaml.Node, path []string) {
+		switch node.Kind {
+		case yaml.DocumentNode:
+			for _, child := range node.Content {
+				encrypt(child, path)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				encrypt(node.Content[i+1], append(append([]string{}, path...), node.Content[i].Value))
+			}
+		case yaml.SequenceNode:
+			for i, child := range node.Content {
+				encrypt(child, append(append([]string{}, path...), strconv.Itoa(i)))
+			}
+		case yaml.ScalarNode:
+			// A list of one value may be written as the value, as Branches
+			// does.
+			for _, key := range []string{secretPath(path), secretPath(append(path, "0"))} {
+				if secret, ok := secrets[key]; ok && secret.plaintext == node.Value {
+					node.Value, node.Style = secret.encrypted, 0
+					break
+				}
+			}
+		}
+	}
+	encrypt(&doc, nil)
+	return yaml.Marshal(&doc)
+}
@@ -0,0 +1,76 @@
+package repos
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMarshalEncryptsOnlyTheSecrets(t *testing.T) {
+	t.Setenv(masterKeyEnv, base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	// The token is the same text as the branch, dir and a repo name.
+	encrypted, err := EncryptValue("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfgFile := filepath.Join(t.TempDir(), "repos.yaml")
+	data := `version: "2"
+auth:
+  github.com:
+    user: main
+    token: ` + encrypted + `
+repos:
+  main:
+    url: https://github.com/org/main.git
+    dir: main
+    branch: main
+`
+	if err := os.WriteFile(cfgFile, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfigFile(cfgFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.DecryptSecrets(); err != nil {
+		t.Fatal(err)
+	}
+	if token := config.Auth["github.com"].Token; token != "main" {
+		t.Fatalf("decrypted token is %q, want main", token)
+	}
+
+	out, err := config.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved struct {
+		Auth  map[string]*HostAuth `yaml:"auth"`
+		Repos map[string]struct {
+			Dir    string `yaml:"dir"`
+			Branch string `yaml:"branch"`
+		} `yaml:"repos"`
+	}
+	if err := yaml.Unmarshal(out, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if token := saved.Auth["github.com"].Token; token != encrypted {
+		t.Errorf("token is saved as %q, want %q", token, encrypted)
+	}
+	if user := saved.Auth["github.com"].User; user != "main" {
+		t.Errorf("user is saved as %q, want main", user)
+	}
+	repo, ok := saved.Repos["main"]
+	if !ok {
+		t.Fatalf("repo main is missing from\n%s", out)
+	}
+	if repo.Dir != "main" || repo.Branch != "main" {
+		t.Errorf("repo main is saved with dir %q and branch %q, want main", repo.Dir, repo.Branch)
+	}
+	if n := strings.Count(string(out), encryptedPrefix); n != 1 {
+		t.Errorf("%d encrypted values saved, want 1:\n%s", n, out)
+	}
+}
@@ -0,0 +1,335 @@
+package repos
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// secretsIgnoreFile lists paths scan-secrets skips, in the workspace and in
+// the root of each repo.
+const secretsIgnoreFile = ".secretsignore"
+
+// secretAllowMarker on a line keeps scan-secrets from reporting it, e.g. for
+// example keys in documentation.
+const secretAllowMarker = "repos:allow-secret"
+
+// maxSecretScanSize is the size above which files aren't scanned, they are
+// rarely hand written.
+const maxSecretScanSize = 1 << 20
+
+type SecretScanOptions struct {
+	Select ListOptions
+	// History also scans the lines added by this many of the latest commits.
+	History int
+}
+
+// SecretFinding is a line that looks like it contains a secret.
+type SecretFinding struct {
+	Repo string `json:"repo"`
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Rule string `json:"rule"`
+	// Commit is the commit that added the line when it was found in the
+	// history, empty for the files as they are.
+	Commit string `json:"commit,omitempty"`
+	// Secret is the match with most of it masked.
+	Secret string `json:"secret"`
+}
+
+type secretRule struct {
+	name    string
+	pattern *regexp.Regexp
+	// group is the submatch holding the secret, 0 for the whole match.
+	group int
+	// minEntropy, when set, is the Shannon entropy in bits per character the
+	// secret needs, to tell keys from placeholders like "changeme".
+	minEntropy float64
+}
+
+var secretRules = []secretRule{
+	{name: "private-key", pattern: regexp.MustCompile(`-----BEGIN ((RSA|DSA|EC|OPENSSH|PGP|ENCRYPTED) )?PRIVATE KEY( BLOCK)?-----`)},
+	{name: "aws-access-key", pattern: regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{name: "github-token", pattern: regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{60,})\b`)},
+	{name: "gitlab-token", pattern: regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}\b`)},
+	{name: "slack-token", pattern: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{name: "google-api-key", pattern: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{name: "stripe-key", pattern: regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{20,}\b`)},
+	{
+		name:       "generic-secret",
+		pattern:    regexp.MustCompile(`(?i)(secret|passw(or)?d|token|api[_-]?key|access[_-]?key|credential)[a-z0-9_-]*["']?\s*[:=]\s*["']([^"'\s]{12,})["']`),
+		group:      3,
+		minEntropy: 3.5,
+	},
+}
+
+// shannonEntropy returns the entropy of s in bits per character.
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(n)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// maskSecret keeps the first characters of secret to recognize it by.
+func maskSecret(secret string) string {
+	runes := []rune(secret)
+	if len(runes) <= 8 {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[:4]) + strings.Repeat("*", len(runes)-4)
+}
+
+// scanLine returns the rules line breaks and the secret each found.
+func scanLine(line string) (rules []string, secrets []string) {
+	if strings.Contains(line, secretAllowMarker) {
+		return nil, nil
+	}
+	for _, rule := range secretRules {
+		match := rule.pattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		secret := match[rule.group]
+		if rule.minEntropy > 0 && shannonEntropy(secret) < rule.minEntropy {
+			continue
+		}
+		rules = append(rules, rule.name)
+		secrets = append(secrets, secret)
+	}
+	return rules, secrets
+}
+
+// secretsIgnore is the path patterns of .secretsignore files. A pattern
+// without a slash matches file names anywhere, one ending in a slash a
+// directory and everything in it, and others paths from the repo root.
+type secretsIgnore []string
+
+// read adds the patterns of the ignore file at file, if it exists.
+func (ignore secretsIgnore) read(file string) (secretsIgnore, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return ignore, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			ignore = append(ignore, strings.TrimPrefix(line, "/"))
+		}
+	}
+	return ignore, scanner.Err()
+}
+
+// matches reports whether file, relative to the repo root and slash
+// separated, is ignored.
+func (ignore secretsIgnore) matches(file string) bool {
+	for _, pattern := range ignore {
+		switch {
+		case strings.HasSuffix(pattern, "/"):
+			if strings.HasPrefix(file, pattern) || strings.Contains(file, "/"+pattern) {
+				return true
+			}
+		case !strings.Contains(pattern, "/"):
+			if ok, _ := path.Match(pattern, path.Base(file)); ok {
+				return true
+			}
+		default:
+			if ok, _ := path.Match(pattern, file); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// scanTree scans the tracked files of the repo in dir as they are on disk.
+func scanTree(dir string, ignore secretsIgnore) ([]SecretFinding, error) {
+	out, err := runGit(dir, "ls-files", "-z")
+	if err != nil {
+		return nil, err
+	}
+	var findings []SecretFinding
+	for _, file := range strings.Split(out, "\x00") {
+		if file == "" || ignore.matches(file) {
+			continue
+		}
+		info, err := os.Lstat(filepath.Join(dir, file))
+		if err != nil || !info.Mode().IsRegular() || info.Size() > maxSecretScanSize {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			return nil, err
+		}
+		if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+			continue
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			rules, secrets := scanLine(line)
+			for j, rule := range rules {
+				findings = append(findings, SecretFinding{File: file, Line: i + 1, Rule: rule, Secret: secrets[j]})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// scanHistory scans the lines added by the latest commits of the repo in
+// dir.
+func scanHistory(dir string, commits int, ignore secretsIgnore) ([]SecretFinding, error) {
+	out, err := runGit(dir, "log", "-p", "-U0", "--no-color", "--no-ext-diff", "--format=commit %H",
+		fmt.Sprintf("--max-count=%d", commits))
+	if err != nil {
+		return nil, err
+	}
+	var findings []SecretFinding
+	var commit, file string
+	line := 0
+	for _, text := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(text, "commit "):
+			commit, file = strings.TrimPrefix(text, "commit "), ""
+		case strings.HasPrefix(text, "+++ "):
+			file = strings.TrimPrefix(strings.TrimPrefix(text, "+++ "), "b/")
+			if file == "/dev/null" || ignore.matches(file) {
+				file = ""
+			}
+		case strings.HasPrefix(text, "@@ "):
+			// @@ -a,b +c,d @@: the added lines start at c.
+			fields := strings.Fields(text)
+			if len(fields) > 2 {
+				start, _, _ := strings.Cut(strings.TrimPrefix(fields[2], "+"), ",")
+				fmt.Sscan(start, &line)
+			}
+		case strings.HasPrefix(text, "+") && file != "":
+			rules, secrets := scanLine(text[1:])
+			for j, rule := range rules {
+				findings = append(findings, SecretFinding{File: file, Line: line, Rule: rule, Commit: commit, Secret: secrets[j]})
+			}
+			line++
+		}
+	}
+	return findings, nil
+}
+
+// scanRepo scans the repo in dir, leaving out history findings of secrets
+// that are still in the files.
+func (client *RepoManager) scanRepo(repoConfig *RepoConfig, opts SecretScanOptions, ignore secretsIgnore) ([]SecretFinding, error) {
+	dir := repoConfig.FullDir(client.workspace)
+	ignore, err := ignore.read(filepath.Join(dir, secretsIgnoreFile))
+	if err != nil {
+		return nil, err
+	}
+	findings, err := scanTree(dir, ignore)
+	if err != nil {
+		return nil, err
+	}
+	if opts.History > 0 {
+		history, err := scanHistory(dir, opts.History, ignore)
+		if err != nil {
+			return nil, err
+		}
+		current := make(map[string]bool)
+		for _, finding := range findings {
+			current[finding.File+"\x00"+finding.Secret] = true
+		}
+		for _, finding := range history {
+			if !current[finding.File+"\x00"+finding.Secret] {
+				current[finding.File+"\x00"+finding.Secret] = true
+				findings = append(findings, finding)
+			}
+		}
+	}
+	for i := range findings {
+		findings[i].Repo = repoConfig.Name
+		findings[i].Secret = maskSecret(findings[i].Secret)
+	}
+	return findings, nil
+}
+
+// ScanSecrets looks for keys, tokens and passwords in the tracked files, and
+// with opts.History in the latest commits, of the selected repos in
+// parallel. Paths listed in the .secretsignore files of the workspace and of
+// the repos are skipped, as are lines marked with repos:allow-secret. Repos
+// that couldn't be scanned are reported in a *BatchError next to the
+// findings of the others.
+func (client *RepoManager) ScanSecrets(opts SecretScanOptions) ([]SecretFinding, error) {
+	client.logger.Info("scanning for secrets", "workspace", client.workspace)
+	ignore, err := secretsIgnore(nil).read(filepath.Join(client.workspace, secretsIgnoreFile))
+	if err != nil {
+		return nil, err
+	}
+	var repoConfigs []*RepoConfig
+	for _, repoConfig := range client.selectedRepos(opts.Select) {
+		if isGitRepo(repoConfig.FullDir(client.workspace)) && !client.mirrorFor(repoConfig) {
+			repoConfigs = append(repoConfigs, repoConfig)
+		}
+	}
+
+	results := make([][]SecretFinding, len(repoConfigs))
+	errs := make(map[string]error)
+	var mu sync.Mutex
+	slots := make(chan struct{}, client.jobsLimit())
+	wg := sync.WaitGroup{}
+	for i, repoConfig := range repoConfigs {
+		wg.Add(1)
+		go func(i int, repoConfig *RepoConfig) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			client.logger.Debug("scanning", "repo", repoConfig.Name)
+			findings, err := client.scanRepo(repoConfig, opts, ignore)
+			if err != nil {
+				mu.Lock()
+				errs[repoConfig.Name] = err
+				mu.Unlock()
+				return
+			}
+			results[i] = findings
+		}(i, repoConfig)
+	}
+	wg.Wait()
+
+	findings := []SecretFinding{}
+	for _, result := range results {
+		findings = append(findings, result...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Repo != b.Repo {
+			return a.Repo < b.Repo
+		}
+		if (a.Commit == "") != (b.Commit == "") {
+			return a.Commit == ""
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	if len(errs) > 0 {
+		return findings, &BatchError{Errors: errs}
+	}
+	return findings, nil
+}
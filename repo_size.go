@@ -0,0 +1,221 @@
+package repos
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxFileSize is the size from which files are too large to push
+// unless max_file_size says otherwise, where GitHub starts warning.
+const defaultMaxFileSize = 50 << 20
+
+// ParseSize parses sizes like 512, 100k, 50MB or 1.5GiB, in bytes with
+// binary multiples.
+func ParseSize(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "IB"), "B")
+	multiple := int64(1)
+	if value != "" {
+		if i := strings.IndexByte("KMGT", value[len(value)-1]); i >= 0 {
+			multiple = 1 << (10 * (i + 1))
+			value = value[:len(value)-1]
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q, e.g. 50MB", s)
+	}
+	return int64(n * float64(multiple)), nil
+}
+
+// maxFileSize is the limit of the max_file_size config setting, 0 when
+// set to 0 to turn the check off.
+func (client *RepoManager) maxFileSize() (int64, error) {
+	if client.config.MaxFileSize == "" {
+		return defaultMaxFileSize, nil
+	}
+	return ParseSize(client.config.MaxFileSize)
+}
+
+// LargeFile is a file over the size limit, in the working tree of a repo or
+// added by a commit that isn't pushed yet.
+type LargeFile struct {
+	Repo string `json:"repo"`
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	// Commit is the unpushed commit that added the file, empty for files in
+	// the working tree.
+	Commit string `json:"commit,omitempty"`
+}
+
+// runGitInput runs git like runGitEnv with input on its stdin.
+func runGitInput(dir, input string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+// largeOutgoingBlobs returns the files over limit added by the commits that
+// pushing spec would publish, those no remote branch has.
+func largeOutgoingBlobs(dir string, spec PushSpec, limit int64) ([]LargeFile, error) {
+	args := []string{"rev-list", "--objects", "HEAD"}
+	if spec.AllBranches {
+		args = append(args, "--branches")
+	}
+	if spec.Tags {
+		args = append(args, "--tags")
+	}
+	objects, err := runGit(dir, append(args, "--not", "--remotes")...)
+	if err != nil || objects == "" {
+		return nil, err
+	}
+	sizes, err := runGitInput(dir, objects+"\n", "cat-file", "--batch-check=%(objecttype) %(objectname) %(objectsize) %(rest)")
+	if err != nil {
+		return nil, err
+	}
+	var large []LargeFile
+	for _, line := range strings.Split(sizes, "\n") {
+		fields := strings.SplitN(line, " ", 4)
+		if len(fields) < 4 || fields[0] != "blob" {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil || size <= limit {
+			continue
+		}
+		commit, _ := runGit(dir, "log", "--format=%h", "-1", "--find-object="+fields[1], "HEAD")
+		large = append(large, LargeFile{Path: fields[3], Size: size, Commit: commit})
+	}
+	return large, nil
+}
+
+// largeWorktreeFiles returns the tracked files and the untracked ones that
+// aren't ignored over limit in the working tree of the repo in dir.
+func largeWorktreeFiles(dir string, limit int64) ([]LargeFile, error) {
+	out, err := runGit(dir, "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	var large []LargeFile
+	seen := make(map[string]bool)
+	for _, file := range strings.Split(out, "\x00") {
+		if file == "" || seen[file] {
+			continue
+		}
+		seen[file] = true
+		info, err := os.Lstat(filepath.Join(dir, file))
+		if err == nil && info.Mode().IsRegular() && info.Size() > limit {
+			large = append(large, LargeFile{Path: file, Size: info.Size()})
+		}
+	}
+	return large, nil
+}
+
+type CheckSizeOptions struct {
+	Select ListOptions
+	// Limit overrides the max_file_size config setting when positive.
+	Limit int64
+}
+
+// CheckSize returns the files over the size limit in the working trees and
+// in the unpushed commits of the selected repos. Repos that couldn't be
+// checked are reported in a *BatchError next to the files of the others.
+func (client *RepoManager) CheckSize(opts CheckSizeOptions) ([]LargeFile, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		var err error
+		if limit, err = client.maxFileSize(); err != nil {
+			return nil, err
+		}
+		if limit == 0 {
+			return nil, fmt.Errorf("max_file_size is 0, pass a limit to check with")
+		}
+	}
+	client.logger.Info("checking file sizes", "workspace", client.workspace, "limit", FormatBytes(limit))
+	files := []LargeFile{}
+	errs := make(map[string]error)
+	for _, repoConfig := range client.selectedRepos(opts.Select) {
+		dir := repoConfig.FullDir(client.workspace)
+		if !isGitRepo(dir) || client.mirrorFor(repoConfig) {
+			continue
+		}
+		client.logger.Debug("checking file sizes", "repo", repoConfig.Name)
+		worktree, err := largeWorktreeFiles(dir, limit)
+		if err != nil {
+			errs[repoConfig.Name] = err
+			continue
+		}
+		outgoing, err := largeOutgoingBlobs(dir, client.pushSpec(repoConfig, PushOptions{}), limit)
+		if err != nil {
+			errs[repoConfig.Name] = err
+			continue
+		}
+		// A file in an unpushed commit is only listed with the commit.
+		committed := make(map[string]bool)
+		for _, file := range outgoing {
+			committed[file.Path] = true
+		}
+		for _, file := range append(worktree, outgoing...) {
+			if file.Commit == "" && committed[file.Path] {
+				continue
+			}
+			file.Repo = repoConfig.Name
+			files = append(files, file)
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Repo < files[j].Repo
+	})
+	if len(errs) > 0 {
+		return files, &BatchError{Errors: errs}
+	}
+	return files, nil
+}
+
+// push pushes the repo as spec says, after making sure it doesn't update a
+// branch of protected_branches or publish files over max_file_size unless
+// allowLarge is set.
+func (client *RepoManager) push(repoConfig *RepoConfig, spec PushSpec, allowLarge bool) (bool, error) {
+	dir := repoConfig.FullDir(client.workspace)
+	if err := client.checkProtected(dir, spec); err != nil {
+		return false, err
+	}
+	if !allowLarge {
+		limit, err := client.maxFileSize()
+		if err != nil {
+			return false, err
+		}
+		if limit > 0 {
+			large, err := largeOutgoingBlobs(dir, spec, limit)
+			if err != nil {
+				return false, err
+			}
+			if len(large) > 0 {
+				file := large[0]
+				msg := fmt.Sprintf("%s of %s in %s is over the %s limit", file.Path, FormatBytes(file.Size), file.Commit, FormatBytes(limit))
+				if len(large) > 1 {
+					msg += fmt.Sprintf(", and %d more file(s)", len(large)-1)
+				}
+				return false, fmt.Errorf("%s, not pushing, see repos check-size", msg)
+			}
+		}
+	}
+	return client.backend.Push(dir, spec)
+}
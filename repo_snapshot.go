@@ -0,0 +1,153 @@
+package repos
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// Manifest records the exact commit of every repo of a workspace, written by
+// Snapshot and checked out again by RestoreManifest.
+type Manifest struct {
+	Created time.Time                 `json:"created"`
+	Repos   map[string]*ManifestEntry `json:"repos"`
+}
+
+type ManifestEntry struct {
+	URL string `json:"url"`
+	// Branch is the branch checked out at the time, empty for a detached
+	// HEAD.
+	Branch string `json:"branch,omitempty"`
+	Commit string `json:"commit"`
+}
+
+// Snapshot records the HEAD commit and branch of the selected repos. Repos
+// that aren't cloned are left out.
+func (client *RepoManager) Snapshot(opts ListOptions) (*Manifest, error) {
+	client.logger.Info("taking a snapshot", "workspace", client.workspace)
+	manifest := &Manifest{Created: time.Now(), Repos: make(map[string]*ManifestEntry)}
+	for _, repoConfig := range client.selectedRepos(opts) {
+		dir := repoConfig.FullDir(client.workspace)
+		commit := headOf(dir)
+		if commit == "" {
+			client.logger.Warn("not cloned, leaving it out", "repo", repoConfig.Name)
+			continue
+		}
+		branch, err := runGit(dir, "symbolic-ref", "--quiet", "--short", "HEAD")
+		if err != nil {
+			branch = ""
+		}
+		manifest.Repos[repoConfig.Name] = &ManifestEntry{URL: repoConfig.Url, Branch: branch, Commit: commit}
+	}
+	return manifest, nil
+}
+
+// IsManifest reports whether path holds a JSON manifest rather than, e.g., a
+// backup archive.
+func IsManifest(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return false
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b == '{'
+	}
+}
+
+// LoadManifest reads a manifest written by Snapshot.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return manifest, nil
+}
+
+// RestoreManifest checks out the commits recorded in manifest in the repos
+// that are in both the manifest and the workspace. Missing commits are
+// fetched from origin. A recorded branch is moved to its commit when that
+// only drops commits that are on origin as well, otherwise HEAD is detached
+// at the commit. Dirty repos are skipped.
+func (client *RepoManager) RestoreManifest(manifest *Manifest) error {
+	client.logger.Info("restoring the snapshot", "workspace", client.workspace)
+	var repoConfigs []*RepoConfig
+	var missing []string
+	for name := range manifest.Repos {
+		repoConfig, ok := client.config.Repos[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		repoConfigs = append(repoConfigs, repoConfig)
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		client.logger.Warn("not in the workspace, skipping", "repo", name)
+	}
+	sort.Slice(repoConfigs, func(i, j int) bool {
+		return repoConfigs[i].Name < repoConfigs[j].Name
+	})
+	cli := client.gitCLI()
+	return client.runBatch("restore", repoConfigs, func(repoConfig *RepoConfig) (outcome, string, error) {
+		entry := manifest.Repos[repoConfig.Name]
+		dir := repoConfig.FullDir(client.workspace)
+		if err := client.backend.Open(dir); err != nil {
+			return outcomeSkipped, "", err
+		}
+		status, err := client.backend.Status(dir)
+		if err != nil {
+			return outcomeFailed, "", err
+		}
+		if headOf(dir) == entry.Commit && status.Branch == entry.Branch {
+			return outcomeUpToDate, "", nil
+		}
+		if status.Changed {
+			return outcomeSkipped, "dirty", nil
+		}
+		if _, err := runGit(dir, "cat-file", "-e", entry.Commit+"^{commit}"); err != nil {
+			client.logger.Info("fetching", "repo", repoConfig.Name, "commit", entry.Commit)
+			if _, err := cli.originGit(dir, "fetch", "origin", entry.Commit); err != nil {
+				return outcomeFailed, "", fmt.Errorf("fetching %s: %w", entry.Commit, err)
+			}
+		}
+		if entry.Branch != "" && canMoveBranch(dir, entry.Branch, entry.Commit) {
+			client.logger.Info("checking out", "repo", repoConfig.Name, "branch", entry.Branch, "commit", entry.Commit)
+			_, err = runGit(dir, "checkout", "-B", entry.Branch, entry.Commit)
+		} else {
+			client.logger.Info("checking out", "repo", repoConfig.Name, "commit", entry.Commit)
+			_, err = runGit(dir, "checkout", "--detach", entry.Commit)
+		}
+		if err != nil {
+			return outcomeFailed, "", err
+		}
+		return outcomeSucceeded, "", nil
+	})
+}
+
+// canMoveBranch reports whether resetting branch to commit loses nothing:
+// the branch doesn't exist yet or every commit it has beyond commit is on
+// origin.
+func canMoveBranch(dir, branch, commit string) bool {
+	if _, err := runGit(dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err != nil {
+		return true
+	}
+	out, err := runGit(dir, "rev-list", "--count", "refs/heads/"+branch, "^"+commit, "--not", "--remotes=origin")
+	return err == nil && out == "0"
+}
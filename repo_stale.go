@@ -0,0 +1,117 @@
+package repos
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StaleEntry is a repo without upstream commits or local activity in the
+// window of Stale.
+type StaleEntry struct {
+	Name string `json:"name"`
+	Dir  string `json:"dir"`
+	// Upstream is the branch whose commits were looked at, empty when the
+	// repo has none.
+	Upstream string `json:"upstream,omitempty"`
+	// LastUpstream is when the newest commit of the upstream was made.
+	LastUpstream time.Time `json:"last_upstream"`
+	// LastLocal is the newest commit on a local branch or change of HEAD,
+	// whichever is later.
+	LastLocal time.Time `json:"last_local"`
+}
+
+// LastActivity is the later of LastUpstream and LastLocal.
+func (entry *StaleEntry) LastActivity() time.Time {
+	if entry.LastUpstream.After(entry.LastLocal) {
+		return entry.LastUpstream
+	}
+	return entry.LastLocal
+}
+
+// gitTime runs a git command printing a unix time and parses it, zero when
+// there is nothing to print.
+func gitTime(dir string, args ...string) (time.Time, error) {
+	out, err := runGit(dir, args...)
+	if err != nil || out == "" {
+		return time.Time{}, err
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected time %q", out)
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+// lastLocalActivity returns when HEAD last changed, by a commit, checkout or
+// reset, or when the newest commit of the local branches was made. Cloning
+// doesn't count.
+func lastLocalActivity(dir string) (time.Time, error) {
+	last, err := gitTime(dir, "log", "-1", "--format=%ct", "--branches")
+	if err != nil {
+		return time.Time{}, err
+	}
+	// Mirrors have no reflog.
+	reflog, _ := runGit(dir, "log", "-g", "-1", "--date=unix", "--format=%gd %gs", "HEAD")
+	if selector, subject, ok := strings.Cut(reflog, " "); ok && !strings.HasPrefix(subject, "clone:") {
+		reflog = selector
+		if open := strings.Index(reflog, "{"); open >= 0 && strings.HasSuffix(reflog, "}") {
+			if seconds, err := strconv.ParseInt(reflog[open+1:len(reflog)-1], 10, 64); err == nil && time.Unix(seconds, 0).After(last) {
+				last = time.Unix(seconds, 0)
+			}
+		}
+	}
+	return last, nil
+}
+
+// Stale returns the repos whose upstream got no commits and that saw no local
+// activity in the last days, least recently active first. Repos with
+// uncommitted changes count as active.
+func (client *RepoManager) Stale(days int) ([]*StaleEntry, error) {
+	if days <= 0 {
+		return nil, fmt.Errorf("days must be positive")
+	}
+	since := time.Now().AddDate(0, 0, -days)
+	entries := []*StaleEntry{}
+	for _, repoConfig := range client.sortedRepos() {
+		dir := repoConfig.FullDir(client.workspace)
+		if err := client.backend.Open(dir); err != nil {
+			client.logger.Warn("skipping", "repo", repoConfig.Name, "err", err)
+			continue
+		}
+		if !client.mirrorFor(repoConfig) {
+			clean, err := IfRepoIsClean(dir)
+			if err != nil {
+				client.logger.Warn("skipping", "repo", repoConfig.Name, "err", err)
+				continue
+			}
+			if !clean {
+				continue
+			}
+		}
+		entry := &StaleEntry{Name: repoConfig.Name, Dir: dir, Upstream: upstreamOf(dir)}
+		if entry.Upstream == "" && repoConfig.Branch.Main() != "" {
+			if _, err := runGit(dir, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+repoConfig.Branch.Main()); err == nil {
+				entry.Upstream = "origin/" + repoConfig.Branch.Main()
+			}
+		}
+		var err error
+		if entry.Upstream != "" {
+			if entry.LastUpstream, err = gitTime(dir, "log", "-1", "--format=%ct", entry.Upstream); err != nil {
+				return nil, fmt.Errorf("%s: %w", repoConfig.Name, err)
+			}
+		}
+		if entry.LastLocal, err = lastLocalActivity(dir); err != nil {
+			return nil, fmt.Errorf("%s: %w", repoConfig.Name, err)
+		}
+		if entry.LastActivity().Before(since) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].LastActivity().Before(entries[j].LastActivity())
+	})
+	return entries, nil
+}
@@ -0,0 +1,143 @@
+package repos
+
+import (
+	"strings"
+	"time"
+)
+
+// stashLabel prefixes every stash message created by Stash so that StashPop
+// only restores entries that were parked by this tool.
+const stashLabel = "repos:"
+
+func stashMessage(message string) string {
+	if message == "" {
+		message = time.Now().Format("2006-01-02 15:04:05")
+	}
+	return stashLabel + " " + message
+}
+
+// isOwnStash reports whether subject, as git stash list shows it, is of a
+// stash created by Stash. git writes the message after "On <branch>: ",
+// branch names can't hold a colon.
+func isOwnStash(subject string) bool {
+	_, message, ok := strings.Cut(subject, ": ")
+	return ok && strings.HasPrefix(subject, "On ") && strings.HasPrefix(message, stashLabel+" ")
+}
+
+// findStash returns the ref (e.g. stash@{0}) of the latest stash created by Stash.
+func findStash(dir string) (string, error) {
+	out, err := runGit(dir, "stash", "list", "--format=%gd%x00%gs")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.SplitN(line, "\x00", 2)
+		if len(parts) == 2 && isOwnStash(parts[1]) {
+			return parts[0], nil
+		}
+	}
+	return "", nil
+}
+
+func (client *RepoManager) Stash(message string) error {
+	client.logger.Info("stashing", "workspace", client.workspace)
+	max := client.nameWidth()
+	message = stashMessage(message)
+	history := client.newHistory("stash")
+	defer history.record()
+	var failed []string
+	for _, repoConfig := range client.sortedRepos() {
+		if client.failingFast(failed) {
+			break
+		}
+		dir := repoConfig.FullDir(client.workspace)
+		head := headOf(dir)
+		clean, err := IfRepoIsClean(dir)
+		if err != nil {
+			client.printRepoLine(max, repoConfig.Name, err)
+			history.fail(repoConfig, head, err)
+			failed = append(failed, repoConfig.Name)
+			continue
+		}
+		if clean {
+			client.printRepoLine(max, repoConfig.Name, "clean")
+			history.add(repoConfig, head, outcomeUpToDate, "")
+			continue
+		}
+		client.logger.Debug("stashing", "repo", repoConfig.Name)
+		if _, err := runGit(dir, "stash", "push", "--include-untracked", "-m", message); err != nil {
+			client.printRepoLine(max, repoConfig.Name, err)
+			history.fail(repoConfig, head, err)
+			failed = append(failed, repoConfig.Name)
+			continue
+		}
+		client.printRepoLine(max, repoConfig.Name, "stashed")
+		history.add(repoConfig, head, outcomeSucceeded, "")
+	}
+	if len(failed) > 0 {
+		return failedIn("stash", failed)
+	}
+	return nil
+}
+
+func (client *RepoManager) StashPop() error {
+	client.logger.Info("restoring stashes", "workspace", client.workspace)
+	max := client.nameWidth()
+	history := client.newHistory("stash pop")
+	defer history.record()
+	var failed []string
+	for _, repoConfig := range client.sortedRepos() {
+		if client.failingFast(failed) {
+			break
+		}
+		dir := repoConfig.FullDir(client.workspace)
+		head := headOf(dir)
+		ref, err := findStash(dir)
+		if err != nil {
+			client.printRepoLine(max, repoConfig.Name, err)
+			history.fail(repoConfig, head, err)
+			failed = append(failed, repoConfig.Name)
+			continue
+		}
+		if ref == "" {
+			continue
+		}
+		client.logger.Debug("popping stash", "repo", repoConfig.Name, "ref", ref)
+		if _, err := runGit(dir, "stash", "pop", ref); err != nil {
+			client.printRepoLine(max, repoConfig.Name, err)
+			history.fail(repoConfig, head, err)
+			failed = append(failed, repoConfig.Name)
+			continue
+		}
+		client.printRepoLine(max, repoConfig.Name, "restored")
+		history.add(repoConfig, head, outcomeSucceeded, "")
+	}
+	if len(failed) > 0 {
+		return failedIn("stash pop", failed)
+	}
+	return nil
+}
+
+// StashList prints the stashes created by Stash in every repo. Repos whose
+// stashes can't be listed are reported once the others are.
+func (client *RepoManager) StashList() error {
+	max := client.nameWidth()
+	errs := make(map[string]error)
+	for _, repoConfig := range client.sortedRepos() {
+		out, err := runGit(repoConfig.FullDir(client.workspace), "stash", "list", "--format=%gd%x00%gs")
+		if err != nil {
+			client.printRepoLine(max, repoConfig.Name, err)
+			errs[repoConfig.Name] = err
+			continue
+		}
+		for _, line := range strings.Split(out, "\n") {
+			if ref, subject, ok := strings.Cut(line, "\x00"); ok && isOwnStash(subject) {
+				client.printRepoLine(max, repoConfig.Name, ref+" "+subject)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return &BatchError{Errors: errs}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package repos
+
+import "testing"
+
+func TestIsOwnStash(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    bool
+	}{
+		{"On main: repos: 2023-01-01 10:00:00", true},
+		{"On feature/x: repos: before the release", true},
+		{"On repos: work in progress", false},
+		{"On repos: fix repos: later", false},
+		{"On main: wip on repos: config", false},
+		{"WIP on main: 1a2b3c4 repos: bump", false},
+		{"repos: no branch", false},
+	}
+	for _, tt := range tests {
+		if got := isOwnStash(tt.subject); got != tt.want {
+			t.Errorf("isOwnStash(%q) = %v, want %v", tt.subject, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package repos
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// transferCommands are the batch commands that talk to remotes, whose
+// transfers are measured.
+var transferCommands = []string{"pull", "push", "sync", "sync-fork"}
+
+// repoStats is how long a repo took in a batch operation, about what it
+// transferred and where its HEAD moved. git doesn't tell what went over the
+// wire, so the transfers are estimates: what was received is the growth of
+// the object database, what was sent the objects the upstream gained less
+// those received.
+type repoStats struct {
+	Took               time.Duration
+	EstBytesReceived   int64
+	EstObjectsReceived int
+	EstObjectsSent     int
+	// Before and After are the HEAD commits around the operation, empty
+	// when the repo had none, e.g. before a clone.
+	Before string
+	After  string
+}
+
+// objectStore is a snapshot of the object database of a repo and its
+// upstream.
+type objectStore struct {
+	objects  int
+	bytes    int64
+	upstream string
+}
+
+// snapshotObjects counts the objects of the repo in dir with git
+// count-objects. It returns nil when that fails, e.g. before a clone.
+func snapshotObjects(dir string) *objectStore {
+	out, err := runGit(dir, "count-objects", "-v")
+	if err != nil {
+		return nil
+	}
+	store := &objectStore{}
+	for _, line := range strings.Split(out, "\n") {
+		key, value, _ := strings.Cut(line, ": ")
+		n, _ := strconv.ParseInt(value, 10, 64)
+		switch key {
+		case "count", "in-pack":
+			store.objects += int(n)
+		case "size", "size-pack":
+			store.bytes += n * 1024
+		}
+	}
+	if upstream := upstreamOf(dir); upstream != "" {
+		store.upstream, _ = runGit(dir, "rev-parse", upstream)
+	}
+	return store
+}
+
+// transferSince estimates what was transferred since the snapshot before.
+func (stats *repoStats) transferSince(dir string, before *objectStore) {
+	after := snapshotObjects(dir)
+	if before == nil || after == nil {
+		return
+	}
+	if after.objects > before.objects {
+		stats.EstObjectsReceived = after.objects - before.objects
+	}
+	if after.bytes > before.bytes {
+		stats.EstBytesReceived = after.bytes - before.bytes
+	}
+	if before.upstream == "" || after.upstream == "" || before.upstream == after.upstream {
+		return
+	}
+	out, err := runGit(dir, "rev-list", "--objects", "--count", before.upstream+".."+after.upstream)
+	if err != nil {
+		return
+	}
+	if gained, _ := strconv.Atoi(out); gained > stats.EstObjectsReceived {
+		stats.EstObjectsSent = gained - stats.EstObjectsReceived
+	}
+}
+
+func (stats *repoStats) String() string {
+	s := stats.Took.Round(time.Millisecond).String()
+	if stats.EstObjectsReceived > 0 || stats.EstBytesReceived > 0 {
+		s += fmt.Sprintf(", received ~%d objects (~%s)", stats.EstObjectsReceived, FormatBytes(stats.EstBytesReceived))
+	}
+	if stats.EstObjectsSent > 0 {
+		s += fmt.Sprintf(", sent ~%d objects", stats.EstObjectsSent)
+	}
+	return s
+}
+
+// WithSlowest makes batch operations list the n repos that took longest
+// after the summary.
+func WithSlowest(n int) NewRepoManagerClientOptions {
+	return func(client *RepoManager) {
+		client.slowest = n
+	}
+}
+
+// WithReport makes batch operations write a JSON report of every repo's
+// outcome, time and transfers to path.
+func WithReport(path string) NewRepoManagerClientOptions {
+	return func(client *RepoManager) {
+		client.reportPath = path
+	}
+}
+
+// WithReportFormat writes the report of WithReport as a CSV or TSV row per
+// repo with OutputCSV or OutputTSV, or with any other format as a Go
+// template, as parsed by FormatTemplate, executed for every repo instead of
+// as JSON.
+func WithReportFormat(format string) NewRepoManagerClientOptions {
+	return func(client *RepoManager) {
+		client.reportFormat = format
+	}
+}
+
+// printSlowest lists the repos that took longest.
+func (client *RepoManager) printSlowest(summary *runSummary) {
+	if client.slowest <= 0 || client.verbosity <= VerbosityQuiet {
+		return
+	}
+	outcomes := append([]repoOutcome{}, summary.outcomes...)
+	sort.SliceStable(outcomes, func(i, j int) bool {
+		return outcomes[i].stats.Took > outcomes[j].stats.Took
+	})
+	if len(outcomes) > client.slowest {
+		outcomes = outcomes[:client.slowest]
+	}
+	fmt.Println("slowest")
+	max := client.nameWidth()
+	for _, o := range outcomes {
+		client.printRepoLine(max, "  "+o.name, o.stats.String())
+	}
+}
+
+// batchReport is the JSON report of a batch operation.
+type batchReport struct {
+	Command  string             `json:"command"`
+	Started  time.Time          `json:"started"`
+	TookMS   int64              `json:"took_ms"`
+	Repos    []*repoReportEntry `json:"repos"`
+	Failed   int                `json:"failed"`
+	Schedule string             `json:"schedule,omitempty"`
+}
+
+type repoReportEntry struct {
+	Name    string  `json:"name"`
+	Outcome outcome `json:"outcome"`
+	Reason  string  `json:"reason,omitempty"`
+	TookMS  int64   `json:"took_ms"`
+	// The transfers are estimates, see repoStats.
+	EstBytesReceived   int64 `json:"est_bytes_received"`
+	EstObjectsReceived int   `json:"est_objects_received"`
+	EstObjectsSent     int   `json:"est_objects_sent"`
+	// Kind and Hint classify the error of failed repos.
+	Kind ErrorKind `json:"kind,omitempty"`
+	Hint string    `json:"hint,omitempty"`
+	// Conflicts lists the files left with conflicts.
+	Conflicts []string `json:"conflicts,omitempty"`
+}
+
+// newBatchReport builds the report of a batch operation from its summary.
+func newBatchReport(command string, started time.Time, summary *runSummary) *batchReport {
+	report := &batchReport{
+		Command: command,
+		Started: started,
+		TookMS:  time.Since(started).Milliseconds(),
+		Repos:   []*repoReportEntry{},
+		Failed:  summary.count(outcomeFailed),
+	}
+	for _, o := range summary.outcomes {
+		var conflicts []string
+		if o.conflict != nil {
+			conflicts = o.conflict.Files
+		}
+		report.Repos = append(report.Repos, &repoReportEntry{
+			Name:               o.name,
+			Outcome:            o.outcome,
+			Reason:             o.reason,
+			Kind:               o.kind,
+			Hint:               o.hint,
+			Conflicts:          conflicts,
+			TookMS:             o.stats.Took.Milliseconds(),
+			EstBytesReceived:   o.stats.EstBytesReceived,
+			EstObjectsReceived: o.stats.EstObjectsReceived,
+			EstObjectsSent:     o.stats.EstObjectsSent,
+		})
+	}
+	return report
+}
+
+// formatReport encodes report as JSON, CSV, TSV or with the report
+// template.
+func (client *RepoManager) formatReport(report *batchReport) ([]byte, error) {
+	var out bytes.Buffer
+	switch {
+	case client.reportFormat == OutputCSV || client.reportFormat == OutputTSV:
+		var rows [][]string
+		for _, entry := range report.Repos {
+			rows = append(rows, []string{entry.Name, string(entry.Outcome), entry.Reason, string(entry.Kind), entry.Hint, strings.Join(entry.Conflicts, " "),
+				strconv.FormatInt(entry.TookMS, 10), strconv.FormatInt(entry.EstBytesReceived, 10),
+				strconv.Itoa(entry.EstObjectsReceived), strconv.Itoa(entry.EstObjectsSent)})
+		}
+		header := []string{"name", "outcome", "reason", "kind", "hint", "conflicts", "took_ms", "est_bytes_received", "est_objects_received", "est_objects_sent"}
+		err := WriteDelimited(&out, client.reportFormat, header, rows)
+		return out.Bytes(), err
+	case client.reportTemplate == nil:
+		data, err := json.MarshalIndent(report, "", "  ")
+		return append(data, '\n'), err
+	}
+	for _, entry := range report.Repos {
+		if err := client.reportTemplate.Execute(&out, entry); err != nil {
+			return nil, err
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// writeReport writes the JSON report of a batch operation when one was asked
+// for. Failing to write it is only logged.
+func (client *RepoManager) writeReport(command string, started time.Time, summary *runSummary) {
+	if client.reportPath == "" {
+		return
+	}
+	report := newBatchReport(command, started, summary)
+	report.Schedule = client.schedule
+	data, err := client.formatReport(report)
+	if err == nil {
+		err = os.WriteFile(client.reportPath, data, 0644)
+	}
+	if err != nil {
+		client.logger.Warn("writing the report failed", "path", client.reportPath, "error", err)
+	}
+}
@@ -0,0 +1,186 @@
+package repos
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// StatusSort is the order status lists the repos in.
+type StatusSort string
+
+const (
+	StatusSortName StatusSort = "name"
+	// StatusSortStatus lists failed repos first, then dirty ones, then those
+	// ahead of or behind their upstream, then the clean ones.
+	StatusSortStatus StatusSort = "status"
+	// StatusSortLastCommit lists the repos with the newest commit first.
+	StatusSortLastCommit StatusSort = "last-commit"
+)
+
+// ParseStatusSort parses the --sort of status, defaulting to
+// StatusSortName.
+func ParseStatusSort(value string) (StatusSort, error) {
+	switch order := StatusSort(value); order {
+	case "":
+		return StatusSortName, nil
+	case StatusSortName, StatusSortStatus, StatusSortLastCommit:
+		return order, nil
+	}
+	return "", fmt.Errorf("unknown sort %q, must be name, status or last-commit", value)
+}
+
+type StatusOptions struct {
+	// Dirty, Behind, Ahead and Failed only list the repos with uncommitted
+	// changes, behind or ahead of their upstream or whose status failed.
+	// Combined, repos matching any of them are listed.
+	Dirty  bool
+	Behind bool
+	Ahead  bool
+	Failed bool
+	Sort   StatusSort
+}
+
+// StatusEntry is the state of the working tree of a repo as listed by
+// status.
+type StatusEntry struct {
+	Name string `json:"name"`
+	Dir  string `json:"dir"`
+	// Branch is the checked out branch, empty for a detached HEAD.
+	Branch string `json:"branch"`
+	// Commit is the abbreviated hash of HEAD.
+	Commit     string    `json:"commit"`
+	Clean      bool      `json:"clean"`
+	Mirror     bool      `json:"mirror,omitempty"`
+	Upstream   string    `json:"upstream,omitempty"`
+	Ahead      int       `json:"ahead"`
+	Behind     int       `json:"behind"`
+	LastCommit time.Time `json:"last_commit"`
+	// Worktree describes the repo as a linked worktree of another one.
+	Worktree      string `json:"worktree,omitempty"`
+	OriginDiffers bool   `json:"origin_differs,omitempty"`
+	// Error is why the status couldn't be read.
+	Error string `json:"error,omitempty"`
+}
+
+// Head describes what is checked out: the branch name or, for a detached
+// HEAD, the commit.
+func (entry *StatusEntry) Head() string {
+	if entry.Branch != "" {
+		return entry.Branch
+	}
+	return "detached at " + entry.Commit
+}
+
+// rank orders entries for StatusSortStatus.
+func (entry *StatusEntry) rank() int {
+	switch {
+	case entry.Error != "":
+		return 0
+	case !entry.Clean:
+		return 1
+	case entry.Ahead > 0 || entry.Behind > 0:
+		return 2
+	}
+	return 3
+}
+
+func (opts StatusOptions) matches(entry *StatusEntry) bool {
+	if !opts.Dirty && !opts.Behind && !opts.Ahead && !opts.Failed {
+		return true
+	}
+	return (opts.Dirty && !entry.Clean && entry.Error == "") || (opts.Behind && entry.Behind > 0) ||
+		(opts.Ahead && entry.Ahead > 0) || (opts.Failed && entry.Error != "")
+}
+
+func (client *RepoManager) statusEntry(repoConfig *RepoConfig) *StatusEntry {
+	dir := repoConfig.FullDir(client.workspace)
+	entry := &StatusEntry{Name: repoConfig.Name, Dir: dir}
+	if committed, err := runGit(dir, "log", "-1", "--format=%ct"); err == nil {
+		if seconds, err := strconv.ParseInt(committed, 10, 64); err == nil {
+			entry.LastCommit = time.Unix(seconds, 0)
+		}
+	}
+	if client.mirrorFor(repoConfig) {
+		head, err := runGit(dir, "rev-parse", "--short", "HEAD")
+		if err != nil {
+			entry.Error = err.Error()
+			return entry
+		}
+		entry.Mirror, entry.Commit, entry.Clean = true, head, true
+		return entry
+	}
+	status, err := client.backend.Status(dir)
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	entry.Branch, entry.Commit, entry.Clean = status.Branch, status.Commit, status.Clean()
+	if entry.Upstream = upstreamOf(dir); entry.Upstream != "" {
+		entry.Ahead, entry.Behind, _ = aheadBehind(dir, entry.Upstream)
+	}
+	entry.Worktree = client.worktreeNote(dir)
+	entry.OriginDiffers = remoteDiffers(repoConfig, dir)
+	return entry
+}
+
+// StatusEntries returns the status of every repo matching the filters of
+// opts, in the order of opts.Sort.
+func (client *RepoManager) StatusEntries(opts StatusOptions) []*StatusEntry {
+	client.logger.Info("statusing", "workspace", client.workspace)
+	entries := []*StatusEntry{}
+	for _, repoConfig := range client.sortedRepos() {
+		client.logger.Debug("statusing", "repo", repoConfig.Name)
+		if entry := client.statusEntry(repoConfig); opts.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	switch opts.Sort {
+	case StatusSortStatus:
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].rank() < entries[j].rank()
+		})
+	case StatusSortLastCommit:
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].LastCommit.After(entries[j].LastCommit)
+		})
+	}
+	return entries
+}
+
+// Status prints whether every repo is clean and its checked out branch,
+// only the repos matching the filters of opts and in the order of
+// opts.Sort.
+func (client *RepoManager) Status(opts StatusOptions) error {
+	max := client.nameWidth()
+	for _, entry := range client.StatusEntries(opts) {
+		if entry.Error != "" {
+			client.printRepoLine(max, entry.Name, errors.New(entry.Error))
+			continue
+		}
+		if entry.Mirror {
+			fmt.Printf("%-"+strconv.Itoa(max)+"s %-5s %s\n", entry.Name, "-", "mirror at "+entry.Commit)
+			continue
+		}
+		clean := fmt.Sprintf("%-5v", entry.Clean)
+		if entry.Clean {
+			clean = client.paint(colorGreen, clean)
+		} else {
+			clean = client.paint(colorYellow, clean)
+		}
+		head := entry.Head()
+		if entry.Ahead > 0 || entry.Behind > 0 {
+			head += client.paint(colorYellow, fmt.Sprintf(" (%d ahead, %d behind)", entry.Ahead, entry.Behind))
+		}
+		if entry.Worktree != "" {
+			head += " (" + entry.Worktree + ")"
+		}
+		if entry.OriginDiffers {
+			head += client.paint(colorYellow, " (origin differs from config, see fix-remote)")
+		}
+		fmt.Printf("%-"+strconv.Itoa(max)+"s %s %s\n", entry.Name, clean, head)
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package repos
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// outcome is how a batch operation ended for one repo.
+type outcome string
+
+const (
+	outcomeSucceeded outcome = "succeeded"
+	outcomeUpToDate  outcome = "up-to-date"
+	outcomeSkipped   outcome = "skipped"
+	outcomeFailed    outcome = "failed"
+)
+
+type repoOutcome struct {
+	name    string
+	outcome outcome
+	reason  string
+	stats   repoStats
+	// kind and hint classify the error of failed repos.
+	kind ErrorKind
+	hint string
+	// conflict is set when the repo failed on conflicts or a diverged
+	// branch, which need a person to look at them.
+	conflict *ConflictError
+}
+
+// runSummary collects the outcomes of a batch operation for the table
+// printed at the end of the run.
+type runSummary struct {
+	mu       sync.Mutex
+	outcomes []repoOutcome
+	// stats holds the stats of the repos until their outcome is added.
+	stats map[string]repoStats
+}
+
+func (s *runSummary) add(name string, result outcome, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.outcomes = append(s.outcomes, repoOutcome{name: name, outcome: result, reason: reason, stats: s.stats[name]})
+}
+
+// addFailure adds a failed repo, with the kind of err and its hint when it
+// is classified.
+func (s *runSummary) addFailure(name string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o := repoOutcome{name: name, outcome: outcomeFailed, reason: err.Error(), stats: s.stats[name]}
+	var classified *ClassifiedError
+	if errors.As(err, &classified) {
+		o.kind, o.hint = classified.Kind, classified.Hint()
+	}
+	errors.As(err, &o.conflict)
+	s.outcomes = append(s.outcomes, o)
+}
+
+func (s *runSummary) setStats(name string, stats repoStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stats == nil {
+		s.stats = make(map[string]repoStats)
+	}
+	s.stats[name] = stats
+}
+
+// reportProgress passes the outcome of a repo on to the progress callback.
+func (client *RepoManager) reportProgress(command, name string, result outcome, reason string) {
+	if client.progress == nil {
+		return
+	}
+	client.progress(ProgressEvent{
+		Type:    "repo",
+		Time:    time.Now(),
+		Run:     client.schedule,
+		Command: command,
+		Repo:    name,
+		Outcome: result,
+		Reason:  reason,
+	})
+}
+
+// runBatch runs op for every repo in dependency order and prints a table of
+// the outcomes at the end. op returns the reason along with outcomeSkipped,
+// name is the command it belongs to, e.g. pull.
+func (client *RepoManager) runBatch(name string, repoConfigs []*RepoConfig, op func(*RepoConfig) (outcome, string, error)) error {
+	max := client.nameWidth()
+	summary := &runSummary{}
+	started := time.Now()
+	measure := contains(transferCommands, name)
+	failFast := client.errorPolicyFor() == ErrorFailFast
+	var failedOnce atomic.Bool
+	var table *liveTable
+	// Review questions would end up in the middle of the table.
+	if client.live && client.verbosity == 0 && client.review == nil && contains(liveCommands, name) {
+		table = client.newLiveTable(repoConfigs)
+		client.table = table
+		go table.run()
+	}
+	err := client.runOrdered(repoConfigs, func(repoConfig *RepoConfig) error {
+		if client.interrupted() {
+			summary.add(repoConfig.Name, outcomeSkipped, reasonInterrupted)
+			return nil
+		}
+		if failFast && failedOnce.Load() {
+			summary.add(repoConfig.Name, outcomeSkipped, reasonCancelled)
+			client.reportProgress(name, repoConfig.Name, outcomeSkipped, reasonCancelled)
+			return nil
+		}
+		dir := repoConfig.FullDir(client.workspace)
+		var before *objectStore
+		if measure {
+			before = snapshotObjects(dir)
+		}
+		head := headOf(dir)
+		opStarted := time.Now()
+		if table != nil {
+			table.start(dir)
+		}
+		result, reason, err := op(repoConfig)
+		if table != nil {
+			if err != nil {
+				result = outcomeFailed
+			}
+			table.finish(dir, result)
+		}
+		stats := repoStats{Took: time.Since(opStarted), Before: head, After: headOf(dir)}
+		if measure && err == nil {
+			stats.transferSince(dir, before)
+		}
+		summary.setStats(repoConfig.Name, stats)
+		if err != nil {
+			err = classifyError(err)
+			failedOnce.Store(true)
+			client.printRepoDetail(max, repoConfig.Name, err)
+			client.reportProgress(name, repoConfig.Name, outcomeFailed, err.Error())
+			return err
+		}
+		summary.add(repoConfig.Name, result, reason)
+		client.reportProgress(name, repoConfig.Name, result, reason)
+		if reason != "" {
+			client.printRepoDetail(max, repoConfig.Name, string(result)+", "+reason)
+		} else {
+			client.printRepoDetail(max, repoConfig.Name, string(result))
+		}
+		return nil
+	})
+
+	if table != nil {
+		table.close()
+		client.table = nil
+	}
+
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) {
+		for repoName, repoErr := range batchErr.Errors {
+			var skipped *skippedError
+			if errors.As(repoErr, &skipped) {
+				summary.add(repoName, outcomeSkipped, skipped.reason)
+				client.reportProgress(name, repoName, outcomeSkipped, skipped.reason)
+				client.printRepoDetail(max, repoName, string(outcomeSkipped)+", "+skipped.reason)
+				delete(batchErr.Errors, repoName)
+				continue
+			}
+			summary.addFailure(repoName, repoErr)
+		}
+		if len(batchErr.Errors) == 0 {
+			err = nil
+		}
+	}
+	client.lastSummary = summary
+	client.printSummary(summary)
+	client.printSlowest(summary)
+	client.writeReport(name, started, summary)
+	client.writeStatus(name, repoConfigs, summary)
+	client.recordHistory(name, summary)
+	client.notifyResult(name, summary)
+	client.postNotifications(name, summary)
+	if err == nil && client.interrupted() {
+		return ErrInterrupted
+	}
+	return err
+}
+
+// count returns how many repos ended with result.
+func (s *runSummary) count(result outcome) int {
+	n := 0
+	for _, o := range s.outcomes {
+		if o.outcome == result {
+			n++
+		}
+	}
+	return n
+}
+
+// failed returns the sorted names of the repos that failed.
+func (s *runSummary) failed() []string {
+	var names []string
+	for _, o := range s.outcomes {
+		if o.outcome == outcomeFailed {
+			names = append(names, o.name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// printSummary prints how many repos ended with each outcome, listing the
+// skipped and failed ones with a one line reason.
+func (client *RepoManager) printSummary(summary *runSummary) {
+	if client.verbosity <= VerbosityQuiet {
+		return
+	}
+	sort.Slice(summary.outcomes, func(i, j int) bool {
+		return summary.outcomes[i].name < summary.outcomes[j].name
+	})
+	max := client.nameWidth()
+	rows := []struct {
+		outcome outcome
+		color   string
+	}{
+		{outcomeSucceeded, colorGreen},
+		{outcomeUpToDate, colorGreen},
+		{outcomeSkipped, colorYellow},
+		{outcomeFailed, colorRed},
+	}
+	for _, row := range rows {
+		var details []repoOutcome
+		count := 0
+		for _, o := range summary.outcomes {
+			if o.outcome != row.outcome {
+				continue
+			}
+			count++
+			if o.reason != "" {
+				details = append(details, o)
+			}
+		}
+		line := fmt.Sprintf("%-10s %d", row.outcome, count)
+		if count > 0 {
+			line = client.paint(row.color, line)
+		}
+		fmt.Println(line)
+		for _, o := range details {
+			reason, _, _ := strings.Cut(o.reason, "\n")
+			client.printRepoLine(max, "  "+o.name, reason)
+			if o.hint != "" {
+				client.printRepoLine(max, "", "  hint: "+o.hint)
+			}
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package repos
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SyncStrategy decides how sync brings a repo and its upstream together.
+type SyncStrategy string
+
+const (
+	// SyncPullPush pulls, then pushes.
+	SyncPullPush SyncStrategy = "pull-push"
+	// SyncPullRebasePush rebases local commits onto the upstream, then pushes.
+	SyncPullRebasePush SyncStrategy = "pull-rebase-push"
+	// SyncFetchFastForwardPush fetches and only fast-forwards, failing when
+	// the branches diverged, then pushes.
+	SyncFetchFastForwardPush SyncStrategy = "fetch-ff-only-push"
+	// SyncPushIfAhead never changes the local branch and only pushes when it
+	// is strictly ahead of the upstream.
+	SyncPushIfAhead SyncStrategy = "push-only-if-ahead"
+)
+
+func ParseSyncStrategy(s string) (SyncStrategy, error) {
+	switch strategy := SyncStrategy(s); strategy {
+	case "", SyncPullPush, SyncPullRebasePush, SyncFetchFastForwardPush, SyncPushIfAhead:
+		return strategy, nil
+	}
+	return "", fmt.Errorf("invalid sync strategy %q, must be one of %s, %s, %s or %s",
+		s, SyncPullPush, SyncPullRebasePush, SyncFetchFastForwardPush, SyncPushIfAhead)
+}
+
+func WithSyncStrategy(strategy SyncStrategy) NewRepoManagerClientOptions {
+	return func(client *RepoManager) {
+		client.syncStrategy = strategy
+	}
+}
+
+// syncStrategyFor returns the first strategy that is set: the command line
+// wins over the repo config, which wins over the workspace config.
+func (client *RepoManager) syncStrategyFor(repoConfig *RepoConfig) SyncStrategy {
+	for _, strategy := range []SyncStrategy{client.syncStrategy, repoConfig.SyncStrategy, client.config.SyncStrategy} {
+		if strategy != "" {
+			return strategy
+		}
+	}
+	return SyncPullPush
+}
+
+// aheadBehind counts the commits HEAD and its upstream have that the other
+// one doesn't.
+func aheadBehind(dir string, upstream string) (int, int, error) {
+	counts, err := runGit(dir, "rev-list", "--left-right", "--count", upstream+"...HEAD")
+	if err != nil {
+		return 0, 0, err
+	}
+	fields := strings.Fields(counts)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("unexpected rev-list output %q", counts)
+	}
+	behind, _ := strconv.Atoi(fields[0])
+	ahead, _ := strconv.Atoi(fields[1])
+	return ahead, behind, nil
+}
+
+// syncSingleRepo syncs the checked out branch of a repo with its strategy,
+// fast-forwards its other tracked branches and reports whether it was up to
+// date already.
+func (client *RepoManager) syncSingleRepo(repoConfig *RepoConfig) (bool, error) {
+	cli := client.gitCLI()
+	upToDate, err := client.syncCheckedOutBranch(repoConfig, cli)
+	if err != nil {
+		return false, err
+	}
+	othersUpToDate, err := client.fastForwardBranches(repoConfig, cli)
+	return upToDate && othersUpToDate, err
+}
+
+// fastForwardBranches fetches the tracked branches that aren't checked out and
+// fast-forwards their local branches, creating the missing ones. A branch that
+// diverged from origin is an error.
+func (client *RepoManager) fastForwardBranches(repoConfig *RepoConfig, cli *cliBackend) (bool, error) {
+	dir := repoConfig.FullDir(client.workspace)
+	current, _ := runGit(dir, "symbolic-ref", "--short", "--quiet", "HEAD")
+	upToDate := true
+	for _, branch := range repoConfig.Branch {
+		if branch == current {
+			continue
+		}
+		client.logger.Debug("fast-forwarding", "repo", repoConfig.Name, "branch", branch)
+		ref := "refs/heads/" + branch
+		before, _ := runGit(dir, "rev-parse", "--verify", "--quiet", ref)
+		// Without a leading + git only updates the ref if it fast-forwards.
+		if _, err := cli.originGit(dir, "fetch", "origin", ref+":"+ref); err != nil {
+			if strings.Contains(err.Error(), "non-fast-forward") {
+				return false, fmt.Errorf("%s diverged from origin/%s, can't fast-forward", branch, branch)
+			}
+			return false, fmt.Errorf("%s: %w", branch, err)
+		}
+		if before == "" {
+			if _, err := runGit(dir, "branch", "--set-upstream-to=origin/"+branch, branch); err != nil {
+				return false, err
+			}
+		}
+		if after, _ := runGit(dir, "rev-parse", ref); after != before {
+			upToDate = false
+		}
+	}
+	return upToDate, nil
+}
+
+// syncCheckedOutBranch syncs the checked out branch of a repo with its
+// strategy and reports whether it was up to date already, with nothing to
+// pull or push.
+func (client *RepoManager) syncCheckedOutBranch(repoConfig *RepoConfig, cli *cliBackend) (bool, error) {
+	dir := repoConfig.FullDir(client.workspace)
+	strategy := client.syncStrategyFor(repoConfig)
+	if repoConfig.IsReadOnly() && strategy == SyncPushIfAhead {
+		// Read-only repos are still brought up to date.
+		strategy = SyncFetchFastForwardPush
+	}
+	client.logger.Debug("syncing", "repo", repoConfig.Name, "strategy", strategy)
+
+	if strategy == SyncPullPush {
+		pulledNothing, err := client.backend.Pull(dir, client.singleBranchFor(repoConfig))
+		if err != nil || repoConfig.IsReadOnly() {
+			return pulledNothing, explainConflict(dir, err)
+		}
+		spec := client.pushSpec(repoConfig, PushOptions{})
+		if err := client.verifyPush(dir, spec, cli); err != nil {
+			return false, err
+		}
+		pushedNothing, err := client.push(repoConfig, spec, false)
+		return pulledNothing && pushedNothing, err
+	}
+
+	upstream := upstreamOf(dir)
+	if upstream == "" {
+		return false, fmt.Errorf("no upstream branch configured")
+	}
+	head := headOf(dir)
+	if strategy == SyncPullRebasePush {
+		if _, err := cli.originGit(dir, "pull", "--rebase", "origin"); err != nil {
+			// The conflicts are gone once the rebase is aborted.
+			err = explainConflict(dir, err)
+			runGit(dir, "rebase", "--abort")
+			return false, err
+		}
+	} else if _, err := cli.originGit(dir, "fetch", "origin"); err != nil {
+		return false, err
+	}
+
+	ahead, behind, err := aheadBehind(dir, upstream)
+	if err != nil {
+		return false, err
+	}
+	// pull --rebase has brought in what the upstream had already, which
+	// moved HEAD.
+	pulled := behind > 0 || headOf(dir) != head
+	switch strategy {
+	case SyncFetchFastForwardPush:
+		if behind > 0 && ahead > 0 {
+			return false, &ConflictError{Upstream: upstream, Ahead: ahead, Behind: behind}
+		}
+		if behind > 0 {
+			if _, err := runGit(dir, "merge", "--ff-only", upstream); err != nil {
+				return false, err
+			}
+		}
+	case SyncPushIfAhead:
+		if behind > 0 {
+			return false, fmt.Errorf("%d commit(s) behind %s, not pushing", behind, upstream)
+		}
+	}
+	if ahead == 0 || repoConfig.IsReadOnly() {
+		return !pulled, nil
+	}
+	spec := client.pushSpec(repoConfig, PushOptions{})
+	if err := client.verifyPush(dir, spec, cli); err != nil {
+		return false, err
+	}
+	pushedNothing, err := client.push(repoConfig, spec, false)
+	return !pulled && pushedNothing, err
+}
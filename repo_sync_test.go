@@ -0,0 +1,94 @@
+package repos
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// mustGit runs git in dir and fails the test when it fails.
+func mustGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := runGit(dir, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// commitFile commits a file with the given name and content in dir.
+func commitFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mustGit(t, dir, "add", name)
+	mustGit(t, dir, "commit", "-m", "add "+name)
+}
+
+func TestSyncReportsPulledCommits(t *testing.T) {
+	for _, key := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+		t.Setenv(key+"_NAME", "test")
+		t.Setenv(key+"_EMAIL", "test@example.com")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	tests := []struct {
+		name     string
+		strategy SyncStrategy
+		readOnly bool
+		// local makes the workspace clone ahead of the remote as well.
+		local bool
+	}{
+		{"pull rebase push", SyncPullRebasePush, false, false},
+		{"pull rebase push while ahead", SyncPullRebasePush, false, true},
+		{"pull rebase read-only", SyncPullRebasePush, true, false},
+		{"fetch fast-forward push", SyncFetchFastForwardPush, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			origin := filepath.Join(root, "origin.git")
+			mustGit(t, root, "init", "--bare", "--initial-branch=main", origin)
+			other := filepath.Join(root, "other")
+			mustGit(t, root, "clone", origin, other)
+			commitFile(t, other, "a", "a")
+			mustGit(t, other, "push", "origin", "HEAD:main")
+
+			workspace := filepath.Join(root, "ws")
+			mustGit(t, root, "clone", origin, filepath.Join(workspace, "repo"))
+			// The remote gets ahead of the workspace clone.
+			commitFile(t, other, "b", "b")
+			mustGit(t, other, "push", "origin", "HEAD:main")
+			if tt.local {
+				commitFile(t, filepath.Join(workspace, "repo"), "c", "c")
+			}
+
+			repoConfig := &RepoConfig{Name: "repo", Dir: "repo", Url: origin, ReadOnly: tt.readOnly}
+			config := &ReposConfig{
+				CfgFile: filepath.Join(workspace, "repos.yaml"),
+				Repos:   map[string]*RepoConfig{"repo": repoConfig},
+			}
+			client, err := NewRepoManager(WithConfig(config), WithSyncStrategy(tt.strategy))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			upToDate, err := client.syncSingleRepo(repoConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if upToDate {
+				t.Error("first sync reported up to date after pulling a commit")
+			}
+			upToDate, err = client.syncSingleRepo(repoConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !upToDate {
+				t.Error("second sync reported changes")
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package repos
+
+import (
+	"fmt"
+)
+
+type TagOptions struct {
+	Message  string
+	Annotate bool
+	Sign     bool
+	Push     bool
+}
+
+func (opts TagOptions) args(tag string) []string {
+	args := []string{"tag"}
+	if opts.Sign {
+		args = append(args, "--sign")
+	} else if opts.Annotate || opts.Message != "" {
+		args = append(args, "--annotate")
+	}
+	if opts.Sign || opts.Annotate || opts.Message != "" {
+		message := opts.Message
+		if message == "" {
+			message = tag
+		}
+		args = append(args, "-m", message)
+	}
+	return append(args, tag)
+}
+
+// Tag creates the tag at HEAD of every repo and optionally pushes it to origin.
+// Either all repos end up with the tag or, on the first failure, tags already
+// created or pushed by this call are deleted again.
+func (client *RepoManager) Tag(tag string, opts TagOptions) error {
+	client.logger.Info("tagging", "workspace", client.workspace, "tag", tag)
+	max := client.nameWidth()
+	repoConfigs := client.sortedRepos()
+
+	history := client.newHistory("tag")
+	defer history.record()
+	var tagged, pushed []*RepoConfig
+	// failedRepo is the repo the tag failed in, recorded as such.
+	var failedRepo *RepoConfig
+	rollback := func(cause error) error {
+		for _, repoConfig := range pushed {
+			client.logger.Warn("deleting remote tag", "repo", repoConfig.Name, "tag", tag)
+			if _, err := runGit(repoConfig.FullDir(client.workspace), "push", "origin", ":refs/tags/"+tag); err != nil {
+				client.printRepoLine(max, repoConfig.Name, err)
+			}
+		}
+		for _, repoConfig := range tagged {
+			client.logger.Warn("deleting tag", "repo", repoConfig.Name, "tag", tag)
+			if _, err := runGit(repoConfig.FullDir(client.workspace), "tag", "--delete", tag); err != nil {
+				client.printRepoLine(max, repoConfig.Name, err)
+			}
+			if repoConfig != failedRepo {
+				history.add(repoConfig, headOf(repoConfig.FullDir(client.workspace)), outcomeSkipped, "rolled back")
+			}
+		}
+		return fmt.Errorf("tag %s rolled back: %w", tag, cause)
+	}
+
+	for _, repoConfig := range repoConfigs {
+		client.logger.Debug("tagging", "repo", repoConfig.Name)
+		if _, err := runGit(repoConfig.FullDir(client.workspace), opts.args(tag)...); err != nil {
+			client.printRepoLine(max, repoConfig.Name, err)
+			history.fail(repoConfig, headOf(repoConfig.FullDir(client.workspace)), err)
+			failedRepo = repoConfig
+			return rollback(&BatchError{Errors: map[string]error{repoConfig.Name: err}})
+		}
+		tagged = append(tagged, repoConfig)
+		client.printRepoLine(max, repoConfig.Name, "tagged")
+	}
+
+	if !opts.Push {
+		client.recordTagged(history, tagged, "tagged "+tag)
+		return nil
+	}
+	for _, repoConfig := range repoConfigs {
+		if repoConfig.IsReadOnly() {
+			client.printRepoLine(max, repoConfig.Name, client.paint(colorYellow, "skipped ("+reasonReadOnly+")"))
+			continue
+		}
+		client.logger.Debug("pushing tag", "repo", repoConfig.Name, "tag", tag)
+		if _, err := runGit(repoConfig.FullDir(client.workspace), "push", "origin", "refs/tags/"+tag); err != nil {
+			client.printRepoLine(max, repoConfig.Name, err)
+			history.fail(repoConfig, headOf(repoConfig.FullDir(client.workspace)), err)
+			failedRepo = repoConfig
+			return rollback(&BatchError{Errors: map[string]error{repoConfig.Name: err}})
+		}
+		pushed = append(pushed, repoConfig)
+		client.printRepoLine(max, repoConfig.Name, "pushed")
+	}
+	client.recordTagged(history, tagged, "tagged "+tag)
+	return nil
+}
+
+// recordTagged records the repos a tag was created in, which doesn't move
+// their HEAD.
+func (client *RepoManager) recordTagged(history *historyLog, tagged []*RepoConfig, reason string) {
+	for _, repoConfig := range tagged {
+		history.add(repoConfig, headOf(repoConfig.FullDir(client.workspace)), outcomeSucceeded, reason)
+	}
+}
@@ -0,0 +1,42 @@
+package repos
+
+import (
+	"fmt"
+)
+
+// Unpushed prints, per repo, the commits of the current branch that its
+// upstream branch doesn't have yet, i.e. what a push would publish.
+func (client *RepoManager) Unpushed() error {
+	client.logger.Info("listing unpushed commits", "workspace", client.workspace)
+	max := client.nameWidth()
+	var failed []string
+	for _, repoConfig := range client.sortedRepos() {
+		if client.failingFast(failed) {
+			break
+		}
+		dir := repoConfig.FullDir(client.workspace)
+		upstream := upstreamOf(dir)
+		if upstream == "" {
+			client.printRepoLine(max, repoConfig.Name, client.paint(colorYellow, "no upstream branch configured"))
+			continue
+		}
+		entries, err := logEntries(repoConfig.Name, dir, "log", upstream+"..HEAD")
+		if err != nil {
+			client.printRepoLine(max, repoConfig.Name, err)
+			failed = append(failed, repoConfig.Name)
+			continue
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		fmt.Printf("== %s, %d commit(s) ahead of %s\n", repoConfig.Name, len(entries), upstream)
+		for _, entry := range entries {
+			fmt.Printf("%s  %s  %s  %s\n", entry.Hash[:7], entry.Date.Format("2006-01-02 15:04"), entry.Author, entry.Subject)
+		}
+		fmt.Println()
+	}
+	if len(failed) > 0 {
+		return failedIn("unpushed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package repos
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// NotificationConfig posts the results of scheduled runs to a chat channel or
+// any other webhook.
+type NotificationConfig struct {
+	// Type is slack, discord or webhook. A generic webhook receives the
+	// result as JSON with the rendered message in the text field.
+	Type string `yaml:"type"`
+	// URL is the webhook url.
+	URL string `yaml:"url,omitempty"`
+	// URLEnv names the environment variable holding the webhook url, for
+	// keeping it out of the config file.
+	URLEnv string `yaml:"url_env,omitempty" mapstructure:"url_env"`
+	// When is always or failure, the default.
+	When NotifyPolicy `yaml:"when,omitempty"`
+	// Template is a text/template rendering the message from a
+	// NotificationResult.
+	Template string `yaml:"template,omitempty"`
+}
+
+// NotificationResult is what a notification template is rendered with.
+type NotificationResult struct {
+	Command   string   `json:"command"`
+	Schedule  string   `json:"schedule,omitempty"`
+	Workspace string   `json:"workspace"`
+	Succeeded int      `json:"succeeded"`
+	UpToDate  int      `json:"up_to_date"`
+	Skipped   int      `json:"skipped"`
+	Failed    []string `json:"failed"`
+	Text      string   `json:"text"`
+}
+
+const defaultNotificationTemplate = `repos {{.Command}}{{if .Schedule}} ({{.Schedule}}){{end}}: ` +
+	`{{.Succeeded}} succeeded, {{.UpToDate}} up-to-date, {{.Skipped}} skipped, {{len .Failed}} failed` +
+	`{{if .Failed}}: {{join .Failed ", "}}{{end}}`
+
+var notificationTypes = []string{"slack", "discord", "webhook"}
+
+func (notification *NotificationConfig) template() (*template.Template, error) {
+	text := notification.Template
+	if text == "" {
+		text = defaultNotificationTemplate
+	}
+	return template.New("notification").Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+}
+
+func (notification *NotificationConfig) validate() error {
+	if !contains(notificationTypes, notification.Type) {
+		return fmt.Errorf("invalid type %q, must be one of slack, discord or webhook", notification.Type)
+	}
+	if notification.URL == "" && notification.URLEnv == "" {
+		return fmt.Errorf("url or url_env is required")
+	}
+	switch notification.When {
+	case "", NotifyAlways, NotifyFailure:
+	default:
+		return fmt.Errorf("invalid when %q, must be always or failure", notification.When)
+	}
+	_, err := notification.template()
+	return err
+}
+
+func (notification *NotificationConfig) url() (string, error) {
+	if notification.URLEnv == "" {
+		return notification.URL, nil
+	}
+	url := os.Getenv(notification.URLEnv)
+	if url == "" {
+		return "", fmt.Errorf("no webhook url, set %s", notification.URLEnv)
+	}
+	return url, nil
+}
+
+// post renders the message of result and sends it to the webhook.
+func (notification *NotificationConfig) post(result NotificationResult) error {
+	if len(result.Failed) == 0 && notification.When != NotifyAlways {
+		return nil
+	}
+	tmpl, err := notification.template()
+	if err != nil {
+		return err
+	}
+	var text strings.Builder
+	if err := tmpl.Execute(&text, result); err != nil {
+		return err
+	}
+	result.Text = text.String()
+
+	var payload interface{}
+	switch notification.Type {
+	case "slack":
+		payload = map[string]string{"text": result.Text}
+	case "discord":
+		payload = map[string]string{"content": result.Text}
+	default:
+		payload = result
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	url, err := notification.url()
+	if err != nil {
+		return err
+	}
+	resp, err := webhookClient.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s webhook: %s: %s", notification.Type, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// webhookClient posts notifications. A hanging endpoint must not hold up
+// the command or the daemon, so requests give up after webhookTimeout.
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+const webhookTimeout = 15 * time.Second
+
+// postNotifications sends the result of a scheduled batch operation to the
+// configured notifications. Failing to post is only logged.
+func (client *RepoManager) postNotifications(name string, summary *runSummary) {
+	if client.schedule == "" {
+		return
+	}
+	notifications := client.config.root().Notifications
+	if len(notifications) == 0 {
+		return
+	}
+	result := NotificationResult{
+		Command:   name,
+		Schedule:  client.schedule,
+		Workspace: client.workspace,
+		Succeeded: summary.count(outcomeSucceeded),
+		UpToDate:  summary.count(outcomeUpToDate),
+		Skipped:   summary.count(outcomeSkipped),
+		Failed:    summary.failed(),
+	}
+	if result.Failed == nil {
+		result.Failed = []string{}
+	}
+	for notificationName, notification := range notifications {
+		if err := notification.post(result); err != nil {
+			client.logger.Warn("posting the notification failed", "notification", notificationName, "error", err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package repos
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// commonGitDir returns the git dir of the main repo when dir is a linked
+// worktree, whose .git is a file pointing to a gitdir with a commondir file,
+// and an empty string otherwise.
+func commonGitDir(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, ".git"))
+	if err != nil {
+		return ""
+	}
+	gitDir := strings.TrimSpace(strings.TrimPrefix(string(data), "gitdir:"))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(dir, gitDir)
+	}
+	common, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return ""
+	}
+	commonDir := strings.TrimSpace(string(common))
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(gitDir, commonDir)
+	}
+	return filepath.Clean(commonDir)
+}
+
+// sharedGitDir returns the git dir holding the object store of the repo in
+// dir, which linked worktrees share with their main repo.
+func sharedGitDir(dir string) string {
+	if commonDir := commonGitDir(dir); commonDir != "" {
+		return commonDir
+	}
+	return filepath.Join(dir, ".git")
+}
+
+// worktreeNote describes dir as a worktree of its main repo, relative to the
+// workspace, or returns an empty string when dir isn't a linked worktree.
+func (client *RepoManager) worktreeNote(dir string) string {
+	commonDir := commonGitDir(dir)
+	if commonDir == "" {
+		return ""
+	}
+	main := commonDir
+	if filepath.Base(commonDir) == ".git" {
+		main = filepath.Dir(commonDir)
+	}
+	if rel, err := filepath.Rel(client.workspace, main); err == nil && !strings.HasPrefix(rel, "..") {
+		main = rel
+	}
+	return "worktree of " + main
+}
+
+// sharingRepos returns the git dirs that more than one of repoConfigs work
+// on, keyed by repo name.
+func (client *RepoManager) sharingRepos(repoConfigs []*RepoConfig) map[string]string {
+	byGitDir := make(map[string][]string)
+	for _, repoConfig := range repoConfigs {
+		gitDir := sharedGitDir(repoConfig.FullDir(client.workspace))
+		byGitDir[gitDir] = append(byGitDir[gitDir], repoConfig.Name)
+	}
+	shared := make(map[string]string)
+	for gitDir, names := range byGitDir {
+		if len(names) < 2 {
+			continue
+		}
+		for _, name := range names {
+			shared[name] = gitDir
+		}
+	}
+	return shared
+}
+
+// fetchOnce runs the fetch of every git dir at most once, so that worktrees
+// sharing an object store don't fetch it again.
+type fetchOnce struct {
+	mu      sync.Mutex
+	fetches map[string]*fetchResult
+}
+
+type fetchResult struct {
+	once sync.Once
+	err  error
+}
+
+func (f *fetchOnce) fetch(gitDir string, fetch func() error) error {
+	f.mu.Lock()
+	if f.fetches == nil {
+		f.fetches = make(map[string]*fetchResult)
+	}
+	result, ok := f.fetches[gitDir]
+	if !ok {
+		result = &fetchResult{}
+		f.fetches[gitDir] = result
+	}
+	f.mu.Unlock()
+	result.once.Do(func() {
+		result.err = fetch()
+	})
+	return result.err
+}
+
+// pullSharedRepo pulls a repo whose object store is shared with other repos
+// of the batch: origin is fetched once for all of them and every working tree
+// is fast-forwarded to its upstream.
+func (client *RepoManager) pullSharedRepo(repoConfig *RepoConfig, gitDir string, fetches *fetchOnce) (bool, error) {
+	dir := repoConfig.FullDir(client.workspace)
+	cli := client.gitCLI()
+	err := fetches.fetch(gitDir, func() error {
+		client.logger.Debug("fetching", "repo", repoConfig.Name, "gitdir", gitDir)
+		_, err := cli.originGit(dir, "fetch", "origin")
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	upstream := upstreamOf(dir)
+	if upstream == "" {
+		return false, fmt.Errorf("no upstream branch configured")
+	}
+	before, _ := runGit(dir, "rev-parse", "HEAD")
+	if _, err := runGit(dir, "merge", "--ff-only", upstream); err != nil {
+		return false, err
+	}
+	after, _ := runGit(dir, "rev-parse", "HEAD")
+	return before == after, nil
+}